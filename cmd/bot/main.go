@@ -0,0 +1,24 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"olx-hunter/internal/app"
+)
+
+// Окремий бот: зміни фільтрів зберігає в базі, звідки їх бере cmd/scraper,
+// і доставляє сповіщення з outbox. Доступ до OLX потрібен лише для /find.
+func main() {
+	cfg := app.LoadConfig(true)
+	db, redisCache := app.Connect(cfg)
+
+	scraping := app.NewScraping(cfg, redisCache)
+
+	botApp, err := app.NewBot(cfg, db, redisCache, scraping.Backends)
+	if err != nil {
+		log.Fatal("Error creating bot:", err)
+	}
+
+	app.Run(time.Duration(cfg.ShutdownTimeout)*time.Second, app.Assemble(scraping, nil, botApp, nil)...)
+}
@@ -1,68 +1,30 @@
 package main
 
 import (
-	"context"
 	"log"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
-	"olx-hunter/internal/bot"
-	"olx-hunter/internal/config"
-	"olx-hunter/internal/database"
-	"olx-hunter/internal/models"
-	"olx-hunter/internal/scraper"
-
-	"github.com/joho/godotenv"
+	"olx-hunter/internal/app"
 )
 
+// Спільний режим: скрапер і бот в одному процесі. Зміни фільтрів і
+// сповіщення йдуть через базу, як і в окремих cmd/scraper та cmd/bot.
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Env file is not found")
-	}
-
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatal("Config error:", err)
-	}
-
-	db, err := database.Connect(cfg.DatabaseDSN)
-	if err != nil {
-		log.Fatal("Error connecting to db:", err)
-	}
+	cfg := app.LoadConfig(true)
+	db, redisCache := app.Connect(cfg)
 
 	log.Println("Starting OLX Hunter Scraper Service...")
 
-	notifyChan := make(chan models.Notification, 100)
-
-	scraperService := scraper.NewScraperService(db, notifyChan, cfg.WorkerCount, cfg.ScrapeInterval)
-	if err := scraperService.LoadExistingFilters(); err != nil {
-		log.Fatalf("Failed to load existing filters: %v", err)
+	scraping := app.NewScraping(cfg, redisCache)
+	scraperApp, err := app.NewScraper(cfg, db, scraping)
+	if err != nil {
+		log.Fatalf("Failed to start scraper: %v", err)
 	}
 
-	log.Println("🤖 Starting Telegram Bot...")
-
-	telegramBot, err := bot.NewBot(cfg.BotToken, db, cfg.RedisAddr, scraperService)
+	botApp, err := app.NewBot(cfg, db, redisCache, scraping.Backends)
 	if err != nil {
 		log.Fatal("Error creating bot:", err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-
-	go scraperService.StartPeriodicScraping(ctx)
-	go telegramBot.Start()
-	go telegramBot.ListenNotifications(notifyChan)
-
-	log.Println("OLX Hunter is running!")
-
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
-
-	log.Println("Shutdown signal received...")
-	cancel()
-	time.Sleep(2 * time.Second)
-	log.Println("Goodbye!")
-}
\ No newline at end of file
+	app.Run(time.Duration(cfg.ShutdownTimeout)*time.Second, app.Assemble(scraping, scraperApp, botApp, nil)...)
+}
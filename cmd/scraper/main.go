@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"olx-hunter/internal/app"
+	"olx-hunter/internal/outbox"
+)
+
+// Окремий скрапер: про зміни фільтрів дізнається з бази через LISTEN/NOTIFY,
+// а сповіщення й попередження для адміністраторів кладе в outbox, звідки їх
+// доставляє cmd/bot. Токен бота не потрібен.
+func main() {
+	cfg := app.LoadConfig(false)
+	db, redisCache := app.Connect(cfg)
+
+	log.Println("Starting OLX Hunter Scraper Service...")
+
+	scraping := app.NewScraping(cfg, redisCache)
+	scraperApp, err := app.NewScraper(cfg, db, scraping)
+	if err != nil {
+		log.Fatalf("Failed to start scraper: %v", err)
+	}
+
+	app.Run(time.Duration(cfg.ShutdownTimeout)*time.Second, app.Assemble(scraping, scraperApp, nil, outbox.AdminAlerts(db, cfg.AdminIDs))...)
+}
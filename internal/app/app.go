@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"olx-hunter/internal/bot"
+	"olx-hunter/internal/cache"
+	"olx-hunter/internal/config"
+	"olx-hunter/internal/database"
+
+	"github.com/joho/godotenv"
+)
+
+// LoadConfig читає .env і змінні оточення. withBot вимагає токен бота.
+func LoadConfig(withBot bool) *config.Config {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Env file is not found")
+	}
+
+	cfg, err := config.Load()
+	if err == nil && withBot {
+		err = cfg.RequireBotToken()
+	}
+	if err != nil {
+		log.Fatal("Config error:", err)
+	}
+	return cfg
+}
+
+// Connect підключається до Postgres і Redis. Без Redis сервіс працює далі,
+// але без кешу, а бюджет запитів стає окремим для кожного процесу.
+func Connect(cfg *config.Config) (*database.DB, *cache.RedisCache) {
+	db, err := database.Connect(cfg.DatabaseDSN)
+	if err != nil {
+		log.Fatal("Error connecting to db:", err)
+	}
+
+	redisCache := cache.NewRedisCache(cfg.RedisAddr)
+	if err := redisCache.Ping(); err != nil {
+		log.Printf("Warning: Redis connection failed: %v", err)
+		log.Printf("Bot will work without caching, request budget is per instance!")
+	} else {
+		log.Printf("Redis connected successfully")
+	}
+	return db, redisCache
+}
+
+// scrapingPart - доступ до OLX, потрібний у будь-якому режимі
+type scrapingPart interface {
+	Run(ctx context.Context)
+	SetAlertHandler(alert func(text string))
+	StatusProviders() []bot.StatusProvider
+}
+
+// scraperPart - періодичний скрапінг
+type scraperPart interface {
+	Run(ctx context.Context)
+	StatusProviders() []bot.StatusProvider
+}
+
+// botPart - Telegram-бот з доставкою сповіщень
+type botPart interface {
+	Run(ctx context.Context)
+	NotifyAdmins(text string)
+	AddStatusProvider(provider bot.StatusProvider)
+}
+
+// Assemble з'єднує частини процесу і повертає компоненти для Run. Процес
+// без скрапера передає nil у scraperApp, без бота - nil у botApp; тоді
+// попередження для адміністраторів кладе в outbox queueAlert, а доставить
+// їх бот в іншому процесі.
+func Assemble(scraping scrapingPart, scraperApp scraperPart, botApp botPart, queueAlert func(text string)) []func(ctx context.Context) {
+	components := []func(ctx context.Context){scraping.Run}
+
+	if botApp == nil {
+		scraping.SetAlertHandler(queueAlert)
+	} else {
+		scraping.SetAlertHandler(botApp.NotifyAdmins)
+		for _, provider := range scraping.StatusProviders() {
+			botApp.AddStatusProvider(provider)
+		}
+	}
+
+	if scraperApp != nil {
+		if botApp != nil {
+			for _, provider := range scraperApp.StatusProviders() {
+				botApp.AddStatusProvider(provider)
+			}
+		}
+		components = append(components, scraperApp.Run)
+	}
+	if botApp != nil {
+		components = append(components, botApp.Run)
+	}
+	return components
+}
+
+// Run запускає компоненти і чекає на SIGINT або SIGTERM. Після сигналу
+// контекст скасовується, а компоненти мають shutdownTimeout, щоб завершити
+// запити й доставку, що виконуються.
+func Run(shutdownTimeout time.Duration, components ...func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	for _, component := range components {
+		wg.Add(1)
+		go func(component func(ctx context.Context)) {
+			defer wg.Done()
+			component(ctx)
+		}(component)
+	}
+
+	log.Println("OLX Hunter is running!")
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	log.Println("Shutdown signal received...")
+	cancel()
+
+	// Недоставлені сповіщення лишаються в outbox і підуть після перезапуску
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("Goodbye!")
+	case <-time.After(shutdownTimeout):
+		log.Printf("Shutdown timed out after %v, exiting anyway", shutdownTimeout)
+	}
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"olx-hunter/internal/bot"
+)
+
+type statusText string
+
+func (s statusText) StatusReport() string { return string(s) }
+
+// fakePart записує, що з нею зробили
+type fakePart struct {
+	name      string
+	runs      *[]string
+	alert     func(text string)
+	providers []bot.StatusProvider
+	alerts    []string
+}
+
+func (p *fakePart) Run(ctx context.Context) {
+	*p.runs = append(*p.runs, p.name)
+}
+
+func (p *fakePart) SetAlertHandler(alert func(text string)) {
+	p.alert = alert
+}
+
+func (p *fakePart) StatusProviders() []bot.StatusProvider {
+	return []bot.StatusProvider{statusText(p.name)}
+}
+
+func (p *fakePart) NotifyAdmins(text string) {
+	p.alerts = append(p.alerts, text)
+}
+
+func (p *fakePart) AddStatusProvider(provider bot.StatusProvider) {
+	p.providers = append(p.providers, provider)
+}
+
+func (p *fakePart) reports() []string {
+	var reports []string
+	for _, provider := range p.providers {
+		reports = append(reports, provider.StatusReport())
+	}
+	return reports
+}
+
+func runAll(components []func(ctx context.Context)) {
+	for _, component := range components {
+		component(context.Background())
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAssembleCombined(t *testing.T) {
+	var runs []string
+	scraping := &fakePart{name: "scraping", runs: &runs}
+	scraperApp := &fakePart{name: "scraper", runs: &runs}
+	botApp := &fakePart{name: "bot", runs: &runs}
+	queued := 0
+
+	runAll(Assemble(scraping, scraperApp, botApp, func(string) { queued++ }))
+
+	if !equal(runs, []string{"scraping", "scraper", "bot"}) {
+		t.Errorf("Combined mode should run every part, got %v", runs)
+	}
+	scraping.alert("ban")
+	if len(botApp.alerts) != 1 || queued != 0 {
+		t.Error("Alerts should go straight to the bot")
+	}
+	if reports := botApp.reports(); !equal(reports, []string{"scraping", "scraper"}) {
+		t.Errorf("/status should cover scraping and the scraper, got %v", reports)
+	}
+}
+
+func TestAssembleBotOnly(t *testing.T) {
+	var runs []string
+	scraping := &fakePart{name: "scraping", runs: &runs}
+	botApp := &fakePart{name: "bot", runs: &runs}
+
+	runAll(Assemble(scraping, nil, botApp, nil))
+
+	if !equal(runs, []string{"scraping", "bot"}) {
+		t.Errorf("Bot mode should not scrape, got %v", runs)
+	}
+	scraping.alert("ban on /find")
+	if len(botApp.alerts) != 1 {
+		t.Error("Alerts from /find should go to the bot")
+	}
+	if reports := botApp.reports(); !equal(reports, []string{"scraping"}) {
+		t.Errorf("/status should cover scraping only, got %v", reports)
+	}
+}
+
+func TestAssembleScraperOnly(t *testing.T) {
+	var runs []string
+	scraping := &fakePart{name: "scraping", runs: &runs}
+	scraperApp := &fakePart{name: "scraper", runs: &runs}
+	var queued []string
+
+	runAll(Assemble(scraping, scraperApp, nil, func(text string) { queued = append(queued, text) }))
+
+	if !equal(runs, []string{"scraping", "scraper"}) {
+		t.Errorf("Scraper mode should not run the bot, got %v", runs)
+	}
+	scraping.alert("ban")
+	if !equal(queued, []string{"ban"}) {
+		t.Errorf("Alerts should be queued for the bot process, got %v", queued)
+	}
+}
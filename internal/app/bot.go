@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"olx-hunter/internal/bot"
+	"olx-hunter/internal/cache"
+	"olx-hunter/internal/config"
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/outbox"
+	"olx-hunter/internal/scraper"
+)
+
+// Bot - Telegram-бот разом з доставкою сповіщень з outbox
+type Bot struct {
+	*bot.Bot
+	dispatcher *outbox.Dispatcher
+}
+
+// NewBot створює бота. Зміни фільтрів бот лише зберігає в базі, звідки їх
+// бере скрапер.
+func NewBot(cfg *config.Config, db *database.DB, redisCache *cache.RedisCache, backends *scraper.Registry) (*Bot, error) {
+	log.Println("🤖 Starting Telegram Bot...")
+
+	telegramBot, err := bot.NewBot(cfg.BotToken, db, redisCache, backends, cfg.AdminIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatcher := outbox.NewDispatcher(db, telegramBot, outbox.Options{
+		PollInterval: time.Duration(cfg.OutboxPollInterval) * time.Second,
+		BatchSize:    20,
+		MaxAttempts:  cfg.OutboxMaxAttempts,
+		BaseDelay:    time.Duration(cfg.OutboxRetryDelay) * time.Second,
+		MaxDelay:     time.Hour,
+		Retention:    time.Duration(cfg.OutboxRetentionDays) * 24 * time.Hour,
+	})
+	telegramBot.AddStatusProvider(dispatcher)
+
+	return &Bot{Bot: telegramBot, dispatcher: dispatcher}, nil
+}
+
+// Run обробляє оновлення і доставляє сповіщення, поки не скасовано контекст
+func (b *Bot) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		b.Start(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		b.dispatcher.Run(ctx)
+	}()
+	wg.Wait()
+}
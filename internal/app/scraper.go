@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"olx-hunter/internal/bot"
+	"olx-hunter/internal/commands"
+	"olx-hunter/internal/config"
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/events"
+	"olx-hunter/internal/scraper"
+)
+
+// Scraper - періодичний скрапінг з перевіркою знятих оголошень, орендами,
+// подіями і командами з Kafka
+type Scraper struct {
+	service         *scraper.ScraperService
+	verifier        *scraper.ListingVerifier
+	leases          *scraper.LeaseCoordinator
+	changes         *scraper.FilterChangeFollower
+	publisher       events.Publisher
+	kafkaPublisher  *events.KafkaPublisher
+	commandHandler  *commands.Handler
+	commandConsumer *commands.KafkaConsumer
+}
+
+// NewScraper створює скрапер. Про зміни фільтрів він дізнається з бази, тож
+// бот і команди можуть працювати в іншому процесі.
+func NewScraper(cfg *config.Config, db *database.DB, scraping *Scraping) (*Scraper, error) {
+	s := &Scraper{}
+
+	// Події для аналітики публікуються, лише якщо задано брокери Kafka
+	if len(cfg.KafkaBrokers) > 0 {
+		s.kafkaPublisher = events.NewKafkaPublisher(cfg.KafkaBrokers, events.Topics{
+			Discovered:   cfg.KafkaTopicListings,
+			PriceChanged: cfg.KafkaTopicPrices,
+			Closed:       cfg.KafkaTopicClosed,
+		})
+		s.publisher = s.kafkaPublisher
+		log.Printf("Publishing listing events to Kafka: %v", cfg.KafkaBrokers)
+	}
+
+	serviceOpts := scraper.ServiceOptions{
+		WorkerCount:    cfg.WorkerCount,
+		MaxPages:       cfg.MaxPages,
+		FetchDetails:   cfg.FetchDetails,
+		ScrapeInterval: time.Duration(cfg.ScrapeInterval) * time.Second,
+		MinInterval:    time.Duration(cfg.MinInterval) * time.Second,
+		MaxInterval:    time.Duration(cfg.MaxInterval) * time.Second,
+		Budget:         scraping.Budget,
+		RepostWindow:   time.Duration(cfg.RepostWindowDays) * 24 * time.Hour,
+		Publisher:      s.publisher,
+	}
+	if cfg.RepostPhotoHash {
+		serviceOpts.PhotoClient = scraping.PhotoClient()
+	}
+
+	s.service = scraper.NewScraperService(db, scraping.Backends, serviceOpts)
+	s.verifier = scraper.NewListingVerifier(db, scraping.Backends, scraping.Budget, scraper.VerifierOptions{
+		Interval:     time.Duration(cfg.VerifyInterval) * time.Second,
+		StaleAfter:   time.Duration(cfg.VerifyStaleAfter) * time.Second,
+		RecheckAfter: time.Duration(cfg.VerifyRecheckAfter) * time.Second,
+		MaxAge:       time.Duration(cfg.VerifyMaxAge) * time.Second,
+		BatchSize:    cfg.VerifyBatchSize,
+		Publisher:    s.publisher,
+	})
+
+	s.changes = scraper.NewFilterChangeFollower(db, s.service, scraper.FollowerOptions{
+		DSN:               cfg.DatabaseDSN,
+		PollInterval:      time.Duration(cfg.FilterChangesPollInterval) * time.Second,
+		ReconcileInterval: time.Duration(cfg.FilterReconcileInterval) * time.Second,
+	})
+	if err := s.changes.Seek(); err != nil {
+		return nil, err
+	}
+
+	// З орендами фільтри розподіляє координатор, інакше беремо всі
+	if cfg.FilterLeases {
+		s.leases = scraper.NewLeaseCoordinator(db, s.service, cfg.InstanceID, time.Duration(cfg.LeaseTTL)*time.Second)
+	} else if err := s.service.LoadExistingFilters(); err != nil {
+		return nil, err
+	}
+
+	if cfg.KafkaCommands && len(cfg.KafkaBrokers) > 0 {
+		s.commandHandler = commands.NewHandler(db)
+		s.commandConsumer = commands.NewKafkaConsumer(cfg.KafkaBrokers, cfg.KafkaGroupID, cfg.KafkaCommandTopic, cfg.KafkaReplyTopic, s.commandHandler)
+	}
+
+	return s, nil
+}
+
+func (s *Scraper) StatusProviders() []bot.StatusProvider {
+	providers := []bot.StatusProvider{s.verifier, s.changes}
+	if s.leases != nil {
+		providers = append(providers, s.leases)
+	}
+	if s.kafkaPublisher != nil {
+		providers = append(providers, s.kafkaPublisher)
+	}
+	if s.commandHandler != nil {
+		providers = append(providers, s.commandHandler)
+	}
+	return providers
+}
+
+// Run скрапить, поки не скасовано контекст, і повертається, коли всі
+// запити завершено, а події дописано в Kafka
+func (s *Scraper) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	run := func(component func(ctx context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			component(ctx)
+		}()
+	}
+
+	run(s.service.StartPeriodicScraping)
+	run(s.verifier.Run)
+	if s.leases != nil {
+		run(s.leases.Run)
+	}
+	run(s.changes.Run)
+	if s.commandConsumer != nil {
+		run(s.commandConsumer.Run)
+	}
+
+	wg.Wait()
+	if s.publisher != nil {
+		if err := s.publisher.Close(); err != nil {
+			log.Printf("Failed to flush events: %v", err)
+		}
+	}
+	log.Println("Scraper stopped")
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"olx-hunter/internal/bot"
+	"olx-hunter/internal/cache"
+	"olx-hunter/internal/config"
+	"olx-hunter/internal/scraper"
+)
+
+// Scraping - доступ до OLX: бекенди з проксі, спільним бюджетом запитів і
+// запобіжником. Потрібен і скраперу, і боту для /find.
+type Scraping struct {
+	Backends      *scraper.Registry
+	Budget        *cache.RequestBudget
+	LayoutMonitor *scraper.LayoutMonitor
+	ProxyPool     *scraper.ProxyPool
+	Breaker       *scraper.CircuitBreaker
+
+	requestTimeout     time.Duration
+	proxyCheckInterval time.Duration
+}
+
+func NewScraping(cfg *config.Config, redisCache *cache.RedisCache) *Scraping {
+	budget := redisCache.NewRequestBudget("olx", cfg.BudgetPerMinute, cfg.BudgetBurst, time.Duration(cfg.BudgetMaxWait)*time.Second)
+
+	layoutMonitor := scraper.NewLayoutMonitor(cfg.SnapshotDir)
+
+	proxyPool := scraper.NewProxyPool(cfg.Proxies, cfg.ProxyCheckURL, time.Duration(cfg.ProxyEjectCooldown)*time.Second)
+	log.Printf("Proxy pool: %d proxies", proxyPool.Size())
+
+	transport := scraper.NewRotatingTransport(proxyPool, budget)
+	requestTimeout := time.Duration(cfg.RequestTimeout) * time.Second
+
+	breaker := scraper.NewCircuitBreaker(cfg.BreakerThreshold, time.Duration(cfg.BreakerCooldown)*time.Second)
+
+	backends := scraper.NewDefaultRegistry(cfg.ScraperBackend, scraper.Options{
+		BaseURL:        cfg.OLXBaseURL,
+		Rates:          cfg.CurrencyRates,
+		Monitor:        layoutMonitor,
+		Transport:      transport,
+		Breaker:        breaker,
+		RequestTimeout: requestTimeout,
+		Retry: scraper.RetryPolicy{
+			MaxAttempts: cfg.RetryMaxAttempts,
+			BaseDelay:   time.Duration(cfg.RetryBaseDelay) * time.Second,
+			MaxDelay:    30 * time.Second,
+		},
+	})
+
+	return &Scraping{
+		Backends:           backends,
+		Budget:             budget,
+		LayoutMonitor:      layoutMonitor,
+		ProxyPool:          proxyPool,
+		Breaker:            breaker,
+		requestTimeout:     requestTimeout,
+		proxyCheckInterval: time.Duration(cfg.ProxyCheckInterval) * time.Second,
+	}
+}
+
+// PhotoClient - окремий HTTP-клієнт для фото оголошень. Фото віддає CDN, а не
+// OLX, тож запити до нього не витрачають бюджет і не навантажують проксі.
+func (s *Scraping) PhotoClient() *http.Client {
+	return &http.Client{Timeout: s.requestTimeout}
+}
+
+// SetAlertHandler передає попередження про розмітку і бани адміністраторам
+func (s *Scraping) SetAlertHandler(alert func(text string)) {
+	s.LayoutMonitor.SetAlertHandler(alert)
+	s.Breaker.SetAlertHandler(alert)
+}
+
+func (s *Scraping) StatusProviders() []bot.StatusProvider {
+	return []bot.StatusProvider{s.Breaker, s.LayoutMonitor, s.ProxyPool, s.Budget}
+}
+
+// Run перевіряє проксі, поки не скасовано контекст
+func (s *Scraping) Run(ctx context.Context) {
+	s.ProxyPool.RunHealthChecks(ctx, s.proxyCheckInterval)
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// StatusProvider - компонент, стан якого адміністратори бачать у /status
+type StatusProvider interface {
+	StatusReport() string
+}
+
+func (b *Bot) AddStatusProvider(provider StatusProvider) {
+	b.statusProviders = append(b.statusProviders, provider)
+}
+
+func (b *Bot) isAdmin(telegramID int64) bool {
+	return b.adminIDs[telegramID]
+}
+
+// NotifyAdmins надсилає службове повідомлення всім адміністраторам
+func (b *Bot) NotifyAdmins(text string) {
+	if len(b.adminIDs) == 0 {
+		log.Printf("No admins configured, alert dropped: %s", text)
+		return
+	}
+	for adminID := range b.adminIDs {
+		b.sendMessage(adminID, text)
+	}
+}
+
+func (b *Bot) handleStatus(message *tgbotapi.Message) {
+	if !b.isAdmin(message.From.ID) {
+		b.handleUnknown(message)
+		return
+	}
+
+	if len(b.statusProviders) == 0 {
+		b.sendMessage(message.Chat.ID, "ℹ️ Немає даних про стан сервісу")
+		return
+	}
+
+	reports := make([]string, 0, len(b.statusProviders))
+	for _, provider := range b.statusProviders {
+		reports = append(reports, strings.TrimSpace(provider.StatusReport()))
+	}
+
+	b.sendMessage(message.Chat.ID, "📊 Стан сервісу\n\n"+strings.Join(reports, "\n\n"))
+}
@@ -1,33 +1,45 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
+	"time"
 
 	"olx-hunter/internal/cache"
 	"olx-hunter/internal/database"
 	"olx-hunter/internal/models"
+	"olx-hunter/internal/outbox"
 	"olx-hunter/internal/scraper"
+	"olx-hunter/internal/utils"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type Bot struct {
-	api     *tgbotapi.BotAPI
-	db      *database.DB
-	cache   *cache.RedisCache
-	scraper *scraper.ScraperService
-
-	pendingNotifications map[string][]models.Listing
-	lastNotifMessages    map[string]int // key: "chatID:filterName" -> message ID
-	notifMutex           sync.Mutex
-	notifCounter         int64
+	api      *tgbotapi.BotAPI
+	db       *database.DB
+	cache    *cache.RedisCache
+	backends *scraper.Registry
+
+	adminIDs        map[int64]bool
+	statusProviders []StatusProvider
+
+	lastNotifMessages map[string]int // key: "chatID:filterName" -> message ID
+	notifMutex        sync.Mutex
 }
 
+// findTimeout обмежує ручний пошук /find разом з повторами
+const findTimeout = 90 * time.Second
+
+// findBudgetWait - скільки /find чекає на токен зі спільного бюджету запитів,
+// щоб користувач не чекав, поки планувальник вибирає ліміт
+const findBudgetWait = 5 * time.Second
+
 type FilterCreationState struct {
 	Step int
 	Data map[string]string
@@ -35,7 +47,7 @@ type FilterCreationState struct {
 
 var creationStates = make(map[int64]*FilterCreationState)
 
-func NewBot(token string, db *database.DB, redisAddr string, scraperService *scraper.ScraperService) (*Bot, error) {
+func NewBot(token string, db *database.DB, redisCache *cache.RedisCache, backends *scraper.Registry, adminIDs []int64) (*Bot, error) {
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
@@ -43,28 +55,27 @@ func NewBot(token string, db *database.DB, redisAddr string, scraperService *scr
 
 	api.Debug = false
 
-	redisCache := cache.NewRedisCache(redisAddr)
+	log.Printf("Bot is authorized as: @%s", api.Self.UserName)
 
-	if err := redisCache.Ping(); err != nil {
-		log.Printf("Warning: Redis connection failed: %v", err)
-		log.Printf("Bot will work without caching!")
-	} else {
-		log.Printf("Redis connected successfully")
+	admins := make(map[int64]bool)
+	for _, id := range adminIDs {
+		admins[id] = true
 	}
 
-	log.Printf("Bot is authorized as: @%s", api.Self.UserName)
-
 	return &Bot{
-		api:                  api,
-		db:                   db,
-		cache:                redisCache,
-		scraper:              scraperService,
-		pendingNotifications: make(map[string][]models.Listing),
-		lastNotifMessages:    make(map[string]int),
+		api:               api,
+		db:                db,
+		cache:             redisCache,
+		backends:          backends,
+		adminIDs:          admins,
+		lastNotifMessages: make(map[string]int),
 	}, nil
 }
 
-func (b *Bot) Start() {
+// Start обробляє оновлення Telegram, поки не скасовано контекст. Після
+// скасування бот перестає отримувати оновлення і повертається, дочекавшись
+// обробки поточного.
+func (b *Bot) Start(ctx context.Context) {
 	updateConfig := tgbotapi.NewUpdate(0)
 	updateConfig.Timeout = 60
 
@@ -72,16 +83,26 @@ func (b *Bot) Start() {
 
 	log.Println("Bot is started! Waiting for message...")
 
-	for update := range updates {
-		if update.CallbackQuery != nil {
-			b.handleCallback(update.CallbackQuery)
-		} else if update.Message != nil {
-			b.handleMessage(update.Message)
+	for {
+		select {
+		case <-ctx.Done():
+			b.api.StopReceivingUpdates()
+			log.Println("Bot stopped receiving updates")
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			if update.CallbackQuery != nil {
+				b.handleCallback(update.CallbackQuery)
+			} else if update.Message != nil {
+				b.handleMessage(ctx, update.Message)
+			}
 		}
 	}
 }
 
-func (b *Bot) handleMessage(message *tgbotapi.Message) {
+func (b *Bot) handleMessage(ctx context.Context, message *tgbotapi.Message) {
 	user, err := b.db.CreateOrUpdateUser(
 		message.From.ID,
 		message.From.UserName,
@@ -106,11 +127,15 @@ func (b *Bot) handleMessage(message *tgbotapi.Message) {
 		case "create":
 			b.handleCreate(message)
 		case "find":
-			b.handleFind(message)
+			b.handleFind(ctx, message)
 		case "delete":
 			b.handleDelete(message)
 		case "toggle":
 			b.handleToggle(message)
+		case "set":
+			b.handleSet(message)
+		case "status":
+			b.handleStatus(message)
 		default:
 			b.handleUnknown(message)
 		}
@@ -157,6 +182,7 @@ func (b *Bot) handleHelp(message *tgbotapi.Message) {
 /delete [номер] - видалити фільтр
 /toggle [номер] - увімкнути/вимкнути фільтр
 /find [номер] - знайти оголошення по фільтру
+/set [номер] [параметр] [значення] - налаштування фільтра
 
 💡 Підказка: введи "-" щоб пропустити необов'язкові поля (ціна, місто)`
 
@@ -292,13 +318,6 @@ func (b *Bot) handleText(message *tgbotapi.Message) {
 
 		successText += "\n\n🟢 Фільтр активний і готовий до роботи!"
 
-		if b.scraper != nil {
-			filterWithUser, _ := b.db.GetFilterWithUser(createdFilter.ID, user.ID)
-			if filterWithUser != nil {
-				b.scraper.AddFilter(filterWithUser)
-			}
-		}
-
 		b.sendMessage(message.Chat.ID, successText)
 		delete(creationStates, message.From.ID)
 	}
@@ -369,7 +388,7 @@ func (b *Bot) handleCreate(message *tgbotapi.Message) {
 	b.sendMessage(message.Chat.ID, "📝 Введи назву фільтра:")
 }
 
-func (b *Bot) handleFind(message *tgbotapi.Message) {
+func (b *Bot) handleFind(ctx context.Context, message *tgbotapi.Message) {
 	args := strings.Fields(message.CommandArguments())
 
 	user, err := b.db.GetUserByTelegramID(message.From.ID)
@@ -416,7 +435,7 @@ func (b *Bot) handleFind(message *tgbotapi.Message) {
 		return
 	}
 
-	cacheKey := fmt.Sprintf("%s:%d:%d:%s", selectedFilter.Query, selectedFilter.MinPrice, selectedFilter.MaxPrice, selectedFilter.City)
+	cacheKey := fmt.Sprintf("%s:%d:%d:%s:%s:%s", selectedFilter.Query, selectedFilter.MinPrice, selectedFilter.MaxPrice, selectedFilter.City, selectedFilter.SortOrder, selectedFilter.Backend)
 
 	if cached, found := b.cache.GetCachedResults(cacheKey); found {
 		b.sendMessage(message.Chat.ID, "⚡ Результати з кешу (швидко!):")
@@ -424,22 +443,36 @@ func (b *Bot) handleFind(message *tgbotapi.Message) {
 		return
 	}
 
-	if !b.cache.CanScrapeQuery(selectedFilter.Query) {
-		b.sendMessage(message.Chat.ID, "⏰ Зачекай трохи перед наступним запитом (захист від бану)")
-		return
-	}
-
 	b.sendMessage(message.Chat.ID, "🔍 Шукаю оголошення по твоїх фільтрах...")
 
-	olxScraper := scraper.NewOLXScraper()
 	searchFilters := models.SearchFilters{
-		Query:    selectedFilter.Query,
-		MinPrice: selectedFilter.MinPrice,
-		MaxPrice: selectedFilter.MaxPrice,
-		City:     selectedFilter.City,
+		Query:     selectedFilter.Query,
+		MinPrice:  selectedFilter.MinPrice,
+		MaxPrice:  selectedFilter.MaxPrice,
+		City:      selectedFilter.City,
+		SortOrder: selectedFilter.SortOrder,
+
+		IncludeNegotiable: selectedFilter.IncludeNegotiable,
+		MaxAgeHours:       selectedFilter.MaxAgeHours,
+		SkipPromoted:      selectedFilter.SkipPromoted,
 	}
 
-	listings, err := olxScraper.SearchListings(searchFilters)
+	ctx, cancel := context.WithTimeout(cache.WithMaxWait(ctx, findBudgetWait), findTimeout)
+	defer cancel()
+
+	listings, err := b.backends.Get(selectedFilter.Backend).SearchListings(ctx, searchFilters)
+	if scraper.IsPartial(err) {
+		log.Printf("Partial results for filter %d: %v", selectedFilter.ID, err)
+		err = nil
+	}
+	if errors.Is(err, scraper.ErrCircuitOpen) {
+		b.sendMessage(message.Chat.ID, "⏸ OLX тимчасово обмежив доступ, пошук призупинено. Спробуй пізніше")
+		return
+	}
+	if errors.Is(err, cache.ErrBudgetExhausted) {
+		b.sendMessage(message.Chat.ID, "⏳ Зараз забагато запитів до OLX, спробуй через хвилину")
+		return
+	}
 	if err != nil {
 		log.Printf("Error scraping for filter %d: %v", selectedFilter.ID, err)
 		b.sendMessage(message.Chat.ID, "❌ Помилка пошуку на OLX")
@@ -488,10 +521,6 @@ func (b *Bot) handleDelete(message *tgbotapi.Message) {
 		return
 	}
 
-	if b.scraper != nil {
-		b.scraper.RemoveFilter(selected.ID)
-	}
-
 	b.sendMessage(message.Chat.ID, fmt.Sprintf("✅ Фільтр \"%s\" видалено!", selected.Name))
 }
 
@@ -536,17 +565,6 @@ func (b *Bot) handleToggle(message *tgbotapi.Message) {
 		return
 	}
 
-	if b.scraper != nil {
-		if selected.IsActive {
-			b.scraper.RemoveFilter(selected.ID)
-		} else {
-			filterWithUser, _ := b.db.GetFilterWithUser(selected.ID, user.ID)
-			if filterWithUser != nil {
-				b.scraper.AddFilter(filterWithUser)
-			}
-		}
-	}
-
 	newStatus := "🟢 активний"
 	if selected.IsActive {
 		newStatus = "🔴 неактивний"
@@ -554,42 +572,98 @@ func (b *Bot) handleToggle(message *tgbotapi.Message) {
 	b.sendMessage(message.Chat.ID, fmt.Sprintf("✅ Фільтр \"%s\" тепер %s", selected.Name, newStatus))
 }
 
-func (b *Bot) ListenNotifications(notifyCh <-chan models.Notification) {
-	log.Println("Listening for notifications...")
-	for notif := range notifyCh {
-		notifID := fmt.Sprintf("notif_%d", atomic.AddInt64(&b.notifCounter, 1))
-		filterKey := fmt.Sprintf("%d:%s", notif.TelegramID, notif.FilterName)
+// Deliver надсилає сповіщення з outbox. Помилка означає, що Telegram не
+// прийняв повідомлення і його треба повторити.
+func (b *Bot) Deliver(id uint, notif models.Notification) error {
+	if notif.Alert != "" {
+		_, err := b.send(tgbotapi.NewMessage(notif.TelegramID, notif.Alert))
+		return err
+	}
+	if len(notif.PriceDrops) > 0 {
+		return b.sendPriceDrops(notif)
+	}
+	if len(notif.Closed) > 0 {
+		return b.sendClosedListings(notif)
+	}
 
-		b.notifMutex.Lock()
-		if oldMsgID, exists := b.lastNotifMessages[filterKey]; exists {
-			del := tgbotapi.NewDeleteMessage(notif.TelegramID, oldMsgID)
-			b.api.Send(del)
-		}
-		b.pendingNotifications[notifID] = notif.Listings
-		b.notifMutex.Unlock()
-
-		text := fmt.Sprintf("🔔 Знайдено %d нових оголошень за фільтром \"%s\"!",
-			len(notif.Listings), notif.FilterName)
-
-		msg := tgbotapi.NewMessage(notif.TelegramID, text)
-		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData(
-					fmt.Sprintf("📋 Показати (%d)", len(notif.Listings)),
-					"show:"+notifID,
-				),
+	text := fmt.Sprintf("🔔 Знайдено %d нових оголошень за фільтром \"%s\"!",
+		len(notif.Listings), notif.FilterName)
+
+	// Кнопка посилається на сповіщення в outbox, тож працює і після перезапуску
+	msg := tgbotapi.NewMessage(notif.TelegramID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("📋 Показати (%d)", len(notif.Listings)),
+				fmt.Sprintf("show:%d", id),
 			),
-		)
+		),
+	)
 
-		sent, err := b.api.Send(msg)
-		if err != nil {
-			log.Printf("Error sending notification: %v", err)
-		} else {
-			b.notifMutex.Lock()
-			b.lastNotifMessages[filterKey] = sent.MessageID
-			b.notifMutex.Unlock()
+	sent, err := b.send(msg)
+	if err != nil {
+		return err
+	}
+
+	filterKey := fmt.Sprintf("%d:%s", notif.TelegramID, notif.FilterName)
+	b.notifMutex.Lock()
+	oldMsgID, exists := b.lastNotifMessages[filterKey]
+	b.lastNotifMessages[filterKey] = sent.MessageID
+	b.notifMutex.Unlock()
+
+	if exists {
+		b.api.Send(tgbotapi.NewDeleteMessage(notif.TelegramID, oldMsgID))
+	}
+	return nil
+}
+
+// send надсилає повідомлення і розрізняє помилки Telegram: заблокований
+// бот чи неіснуючий чат не варто повторювати, а flood control каже, скільки чекати
+func (b *Bot) send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	sent, err := b.api.Send(c)
+	if err == nil {
+		return sent, nil
+	}
+
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.RetryAfter > 0:
+			return sent, outbox.RetryAfter(err, time.Duration(apiErr.RetryAfter)*time.Second)
+		case apiErr.Code == 400 || apiErr.Code == 403:
+			return sent, outbox.Permanent(err)
+		}
+	}
+	return sent, err
+}
+
+// sendPriceDrops надсилає сповіщення про зниження цін одразу, без кнопки
+func (b *Bot) sendPriceDrops(notif models.Notification) error {
+	text := fmt.Sprintf("📉 Ціна знизилась за фільтром \"%s\":\n\n", notif.FilterName)
+	for i, drop := range notif.PriceDrops {
+		if i >= 10 {
+			text += fmt.Sprintf("... і ще %d оголошень\n", len(notif.PriceDrops)-10)
+			break
 		}
+		text += fmt.Sprintf("%d. %s\n💰 %s → %s (-%.0f%%)\n🔗 %s\n\n",
+			i+1, drop.Listing.Title, drop.OldPrice, drop.Listing.Price, drop.Percent, drop.Listing.URL)
 	}
+	_, err := b.send(tgbotapi.NewMessage(notif.TelegramID, text))
+	return err
+}
+
+// sendClosedListings повідомляє, що оголошення з фільтра продані або зняті
+func (b *Bot) sendClosedListings(notif models.Notification) error {
+	text := fmt.Sprintf("🏁 Знято з публікації за фільтром \"%s\":\n\n", notif.FilterName)
+	for i, listing := range notif.Closed {
+		if i >= 10 {
+			text += fmt.Sprintf("... і ще %d оголошень\n", len(notif.Closed)-10)
+			break
+		}
+		text += fmt.Sprintf("%d. %s\n💰 %s\n🔗 %s\n\n", i+1, listing.Title, listing.Price, listing.URL)
+	}
+	_, err := b.send(tgbotapi.NewMessage(notif.TelegramID, text))
+	return err
 }
 
 func (b *Bot) handleCallback(callback *tgbotapi.CallbackQuery) {
@@ -600,34 +674,113 @@ func (b *Bot) handleCallback(callback *tgbotapi.CallbackQuery) {
 		return
 	}
 
-	notifID := strings.TrimPrefix(callback.Data, "show:")
-
-	b.notifMutex.Lock()
-	listings, exists := b.pendingNotifications[notifID]
-	if exists {
-		delete(b.pendingNotifications, notifID)
-	}
-	b.notifMutex.Unlock()
-
-	del := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
+	chatID := callback.Message.Chat.ID
+	del := tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID)
 	b.api.Send(del)
 
-	if !exists || len(listings) == 0 {
-		b.sendMessage(callback.Message.Chat.ID, "⏳ Ці оголошення вже були показані або застаріли.")
+	listings := b.notificationListings(chatID, strings.TrimPrefix(callback.Data, "show:"))
+	if len(listings) == 0 {
+		b.sendMessage(chatID, "⏳ Ці оголошення вже були показані або застаріли.")
 		return
 	}
 
-	text := fmt.Sprintf("📋 Нові оголошення (%d):\n\n", len(listings))
+	b.sendMessage(chatID, fmt.Sprintf("📋 Нові оголошення (%d):", len(listings)))
+
 	for i, listing := range listings {
 		if i >= 10 {
-			text += fmt.Sprintf("... і ще %d оголошень\n", len(listings)-10)
+			b.sendMessage(chatID, fmt.Sprintf("... і ще %d оголошень", len(listings)-10))
 			break
 		}
-		text += fmt.Sprintf("%d. %s\n💰 %s\n📍 %s\n🔗 %s\n\n",
-			i+1, listing.Title, listing.Price, listing.Location, listing.URL)
+		b.sendListing(chatID, i+1, listing)
+	}
+}
+
+// notificationListings повертає оголошення сповіщення з outbox, якщо воно
+// ще зберігається і адресоване цьому чату
+func (b *Bot) notificationListings(chatID int64, rawID string) []models.Listing {
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	message, err := b.db.GetOutboxMessage(uint(id))
+	if err != nil {
+		log.Printf("Error loading notification %d: %v", id, err)
+		return nil
+	}
+	if message == nil || message.TelegramID != chatID {
+		return nil
+	}
+
+	notif, err := message.Notification()
+	if err != nil {
+		log.Printf("Notification %d is corrupted: %v", id, err)
+		return nil
+	}
+	return notif.Listings
+}
+
+// sendListing надсилає оголошення з першим фото, якщо воно є
+func (b *Bot) sendListing(chatID int64, num int, listing models.Listing) {
+	text := formatListing(num, listing)
+
+	if len(listing.Photos) == 0 {
+		b.sendMessage(chatID, text)
+		return
+	}
+
+	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(listing.Photos[0]))
+	photo.Caption = truncateText(text, 1024)
+	if _, err := b.api.Send(photo); err != nil {
+		log.Printf("Error sending photo, falling back to text: %v", err)
+		b.sendMessage(chatID, text)
+	}
+}
+
+func formatListing(num int, listing models.Listing) string {
+	title := listing.Title
+	if listing.Promoted {
+		title = "🔝 ТОП · " + title
+	}
+	text := fmt.Sprintf("%d. %s\n💰 %s\n", num, title, listing.Price)
+	if listing.Repost != nil {
+		text += fmt.Sprintf("♻️ Репост, раніше бачили %s за %s\n",
+			listing.Repost.SeenAt.In(utils.Kyiv).Format("02.01.2006"), listing.Repost.Price)
+	}
+
+	if listing.City != "" {
+		location := listing.City
+		if listing.District != "" {
+			location += ", " + listing.District
+		}
+		text += fmt.Sprintf("📍 %s\n", location)
+	} else {
+		text += fmt.Sprintf("📍 %s\n", listing.Location)
+	}
+	if !listing.PostedAt.IsZero() {
+		text += fmt.Sprintf("🕒 %s\n", listing.PostedAt.In(utils.Kyiv).Format("02.01.2006 15:04"))
 	}
 
-	b.sendMessage(callback.Message.Chat.ID, text)
+	if listing.SellerName != "" {
+		text += fmt.Sprintf("👤 %s\n", listing.SellerName)
+	}
+	if listing.Views > 0 {
+		text += fmt.Sprintf("👁 %d переглядів\n", listing.Views)
+	}
+	if listing.Description != "" {
+		text += fmt.Sprintf("📝 %s\n", truncateText(listing.Description, 300))
+	}
+
+	text += fmt.Sprintf("🔗 %s\n", listing.URL)
+	return text
+}
+
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
 }
 
 func (b *Bot) sendSearchResults(chatID int64, filterName string, listings []models.Listing) {
@@ -642,8 +795,7 @@ func (b *Bot) sendSearchResults(chatID int64, filterName string, listings []mode
 		if i >= 5 {
 			break
 		}
-		text += fmt.Sprintf("%d. %s\n💰 %s\n📍 %s\n🔗 %s\n\n",
-			i+1, listing.Title, listing.Price, listing.Location, listing.URL)
+		text += formatListing(i+1, listing) + "\n"
 	}
 
 	if len(listings) > 5 {
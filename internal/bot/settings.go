@@ -0,0 +1,291 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/scraper"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// filterOption описує один параметр фільтра, який можна змінити через /set
+type filterOption struct {
+	column      string
+	description string
+	parse       func(value string) (interface{}, error)
+	current     func(filter *database.UserFilter) string
+}
+
+var filterOptions = map[string]filterOption{
+	"pages": {
+		column:      "max_pages",
+		description: "скільки сторінок OLX переглядати (0 - за замовчуванням)",
+		parse:       parseIntOption(0, 10),
+		current: func(filter *database.UserFilter) string {
+			if filter.MaxPages == 0 {
+				return "за замовчуванням"
+			}
+			return strconv.Itoa(filter.MaxPages)
+		},
+	},
+	"sort": {
+		column:      "sort_order",
+		description: "сортування: new, cheap, expensive, relevance",
+		parse:       parseChoiceOption(sortChoices),
+		current: func(filter *database.UserFilter) string {
+			return choiceName(sortChoices, filter.SortOrder, "new")
+		},
+	},
+	"backend": {
+		column:      "backend",
+		description: "джерело даних: html (сторінка пошуку), api (JSON API) або default",
+		parse:       parseChoiceOption(backendChoices),
+		current: func(filter *database.UserFilter) string {
+			return choiceName(backendChoices, filter.Backend, "default")
+		},
+	},
+	"fresh": {
+		column:      "max_age_hours",
+		description: "тільки оголошення, опубліковані за останні N годин (0 - без обмежень)",
+		parse:       parseIntOption(0, 24*30),
+		current: func(filter *database.UserFilter) string {
+			if filter.MaxAgeHours == 0 {
+				return "без обмежень"
+			}
+			return fmt.Sprintf("%d год", filter.MaxAgeHours)
+		},
+	},
+	"promoted": {
+		column:      "skip_promoted",
+		description: "рекламні (ТОП) оголошення: skip - пропускати, label - показувати з позначкою",
+		parse:       parseChoiceOption(promotedChoices),
+		current: func(filter *database.UserFilter) string {
+			return choiceName(promotedChoices, filter.SkipPromoted, "label")
+		},
+	},
+	"interval": {
+		column:      "scrape_interval",
+		description: "як часто перевіряти фільтр, у хвилинах (0 - за замовчуванням, межі задає сервіс)",
+		parse:       parseMinutesOption(0, 24*60),
+		current: func(filter *database.UserFilter) string {
+			if filter.ScrapeInterval == 0 {
+				return "за замовчуванням"
+			}
+			return fmt.Sprintf("%d хв", filter.ScrapeInterval/60)
+		},
+	},
+	"pricedrop": {
+		column:      "price_alerts",
+		description: "сповіщати, коли оголошення з фільтра дешевшає: on/off",
+		parse:       parseBoolOption,
+		current: func(filter *database.UserFilter) string {
+			return boolName(filter.PriceAlerts)
+		},
+	},
+	"mindrop": {
+		column:      "min_price_drop",
+		description: "мінімальне зниження ціни для сповіщення, у відсотках (0 - будь-яке)",
+		parse:       parseIntOption(0, 99),
+		current: func(filter *database.UserFilter) string {
+			if filter.MinPriceDrop == 0 {
+				return "будь-яке"
+			}
+			return fmt.Sprintf("%d%%", filter.MinPriceDrop)
+		},
+	},
+	"closed": {
+		column:      "closed_alerts",
+		description: "сповіщати, коли показане оголошення продали або зняли з публікації: on/off",
+		parse:       parseBoolOption,
+		current: func(filter *database.UserFilter) string {
+			return boolName(filter.ClosedAlerts)
+		},
+	},
+	"repost": {
+		column:      "repost_mode",
+		description: "повторно викладені оголошення: tag - з позначкою, skip - пропускати, off - не перевіряти",
+		parse:       parseChoiceOption(repostChoices),
+		current: func(filter *database.UserFilter) string {
+			return choiceName(repostChoices, filter.RepostMode, "tag")
+		},
+	},
+	"negotiable": {
+		column:      "include_negotiable",
+		description: "показувати оголошення з ціною \"Договірна\": on/off",
+		parse:       parseBoolOption,
+		current: func(filter *database.UserFilter) string {
+			return boolName(filter.IncludeNegotiable)
+		},
+	},
+}
+
+var backendChoices = map[string]string{
+	"default":           "",
+	scraper.BackendHTML: scraper.BackendHTML,
+	scraper.BackendAPI:  scraper.BackendAPI,
+}
+
+var promotedChoices = map[string]bool{
+	"skip":  true,
+	"label": false,
+}
+
+var repostChoices = map[string]string{
+	"tag":  scraper.RepostTag,
+	"skip": scraper.RepostSkip,
+	"off":  scraper.RepostOff,
+}
+
+var sortChoices = map[string]string{
+	"new":       scraper.SortNewest,
+	"cheap":     scraper.SortCheapest,
+	"expensive": scraper.SortExpensive,
+	"relevance": scraper.SortRelevance,
+}
+
+func parseChoiceOption[T any](choices map[string]T) func(string) (interface{}, error) {
+	return func(value string) (interface{}, error) {
+		stored, ok := choices[value]
+		if !ok {
+			names := make([]string, 0, len(choices))
+			for name := range choices {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("допустимі значення: %s", strings.Join(names, ", "))
+		}
+		return stored, nil
+	}
+}
+
+// choiceName повертає назву варіанту за збереженим значенням
+func choiceName[T comparable](choices map[string]T, stored T, fallback string) string {
+	for name, value := range choices {
+		if value == stored {
+			return name
+		}
+	}
+	return fallback
+}
+
+func parseBoolOption(value string) (interface{}, error) {
+	switch value {
+	case "on", "yes", "так", "1":
+		return true, nil
+	case "off", "no", "ні", "0":
+		return false, nil
+	}
+	return nil, fmt.Errorf("допустимі значення: on, off")
+}
+
+func boolName(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
+}
+
+func parseIntOption(min, max int) func(string) (interface{}, error) {
+	return func(value string) (interface{}, error) {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("значення має бути числом")
+		}
+		if parsed < min || parsed > max {
+			return nil, fmt.Errorf("значення має бути від %d до %d", min, max)
+		}
+		return parsed, nil
+	}
+}
+
+// parseMinutesOption приймає хвилини, а зберігає секунди
+func parseMinutesOption(min, max int) func(string) (interface{}, error) {
+	parseMinutes := parseIntOption(min, max)
+	return func(value string) (interface{}, error) {
+		minutes, err := parseMinutes(value)
+		if err != nil {
+			return nil, err
+		}
+		return minutes.(int) * 60, nil
+	}
+}
+
+func sortedOptionNames() []string {
+	names := make([]string, 0, len(filterOptions))
+	for name := range filterOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (b *Bot) handleSet(message *tgbotapi.Message) {
+	user, err := b.db.GetUserByTelegramID(message.From.ID)
+	if err != nil || user == nil {
+		b.sendMessage(message.Chat.ID, "❌ Помилка отримання даних користувача")
+		return
+	}
+
+	filters, err := b.db.GetUserFilters(user.ID)
+	if err != nil || len(filters) == 0 {
+		b.sendMessage(message.Chat.ID, "📝 У тебе немає фільтрів.")
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		text := "⚙️ Доступні налаштування фільтра:\n\n"
+		for _, name := range sortedOptionNames() {
+			text += fmt.Sprintf("• `%s` - %s\n", name, filterOptions[name].description)
+		}
+		text += "\n📝 Використання: /set 1 pages 3\n👀 Поточні значення: /set 1"
+		b.sendMessage(message.Chat.ID, text)
+		return
+	}
+
+	num, err := strconv.Atoi(args[0])
+	if err != nil || num < 1 || num > len(filters) {
+		b.sendMessage(message.Chat.ID, fmt.Sprintf("❌ Невірний номер. Використай від 1 до %d", len(filters)))
+		return
+	}
+	selected := filters[num-1]
+
+	if len(args) == 1 {
+		text := fmt.Sprintf("⚙️ Налаштування фільтра \"%s\":\n\n", selected.Name)
+		for _, name := range sortedOptionNames() {
+			text += fmt.Sprintf("• `%s`: %s\n", name, filterOptions[name].current(selected))
+		}
+		b.sendMessage(message.Chat.ID, text)
+		return
+	}
+
+	if len(args) < 3 {
+		b.sendMessage(message.Chat.ID, "📝 Використання: /set [номер] [параметр] [значення]")
+		return
+	}
+
+	option, exists := filterOptions[strings.ToLower(args[1])]
+	if !exists {
+		b.sendMessage(message.Chat.ID, "❓ Невідомий параметр. Використай /set щоб побачити список.")
+		return
+	}
+
+	value, err := option.parse(strings.ToLower(strings.Join(args[2:], " ")))
+	if err != nil {
+		b.sendMessage(message.Chat.ID, "❌ "+err.Error())
+		return
+	}
+
+	if err := b.db.UpdateFilterOption(selected.ID, user.ID, option.column, value); err != nil {
+		log.Printf("Error updating filter option: %v", err)
+		b.sendMessage(message.Chat.ID, "❌ Помилка збереження налаштування")
+		return
+	}
+
+	b.sendMessage(message.Chat.ID, fmt.Sprintf("✅ Фільтр \"%s\": `%s` оновлено", selected.Name, args[1]))
+}
@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrBudgetExhausted повертається, якщо токен не вдалося отримати
+// за допустимий час очікування
+var ErrBudgetExhausted = errors.New("OLX request budget exhausted")
+
+// tokenBucketScript - атомарний токен-бакет у Redis. Час береться з самого
+// Redis, щоб усі інстанси рахували однаково.
+// ARGV: швидкість (токенів/с), місткість, скільки взяти (0 - лише подивитись)
+// Повертає: {взято (0/1), мс до появи потрібних токенів, токенів лишилось}
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local requested = tonumber(ARGV[3])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+
+local taken = 0
+local wait = 0
+if tokens >= requested then
+	tokens = tokens - requested
+	taken = 1
+else
+	wait = math.ceil((requested - tokens) * 1000 / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {taken, wait, math.floor(tokens)}
+`)
+
+type maxWaitKey struct{}
+
+// WithMaxWait задає, скільки викликач готовий чекати на токен. Бот
+// використовує коротке очікування, щоб /find не зависав.
+func WithMaxWait(ctx context.Context, maxWait time.Duration) context.Context {
+	return context.WithValue(ctx, maxWaitKey{}, maxWait)
+}
+
+// BudgetStats - лічильники використання бюджету
+type BudgetStats struct {
+	Acquired    int64
+	Waited      int64
+	Refused     int64
+	RedisErrors int64
+	WaitTime    time.Duration
+}
+
+// RequestBudget - спільний для всіх воркерів, інстансів і /find ліміт
+// запитів до OLX (токен-бакет у Redis). Якщо Redis недоступний, працює
+// локальний бакет з тими самими параметрами.
+type RequestBudget struct {
+	client  *redis.Client
+	key     string
+	rate    float64 // токенів на секунду
+	burst   int
+	maxWait time.Duration
+
+	local *localBucket
+
+	acquired    int64
+	waited      int64
+	refused     int64
+	redisErrors int64
+	waitNanos   int64
+}
+
+// NewRequestBudget створює бюджет на perMinute запитів з місткістю burst
+func (r *RedisCache) NewRequestBudget(name string, perMinute, burst int, maxWait time.Duration) *RequestBudget {
+	if perMinute < 1 {
+		perMinute = 30
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	rate := float64(perMinute) / 60
+
+	return &RequestBudget{
+		client:  r.client,
+		key:     fmt.Sprintf("budget:%s", name),
+		rate:    rate,
+		burst:   burst,
+		maxWait: maxWait,
+		local:   newLocalBucket(rate, burst),
+	}
+}
+
+// take пробує взяти n токенів і повертає час до появи потрібних токенів
+func (b *RequestBudget) take(ctx context.Context, n int) (bool, time.Duration, int) {
+	result, err := tokenBucketScript.Run(ctx, b.client, []string{b.key}, b.rate, b.burst, n).Int64Slice()
+	if err != nil || len(result) != 3 {
+		if atomic.AddInt64(&b.redisErrors, 1) == 1 {
+			log.Printf("Request budget falls back to a local bucket: %v", err)
+		}
+		return b.local.take(time.Now(), n)
+	}
+	return result[0] == 1, time.Duration(result[1]) * time.Millisecond, int(result[2])
+}
+
+// Acquire чекає на токен для одного запиту. Очікування обмежене контекстом
+// та WithMaxWait (або значенням за замовчуванням).
+func (b *RequestBudget) Acquire(ctx context.Context) error {
+	maxWait := b.maxWait
+	if wait, ok := ctx.Value(maxWaitKey{}).(time.Duration); ok {
+		maxWait = wait
+	}
+
+	start := time.Now()
+	deadline := start.Add(maxWait)
+	waited := false
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ok, wait, _ := b.take(ctx, 1)
+		if ok {
+			atomic.AddInt64(&b.acquired, 1)
+			if waited {
+				atomic.AddInt64(&b.waited, 1)
+				atomic.AddInt64(&b.waitNanos, int64(time.Since(start)))
+			}
+			return nil
+		}
+
+		if time.Now().Add(wait).After(deadline) {
+			atomic.AddInt64(&b.refused, 1)
+			return ErrBudgetExhausted
+		}
+
+		waited = true
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Available повертає кількість токенів, доступних прямо зараз
+func (b *RequestBudget) Available(ctx context.Context) int {
+	_, _, tokens := b.take(ctx, 0)
+	return tokens
+}
+
+func (b *RequestBudget) Stats() BudgetStats {
+	return BudgetStats{
+		Acquired:    atomic.LoadInt64(&b.acquired),
+		Waited:      atomic.LoadInt64(&b.waited),
+		Refused:     atomic.LoadInt64(&b.refused),
+		RedisErrors: atomic.LoadInt64(&b.redisErrors),
+		WaitTime:    time.Duration(atomic.LoadInt64(&b.waitNanos)),
+	}
+}
+
+// StatusReport - стан бюджету для команди /status
+func (b *RequestBudget) StatusReport() string {
+	stats := b.Stats()
+
+	text := fmt.Sprintf("🎫 Бюджет запитів OLX: %.0f/хв, запас %d (зараз %d)\n",
+		b.rate*60, b.burst, b.Available(context.Background()))
+	text += fmt.Sprintf("   видано: %d, з очікуванням: %d, відмов: %d\n",
+		stats.Acquired, stats.Waited, stats.Refused)
+	if stats.Waited > 0 {
+		text += fmt.Sprintf("   середнє очікування: %v\n",
+			(stats.WaitTime / time.Duration(stats.Waited)).Round(time.Millisecond))
+	}
+	if stats.RedisErrors > 0 {
+		text += fmt.Sprintf("   ⚠️ помилок Redis (локальний ліміт): %d\n", stats.RedisErrors)
+	}
+	return text
+}
+
+// localBucket - токен-бакет у пам'яті на випадок недоступного Redis
+type localBucket struct {
+	rate  float64
+	burst int
+
+	mutex  sync.Mutex
+	tokens float64
+	ts     time.Time
+}
+
+func newLocalBucket(rate float64, burst int) *localBucket {
+	return &localBucket{rate: rate, burst: burst, tokens: float64(burst)}
+}
+
+func (l *localBucket) take(now time.Time, n int) (bool, time.Duration, int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if !l.ts.IsZero() && now.After(l.ts) {
+		l.tokens = math.Min(float64(l.burst), l.tokens+now.Sub(l.ts).Seconds()*l.rate)
+	}
+	l.ts = now
+
+	if l.tokens >= float64(n) {
+		l.tokens -= float64(n)
+		return true, 0, int(l.tokens)
+	}
+
+	missing := float64(n) - l.tokens
+	wait := time.Duration(math.Ceil(missing / l.rate * float64(time.Second)))
+	return false, wait, int(l.tokens)
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// testRedis підключається до Redis з REDIS_ADDR або пропускає тест
+func testRedis(t *testing.T) *RedisCache {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	r := NewRedisCache(addr)
+	if err := r.Ping(); err != nil {
+		t.Skipf("Redis is not available at %s: %v", addr, err)
+	}
+	return r
+}
+
+// offlineBudget працює на локальному бакеті, бо Redis за адресою немає
+func offlineBudget(perMinute, burst int, maxWait time.Duration) *RequestBudget {
+	return NewRedisCache("127.0.0.1:1").NewRequestBudget("offline", perMinute, burst, maxWait)
+}
+
+func TestLocalBucketRefills(t *testing.T) {
+	bucket := newLocalBucket(1, 2) // 1 токен на секунду, місткість 2
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := bucket.take(now, 1); !ok {
+			t.Fatalf("Token %d should be available from the burst", i+1)
+		}
+	}
+
+	ok, wait, left := bucket.take(now, 1)
+	if ok || left != 0 {
+		t.Fatalf("Bucket should be empty, got ok=%v left=%d", ok, left)
+	}
+	if wait != time.Second {
+		t.Errorf("Expected to wait 1s for the next token, got %v", wait)
+	}
+
+	if ok, _, _ := bucket.take(now.Add(1500*time.Millisecond), 1); !ok {
+		t.Error("Token should be refilled after the wait")
+	}
+	if _, _, left := bucket.take(now.Add(time.Hour), 0); left != 2 {
+		t.Errorf("Refill should stop at the burst size, got %d", left)
+	}
+}
+
+func TestRedisTokenBucket(t *testing.T) {
+	r := testRedis(t)
+	ctx := context.Background()
+
+	// 60 токенів на хвилину - один на секунду, місткість 2
+	budget := r.NewRequestBudget(fmt.Sprintf("test:%d", time.Now().UnixNano()), 60, 2, time.Second)
+	t.Cleanup(func() { r.client.Del(ctx, budget.key) })
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := budget.take(ctx, 1); !ok {
+			t.Fatalf("Token %d should be available from the burst", i+1)
+		}
+	}
+
+	ok, wait, left := budget.take(ctx, 1)
+	if ok || left != 0 {
+		t.Fatalf("Bucket should be empty, got ok=%v left=%d", ok, left)
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("Expected to wait up to 1s for the next token, got %v", wait)
+	}
+
+	// Інший екземпляр з тим самим ключем бачить той самий бакет
+	other := r.NewRequestBudget(budget.key[len("budget:"):], 60, 2, time.Second)
+	if other.Available(ctx) != 0 {
+		t.Error("Budget should be shared by every instance using the key")
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if ok, _, _ := other.take(ctx, 1); !ok {
+		t.Error("Token should be refilled after the wait")
+	}
+	if budget.Stats().RedisErrors != 0 || other.Stats().RedisErrors != 0 {
+		t.Error("Script should run in Redis without falling back")
+	}
+}
+
+func TestAcquireWaitsForToken(t *testing.T) {
+	// Два токени на секунду, місткість 1
+	budget := offlineBudget(120, 1, 5*time.Second)
+
+	if err := budget.Acquire(context.Background()); err != nil {
+		t.Fatal("First token should be available:", err)
+	}
+	if err := budget.Acquire(context.Background()); err != nil {
+		t.Fatal("Acquire should wait for the refill:", err)
+	}
+
+	stats := budget.Stats()
+	if stats.Acquired != 2 || stats.Waited != 1 || stats.WaitTime <= 0 {
+		t.Errorf("Expected the second token to be waited for, got %+v", stats)
+	}
+}
+
+func TestAcquireTimesOut(t *testing.T) {
+	// Один токен на хвилину: наступний не з'явиться за час тесту
+	budget := offlineBudget(1, 1, time.Minute)
+	if err := budget.Acquire(context.Background()); err != nil {
+		t.Fatal("First token should be available:", err)
+	}
+
+	start := time.Now()
+	err := budget.Acquire(WithMaxWait(context.Background(), 50*time.Millisecond))
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("Expected ErrBudgetExhausted, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("Acquire should give up without waiting for the refill")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := budget.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context deadline, got %v", err)
+	}
+
+	if stats := budget.Stats(); stats.Acquired != 1 || stats.Refused != 1 {
+		t.Errorf("Expected 1 acquired and 1 refused token, got %+v", stats)
+	}
+}
@@ -60,12 +60,3 @@ func (r *RedisCache) GetCachedResults(query string) ([]models.Listing, bool) {
 
 	return results, true
 }
-
-func (r *RedisCache) CanScrapeQuery(query string) bool {
-	key := fmt.Sprintf("rate_limit:manual:%s", query)
-	count := r.client.Incr(r.ctx, key).Val()
-	if count == 1 {
-		r.client.Expire(r.ctx, key, 30*time.Second)
-	}
-	return count <= 3
-}
\ No newline at end of file
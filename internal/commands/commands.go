@@ -0,0 +1,363 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"olx-hunter/internal/database"
+)
+
+// SchemaVersion - версія формату команд і відповідей
+const SchemaVersion = 1
+
+// Типи команд
+const (
+	TypeCreate = "filter.create"
+	TypeUpdate = "filter.update"
+	TypePause  = "filter.pause"
+	TypeResume = "filter.resume"
+	TypeDelete = "filter.delete"
+)
+
+// Статуси відповіді. Rejected - команда некоректна і повтор не допоможе,
+// Failed - тимчасова помилка, команду можна надіслати ще раз з тим самим ключем.
+const (
+	StatusOK       = "ok"
+	StatusRejected = "rejected"
+	StatusFailed   = "failed"
+)
+
+// Обмеження полів відповідають розмірам колонок у базі
+const (
+	maxKeyLength   = 100
+	maxNameLength  = 100
+	maxQueryLength = 100
+	maxCityLength  = 50
+)
+
+// Command - команда керування фільтром від зовнішньої системи
+type Command struct {
+	IdempotencyKey string      `json:"idempotency_key"`
+	Type           string      `json:"type"`
+	Version        int         `json:"version"`
+	TelegramID     int64       `json:"telegram_id"`
+	FilterID       uint        `json:"filter_id,omitempty"`
+	Filter         *FilterSpec `json:"filter,omitempty"`
+}
+
+// FilterSpec - поля фільтра. В update відсутні поля лишаються без змін.
+type FilterSpec struct {
+	Name     *string `json:"name,omitempty"`
+	Query    *string `json:"query,omitempty"`
+	MinPrice *int    `json:"min_price,omitempty"`
+	MaxPrice *int    `json:"max_price,omitempty"`
+	City     *string `json:"city,omitempty"`
+}
+
+// Reply - результат команди, надсилається в топік відповідей
+type Reply struct {
+	IdempotencyKey string    `json:"idempotency_key"`
+	Type           string    `json:"type"`
+	Version        int       `json:"version"`
+	Status         string    `json:"status"`
+	FilterID       uint      `json:"filter_id,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	Duplicate      bool      `json:"duplicate,omitempty"`
+	ProcessedAt    time.Time `json:"processed_at"`
+}
+
+// rejection - помилка в самій команді
+type rejection struct {
+	msg string
+}
+
+func (r *rejection) Error() string {
+	return r.msg
+}
+
+func reject(format string, args ...interface{}) error {
+	return &rejection{msg: fmt.Sprintf(format, args...)}
+}
+
+// Handler застосовує команди через ті самі функції бази, що й бот. Скрапер
+// дізнається про зміни фільтрів з бази, як і про зміни з бота.
+type Handler struct {
+	db *database.DB
+
+	mutex      sync.Mutex
+	applied    int
+	rejected   int
+	failed     int
+	duplicates int
+}
+
+func NewHandler(db *database.DB) *Handler {
+	return &Handler{db: db}
+}
+
+// HandleMessage розбирає JSON команди і застосовує її
+func (h *Handler) HandleMessage(value []byte) Reply {
+	var cmd Command
+	if err := json.Unmarshal(value, &cmd); err != nil {
+		reply := newReply(cmd)
+		reply.Status = StatusRejected
+		reply.Error = "invalid JSON: " + err.Error()
+		h.count(reply)
+		return reply
+	}
+	return h.Handle(cmd)
+}
+
+// Handle застосовує команду один раз на ключ ідемпотентності. Повтор
+// отримує збережену відповідь з Duplicate=true.
+func (h *Handler) Handle(cmd Command) Reply {
+	reply := newReply(cmd)
+
+	if err := validate(cmd); err != nil {
+		reply.Status = StatusRejected
+		reply.Error = err.Error()
+		h.count(reply)
+		return reply
+	}
+
+	stored, duplicate, err := h.db.ApplyCommand(cmd.IdempotencyKey, cmd.Type, func(tx *database.DB) (string, error) {
+		filterID, err := apply(tx, cmd)
+		if err != nil {
+			var rejected *rejection
+			if !errors.As(err, &rejected) {
+				return "", err
+			}
+			// Відмову теж запам'ятовуємо: повтор команди дасть той самий результат
+			reply.Status = StatusRejected
+			reply.Error = rejected.msg
+		} else {
+			reply.Status = StatusOK
+		}
+		reply.FilterID = filterID
+		encoded, err := json.Marshal(reply)
+		return string(encoded), err
+	})
+	if err != nil {
+		log.Printf("Failed to apply command %s (%s): %v", cmd.IdempotencyKey, cmd.Type, err)
+		reply.Status = StatusFailed
+		reply.Error = "internal error, retry with the same idempotency key"
+		reply.FilterID = 0
+		h.count(reply)
+		return reply
+	}
+
+	if duplicate {
+		if err := json.Unmarshal([]byte(stored), &reply); err != nil {
+			log.Printf("Failed to decode stored reply for %s: %v", cmd.IdempotencyKey, err)
+		}
+		reply.Duplicate = true
+		h.count(reply)
+		return reply
+	}
+
+	h.count(reply)
+	return reply
+}
+
+func newReply(cmd Command) Reply {
+	return Reply{
+		IdempotencyKey: cmd.IdempotencyKey,
+		Type:           cmd.Type,
+		Version:        SchemaVersion,
+		FilterID:       cmd.FilterID,
+		ProcessedAt:    time.Now().UTC(),
+	}
+}
+
+// validate перевіряє команду без звернення до бази
+func validate(cmd Command) error {
+	if cmd.IdempotencyKey == "" {
+		return reject("idempotency_key is required")
+	}
+	if len(cmd.IdempotencyKey) > maxKeyLength {
+		return reject("idempotency_key is longer than %d characters", maxKeyLength)
+	}
+	if cmd.Version != SchemaVersion {
+		return reject("unsupported version %d, expected %d", cmd.Version, SchemaVersion)
+	}
+	if cmd.TelegramID == 0 {
+		return reject("telegram_id is required")
+	}
+
+	switch cmd.Type {
+	case TypeCreate:
+		if cmd.FilterID != 0 {
+			return reject("filter_id must be empty for %s", cmd.Type)
+		}
+		if cmd.Filter == nil || cmd.Filter.Name == nil || cmd.Filter.Query == nil {
+			return reject("filter.name and filter.query are required")
+		}
+		return validateFilter(specValues(cmd.Filter, &database.UserFilter{}))
+	case TypeUpdate:
+		if cmd.FilterID == 0 {
+			return reject("filter_id is required")
+		}
+		if cmd.Filter == nil || *cmd.Filter == (FilterSpec{}) {
+			return reject("filter must contain at least one field")
+		}
+		return nil
+	case TypePause, TypeResume, TypeDelete:
+		if cmd.FilterID == 0 {
+			return reject("filter_id is required")
+		}
+		return nil
+	default:
+		return reject("unknown command type %q", cmd.Type)
+	}
+}
+
+// specValues накладає задані поля spec на фільтр
+func specValues(spec *FilterSpec, filter *database.UserFilter) database.UserFilter {
+	values := *filter
+	if spec.Name != nil {
+		values.Name = strings.TrimSpace(*spec.Name)
+	}
+	if spec.Query != nil {
+		values.Query = strings.TrimSpace(*spec.Query)
+	}
+	if spec.MinPrice != nil {
+		values.MinPrice = *spec.MinPrice
+	}
+	if spec.MaxPrice != nil {
+		values.MaxPrice = *spec.MaxPrice
+	}
+	if spec.City != nil {
+		values.City = strings.TrimSpace(*spec.City)
+	}
+	return values
+}
+
+// validateFilter застосовує до полів ті самі правила, що й /create
+func validateFilter(filter database.UserFilter) error {
+	switch {
+	case filter.Name == "":
+		return reject("filter.name must not be empty")
+	case filter.Query == "":
+		return reject("filter.query must not be empty")
+	case utf8.RuneCountInString(filter.Name) > maxNameLength:
+		return reject("filter.name is longer than %d characters", maxNameLength)
+	case utf8.RuneCountInString(filter.Query) > maxQueryLength:
+		return reject("filter.query is longer than %d characters", maxQueryLength)
+	case utf8.RuneCountInString(filter.City) > maxCityLength:
+		return reject("filter.city is longer than %d characters", maxCityLength)
+	case filter.MinPrice < 0 || filter.MaxPrice < 0:
+		return reject("prices must not be negative")
+	case filter.MaxPrice > 0 && filter.MinPrice > filter.MaxPrice:
+		return reject("min_price must not exceed max_price")
+	}
+	return nil
+}
+
+// filterStore - функції бази, якими команди змінюють фільтри. Його реалізує *database.DB.
+type filterStore interface {
+	GetUserByTelegramID(telegramID int64) (*database.User, error)
+	GetUserFilters(userID uint) ([]*database.UserFilter, error)
+	GetFilterByID(filterID, userID uint) (*database.UserFilter, error)
+	CreateFilter(userID uint, name, query string, minPrice, maxPrice int, city string) (*database.UserFilter, error)
+	UpdateFilter(filterID, userID uint, name, query string, minPrice, maxPrice int, city string) error
+	UpdateFilterOption(filterID, userID uint, column string, value interface{}) error
+	DeleteFilter(filterID, userID uint) error
+}
+
+// checkName відхиляє назву, яку вже має інший фільтр користувача. Інакше
+// UNIQUE(user_id, name) давав би тимчасову помилку на кожен повтор команди.
+func checkName(tx filterStore, userID, filterID uint, name string) error {
+	filters, err := tx.GetUserFilters(userID)
+	if err != nil {
+		return err
+	}
+	for _, filter := range filters {
+		if filter.ID != filterID && filter.Name == name {
+			return reject("filter name %q already exists", name)
+		}
+	}
+	return nil
+}
+
+// apply змінює фільтр у транзакції tx і повертає ID фільтра
+func apply(tx filterStore, cmd Command) (uint, error) {
+	user, err := tx.GetUserByTelegramID(cmd.TelegramID)
+	if err != nil {
+		return 0, err
+	}
+	if user == nil {
+		return 0, reject("user %d has not started the bot", cmd.TelegramID)
+	}
+
+	if cmd.Type == TypeCreate {
+		values := specValues(cmd.Filter, &database.UserFilter{})
+		if err := checkName(tx, user.ID, 0, values.Name); err != nil {
+			return 0, err
+		}
+		filter, err := tx.CreateFilter(user.ID, values.Name, values.Query, values.MinPrice, values.MaxPrice, values.City)
+		if err != nil {
+			return 0, err
+		}
+		return filter.ID, nil
+	}
+
+	filter, err := tx.GetFilterByID(cmd.FilterID, user.ID)
+	if err != nil {
+		return 0, err
+	}
+	if filter == nil {
+		return 0, reject("filter %d of user %d not found", cmd.FilterID, cmd.TelegramID)
+	}
+
+	switch cmd.Type {
+	case TypeUpdate:
+		values := specValues(cmd.Filter, filter)
+		if err := validateFilter(values); err != nil {
+			return 0, err
+		}
+		if values.Name != filter.Name {
+			if err := checkName(tx, user.ID, filter.ID, values.Name); err != nil {
+				return 0, err
+			}
+		}
+		err = tx.UpdateFilter(filter.ID, user.ID, values.Name, values.Query, values.MinPrice, values.MaxPrice, values.City)
+	case TypePause:
+		err = tx.UpdateFilterOption(filter.ID, user.ID, "is_active", false)
+	case TypeResume:
+		err = tx.UpdateFilterOption(filter.ID, user.ID, "is_active", true)
+	case TypeDelete:
+		err = tx.DeleteFilter(filter.ID, user.ID)
+	}
+	return filter.ID, err
+}
+
+func (h *Handler) count(reply Reply) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	switch {
+	case reply.Duplicate:
+		h.duplicates++
+	case reply.Status == StatusOK:
+		h.applied++
+	case reply.Status == StatusRejected:
+		h.rejected++
+	default:
+		h.failed++
+	}
+}
+
+// StatusReport - статистика команд для /status
+func (h *Handler) StatusReport() string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return fmt.Sprintf("📥 Команди Kafka: застосовано %d, відхилено %d, помилок %d, повторів %d\n",
+		h.applied, h.rejected, h.failed, h.duplicates)
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"olx-hunter/internal/database"
+)
+
+func strPtr(s string) *string { return &s }
+func intPtr(i int) *int       { return &i }
+
+func TestValidate(t *testing.T) {
+	create := func(spec FilterSpec) Command {
+		return Command{IdempotencyKey: "key-1", Type: TypeCreate, Version: SchemaVersion, TelegramID: 42, Filter: &spec}
+	}
+
+	tests := []struct {
+		name    string
+		cmd     Command
+		wantErr string
+	}{
+		{"valid create", create(FilterSpec{Name: strPtr("iPhone"), Query: strPtr("iphone 15"), MaxPrice: intPtr(30000)}), ""},
+		{"missing key", Command{Type: TypeDelete, Version: SchemaVersion, TelegramID: 42, FilterID: 1}, "idempotency_key"},
+		{"long key", Command{IdempotencyKey: strings.Repeat("k", 101), Type: TypeDelete, Version: SchemaVersion, TelegramID: 42, FilterID: 1}, "idempotency_key"},
+		{"wrong version", Command{IdempotencyKey: "key-1", Type: TypeDelete, Version: 2, TelegramID: 42, FilterID: 1}, "version"},
+		{"missing user", Command{IdempotencyKey: "key-1", Type: TypeDelete, Version: SchemaVersion, FilterID: 1}, "telegram_id"},
+		{"unknown type", Command{IdempotencyKey: "key-1", Type: "filter.rename", Version: SchemaVersion, TelegramID: 42}, "unknown command type"},
+		{"create without query", create(FilterSpec{Name: strPtr("iPhone")}), "filter.query"},
+		{"create with blank name", create(FilterSpec{Name: strPtr("  "), Query: strPtr("iphone")}), "filter.name"},
+		{"negative price", create(FilterSpec{Name: strPtr("iPhone"), Query: strPtr("iphone"), MinPrice: intPtr(-1)}), "negative"},
+		{"min above max", create(FilterSpec{Name: strPtr("iPhone"), Query: strPtr("iphone"), MinPrice: intPtr(500), MaxPrice: intPtr(100)}), "min_price"},
+		{"long city", create(FilterSpec{Name: strPtr("iPhone"), Query: strPtr("iphone"), City: strPtr(strings.Repeat("к", 51))}), "filter.city"},
+		{"update without fields", Command{IdempotencyKey: "key-1", Type: TypeUpdate, Version: SchemaVersion, TelegramID: 42, FilterID: 1, Filter: &FilterSpec{}}, "at least one field"},
+		{"pause without filter", Command{IdempotencyKey: "key-1", Type: TypePause, Version: SchemaVersion, TelegramID: 42}, "filter_id"},
+		{"valid resume", Command{IdempotencyKey: "key-1", Type: TypeResume, Version: SchemaVersion, TelegramID: 42, FilterID: 7}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(tt.cmd)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHandleMessageRejectsBadInput(t *testing.T) {
+	handler := NewHandler(nil)
+
+	reply := handler.HandleMessage([]byte(`{"type": "filter.create"`))
+	if reply.Status != StatusRejected || !strings.Contains(reply.Error, "invalid JSON") {
+		t.Errorf("Expected a rejection for broken JSON, got %+v", reply)
+	}
+
+	reply = handler.HandleMessage([]byte(`{"idempotency_key": "key-1", "type": "filter.delete", "version": 1, "telegram_id": 42}`))
+	if reply.Status != StatusRejected || reply.IdempotencyKey != "key-1" || reply.Version != SchemaVersion {
+		t.Errorf("Expected a rejection echoing the key, got %+v", reply)
+	}
+
+	if !strings.Contains(handler.StatusReport(), "відхилено 2") {
+		t.Errorf("Rejections should be counted, got %q", handler.StatusReport())
+	}
+}
+
+// memFilters - користувачі і фільтри в пам'яті з UNIQUE(user_id, name), як у базі
+type memFilters struct {
+	users   map[int64]*database.User
+	filters map[uint]*database.UserFilter
+	nextID  uint
+}
+
+var errDuplicateName = errors.New("duplicate key value violates unique constraint")
+
+func newMemFilters() *memFilters {
+	return &memFilters{
+		users:   map[int64]*database.User{42: {ID: 1, TelegramID: 42}},
+		filters: make(map[uint]*database.UserFilter),
+	}
+}
+
+func (m *memFilters) unique(userID, filterID uint, name string) error {
+	for _, filter := range m.filters {
+		if filter.UserID == userID && filter.ID != filterID && filter.Name == name {
+			return errDuplicateName
+		}
+	}
+	return nil
+}
+
+func (m *memFilters) GetUserByTelegramID(telegramID int64) (*database.User, error) {
+	return m.users[telegramID], nil
+}
+
+func (m *memFilters) GetUserFilters(userID uint) ([]*database.UserFilter, error) {
+	var filters []*database.UserFilter
+	for _, filter := range m.filters {
+		if filter.UserID == userID {
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+func (m *memFilters) GetFilterByID(filterID, userID uint) (*database.UserFilter, error) {
+	if filter, ok := m.filters[filterID]; ok && filter.UserID == userID {
+		return filter, nil
+	}
+	return nil, nil
+}
+
+func (m *memFilters) CreateFilter(userID uint, name, query string, minPrice, maxPrice int, city string) (*database.UserFilter, error) {
+	if err := m.unique(userID, 0, name); err != nil {
+		return nil, err
+	}
+	m.nextID++
+	filter := &database.UserFilter{ID: m.nextID, UserID: userID, Name: name, Query: query, MinPrice: minPrice, MaxPrice: maxPrice, City: city, IsActive: true}
+	m.filters[filter.ID] = filter
+	return filter, nil
+}
+
+func (m *memFilters) UpdateFilter(filterID, userID uint, name, query string, minPrice, maxPrice int, city string) error {
+	if err := m.unique(userID, filterID, name); err != nil {
+		return err
+	}
+	filter := m.filters[filterID]
+	filter.Name, filter.Query, filter.MinPrice, filter.MaxPrice, filter.City = name, query, minPrice, maxPrice, city
+	return nil
+}
+
+func (m *memFilters) UpdateFilterOption(filterID, userID uint, column string, value interface{}) error {
+	return nil
+}
+
+func (m *memFilters) DeleteFilter(filterID, userID uint) error {
+	delete(m.filters, filterID)
+	return nil
+}
+
+func TestApplyRejectsDuplicateNames(t *testing.T) {
+	store := newMemFilters()
+	command := func(cmdType string, filterID uint, spec FilterSpec) Command {
+		return Command{IdempotencyKey: "key-1", Type: cmdType, Version: SchemaVersion, TelegramID: 42, FilterID: filterID, Filter: &spec}
+	}
+
+	iphone, err := apply(store, command(TypeCreate, 0, FilterSpec{Name: strPtr("iPhone"), Query: strPtr("iphone 15")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	macbook, err := apply(store, command(TypeCreate, 0, FilterSpec{Name: strPtr("MacBook"), Query: strPtr("macbook")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cmd  Command
+	}{
+		{"create", command(TypeCreate, 0, FilterSpec{Name: strPtr(" iPhone "), Query: strPtr("iphone 16")})},
+		{"rename", command(TypeUpdate, macbook, FilterSpec{Name: strPtr("iPhone")})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := apply(store, tt.cmd)
+			var rejected *rejection
+			if !errors.As(err, &rejected) || !strings.Contains(err.Error(), "already exists") {
+				t.Errorf("A duplicate name should be rejected, not retried: %v", err)
+			}
+		})
+	}
+
+	// Фільтр можна зберегти з його власною назвою
+	if _, err := apply(store, command(TypeUpdate, iphone, FilterSpec{Name: strPtr("iPhone"), Query: strPtr("iphone 15 pro")})); err != nil {
+		t.Errorf("Keeping the filter's own name should be allowed: %v", err)
+	}
+	if len(store.filters) != 2 || store.filters[macbook].Name != "MacBook" {
+		t.Error("Rejected commands should not change filters")
+	}
+}
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// retryDelay - пауза після помилки читання або відправки відповіді
+const retryDelay = 5 * time.Second
+
+// KafkaConsumer читає команди з топіка і надсилає результати в топік
+// відповідей. Зміщення фіксується лише після відправки відповіді, тож після
+// перезапуску команда прийде знову і отримає збережену відповідь.
+type KafkaConsumer struct {
+	reader  *kafka.Reader
+	writer  *kafka.Writer
+	handler *Handler
+}
+
+func NewKafkaConsumer(brokers []string, groupID, topic, replyTopic string, handler *Handler) *KafkaConsumer {
+	return &KafkaConsumer{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: brokers,
+			GroupID: groupID,
+			Topic:   topic,
+		}),
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        replyTopic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		},
+		handler: handler,
+	}
+}
+
+// Run обробляє команди по одній, поки не скасовано контекст
+func (c *KafkaConsumer) Run(ctx context.Context) {
+	defer func() {
+		if err := c.reader.Close(); err != nil {
+			log.Printf("Failed to close command reader: %v", err)
+		}
+		if err := c.writer.Close(); err != nil {
+			log.Printf("Failed to close reply writer: %v", err)
+		}
+	}()
+
+	log.Printf("Listening for filter commands on %s", c.reader.Config().Topic)
+
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to read command: %v", err)
+			if !sleep(ctx, retryDelay) {
+				return
+			}
+			continue
+		}
+
+		reply := c.handler.HandleMessage(msg.Value)
+		if reply.Status != StatusOK {
+			log.Printf("Command %s (%s) %s: %s", reply.IdempotencyKey, reply.Type, reply.Status, reply.Error)
+		}
+
+		if !c.sendReply(ctx, reply) {
+			return
+		}
+		if err := c.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to commit command offset: %v", err)
+		}
+	}
+}
+
+// sendReply повторює відправку, поки вона не вдасться або не скасують контекст
+func (c *KafkaConsumer) sendReply(ctx context.Context, reply Reply) bool {
+	value, err := json.Marshal(reply)
+	if err != nil {
+		log.Printf("Failed to encode reply: %v", err)
+		return true
+	}
+
+	for {
+		err := c.writer.WriteMessages(ctx, kafka.Message{Key: []byte(reply.IdempotencyKey), Value: value})
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		log.Printf("Failed to send reply for %s: %v", reply.IdempotencyKey, err)
+		if !sleep(ctx, retryDelay) {
+			return false
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -11,7 +12,63 @@ type Config struct {
 	DatabaseDSN    string
 	RedisAddr      string
 	WorkerCount    int
-	ScrapeInterval int // in seconds
+	ScrapeInterval int // default per-filter interval, in seconds
+	MinInterval    int // bounds for intervals set on a filter, in seconds
+	MaxInterval    int
+	MaxPages       int // default pagination depth per filter
+	FetchDetails   bool
+	CurrencyRates  map[string]float64 // UAH per unit, e.g. USD=41.5
+	ScraperBackend string             // default backend: "html" or "api"
+	OLXBaseURL     string
+	AdminIDs       []int64 // Telegram IDs that receive alerts and can use /status
+	SnapshotDir    string  // where HTML of anomalous pages is saved
+
+	Proxies            []string // proxy URLs, empty means direct requests
+	ProxyCheckURL      string
+	ProxyCheckInterval int // in seconds
+	ProxyEjectCooldown int // in seconds
+
+	RetryMaxAttempts int
+	RetryBaseDelay   int // in seconds
+	BreakerThreshold int // ban signals in a row before scraping is paused
+	BreakerCooldown  int // in seconds
+
+	BudgetPerMinute int // OLX requests per minute shared by all workers, instances and /find
+	BudgetBurst     int
+	BudgetMaxWait   int // how long a scheduled request waits for a token, in seconds
+
+	VerifyInterval     int // how often vanished listings are checked, in seconds, 0 disables
+	VerifyStaleAfter   int // in seconds
+	VerifyRecheckAfter int // in seconds
+	VerifyMaxAge       int // in seconds
+	VerifyBatchSize    int
+
+	RepostPhotoHash  bool // download the first photo of new listings to detect reposts
+	RepostWindowDays int
+
+	FilterLeases bool   // share filters between instances through Postgres leases; a single instance owns them all
+	InstanceID   string // unique name of this instance, hostname-pid by default
+	LeaseTTL     int    // in seconds
+
+	FilterChangesPollInterval int // how often the filter change log is read without a NOTIFY, in seconds
+	FilterReconcileInterval   int // how often all filters are compared with the database, in seconds
+
+	KafkaBrokers       []string // empty disables event publishing
+	KafkaTopicListings string
+	KafkaTopicPrices   string
+	KafkaTopicClosed   string
+	KafkaCommands      bool // accept filter commands from other services
+	KafkaCommandTopic  string
+	KafkaReplyTopic    string
+	KafkaGroupID       string
+
+	OutboxPollInterval  int // how often undelivered notifications are picked up, in seconds
+	OutboxMaxAttempts   int
+	OutboxRetryDelay    int // first retry delay, doubled after every failure, in seconds
+	OutboxRetentionDays int // delivered notifications are kept for the "Show" button
+
+	RequestTimeout  int // per OLX request, in seconds
+	ShutdownTimeout int // how long to wait for in-flight work on exit, in seconds
 }
 
 func Load() (*Config, error) {
@@ -20,7 +77,63 @@ func Load() (*Config, error) {
 		RedisAddr:      getEnvOrDefault("REDIS_ADDR", "localhost:6379"),
 		WorkerCount:    getEnvOrDefaultInt("WORKER_COUNT", 5),
 		ScrapeInterval: getEnvOrDefaultInt("SCRAPE_INTERVAL", 60),
+		MinInterval:    getEnvOrDefaultInt("SCRAPE_INTERVAL_MIN", 60),
+		MaxInterval:    getEnvOrDefaultInt("SCRAPE_INTERVAL_MAX", 3600),
+		MaxPages:       getEnvOrDefaultInt("SCRAPE_MAX_PAGES", 3),
+		FetchDetails:   getEnvOrDefaultBool("FETCH_DETAILS", true),
+		CurrencyRates:  parseRates(getEnvOrDefault("CURRENCY_RATES", "USD=41.5,EUR=48.0")),
+		ScraperBackend: getEnvOrDefault("SCRAPER_BACKEND", "html"),
+		OLXBaseURL:     getEnvOrDefault("OLX_BASE_URL", "https://www.olx.ua"),
+		AdminIDs:       parseIDs(os.Getenv("ADMIN_IDS")),
+		SnapshotDir:    getEnvOrDefault("SNAPSHOT_DIR", "snapshots"),
+
+		Proxies:            parseList(os.Getenv("PROXY_LIST")),
+		ProxyCheckInterval: getEnvOrDefaultInt("PROXY_CHECK_INTERVAL", 300),
+		ProxyEjectCooldown: getEnvOrDefaultInt("PROXY_EJECT_COOLDOWN", 600),
+
+		RetryMaxAttempts: getEnvOrDefaultInt("RETRY_MAX_ATTEMPTS", 3),
+		RetryBaseDelay:   getEnvOrDefaultInt("RETRY_BASE_DELAY", 2),
+		BreakerThreshold: getEnvOrDefaultInt("BREAKER_THRESHOLD", 3),
+		BreakerCooldown:  getEnvOrDefaultInt("BREAKER_COOLDOWN", 900),
+
+		BudgetPerMinute: getEnvOrDefaultInt("OLX_BUDGET_PER_MINUTE", 30),
+		BudgetBurst:     getEnvOrDefaultInt("OLX_BUDGET_BURST", 10),
+		BudgetMaxWait:   getEnvOrDefaultInt("OLX_BUDGET_MAX_WAIT", 60),
+
+		VerifyInterval:     getEnvOrDefaultInt("VERIFY_INTERVAL", 600),
+		VerifyStaleAfter:   getEnvOrDefaultInt("VERIFY_STALE_AFTER", 7200),
+		VerifyRecheckAfter: getEnvOrDefaultInt("VERIFY_RECHECK_AFTER", 21600),
+		VerifyMaxAge:       getEnvOrDefaultInt("VERIFY_MAX_AGE", 604800),
+		VerifyBatchSize:    getEnvOrDefaultInt("VERIFY_BATCH_SIZE", 20),
+
+		RepostPhotoHash:  getEnvOrDefaultBool("REPOST_PHOTO_HASH", false),
+		RepostWindowDays: getEnvOrDefaultInt("REPOST_WINDOW_DAYS", 30),
+
+		FilterLeases: getEnvOrDefaultBool("FILTER_LEASES", true),
+		InstanceID:   getEnvOrDefault("INSTANCE_ID", defaultInstanceID()),
+		LeaseTTL:     getEnvOrDefaultInt("LEASE_TTL", 30),
+
+		FilterChangesPollInterval: getEnvOrDefaultInt("FILTER_CHANGES_POLL_INTERVAL", 30),
+		FilterReconcileInterval:   getEnvOrDefaultInt("FILTER_RECONCILE_INTERVAL", 300),
+
+		KafkaBrokers:       parseList(os.Getenv("KAFKA_BROKERS")),
+		KafkaTopicListings: getEnvOrDefault("KAFKA_TOPIC_LISTINGS", "olx.listings.discovered"),
+		KafkaTopicPrices:   getEnvOrDefault("KAFKA_TOPIC_PRICES", "olx.listings.price-changed"),
+		KafkaTopicClosed:   getEnvOrDefault("KAFKA_TOPIC_CLOSED", "olx.listings.closed"),
+		KafkaCommands:      getEnvOrDefaultBool("KAFKA_COMMANDS", false),
+		KafkaCommandTopic:  getEnvOrDefault("KAFKA_COMMAND_TOPIC", "olx.filters.commands"),
+		KafkaReplyTopic:    getEnvOrDefault("KAFKA_REPLY_TOPIC", "olx.filters.replies"),
+		KafkaGroupID:       getEnvOrDefault("KAFKA_GROUP_ID", "olx-hunter"),
+
+		OutboxPollInterval:  getEnvOrDefaultInt("OUTBOX_POLL_INTERVAL", 2),
+		OutboxMaxAttempts:   getEnvOrDefaultInt("OUTBOX_MAX_ATTEMPTS", 10),
+		OutboxRetryDelay:    getEnvOrDefaultInt("OUTBOX_RETRY_DELAY", 5),
+		OutboxRetentionDays: getEnvOrDefaultInt("OUTBOX_RETENTION_DAYS", 7),
+
+		RequestTimeout:  getEnvOrDefaultInt("REQUEST_TIMEOUT", 30),
+		ShutdownTimeout: getEnvOrDefaultInt("SHUTDOWN_TIMEOUT", 30),
 	}
+	cfg.ProxyCheckURL = getEnvOrDefault("PROXY_CHECK_URL", cfg.OLXBaseURL)
 
 	cfg.DatabaseDSN = fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
@@ -32,11 +145,23 @@ func Load() (*Config, error) {
 		getEnvOrDefault("DB_SSLMODE", "disable"),
 	)
 
-	if cfg.BotToken == "" {
-		return nil, fmt.Errorf("BOT_TOKEN is required")
+	return cfg, nil
+}
+
+// RequireBotToken перевіряє токен бота; окремому скраперу він не потрібен
+func (c *Config) RequireBotToken() error {
+	if c.BotToken == "" {
+		return fmt.Errorf("BOT_TOKEN is required")
 	}
+	return nil
+}
 
-	return cfg, nil
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "scraper"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
 }
 
 func getEnvOrDefault(key, defaultVal string) string {
@@ -57,3 +182,56 @@ func getEnvOrDefaultInt(key string, defaultVal int) int {
 	}
 	return parsed
 }
+
+func getEnvOrDefaultBool(key string, defaultVal bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	parsed, err := strconv.ParseBool(val)
+	if err != nil {
+		return defaultVal
+	}
+	return parsed
+}
+
+// parseRates parses "USD=41.5,EUR=48" into a rate table, skipping bad entries
+func parseRates(val string) map[string]float64 {
+	rates := make(map[string]float64)
+	for _, pair := range strings.Split(val, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || rate <= 0 {
+			continue
+		}
+		rates[strings.ToUpper(strings.TrimSpace(parts[0]))] = rate
+	}
+	return rates
+}
+
+// parseIDs parses a comma-separated list of Telegram IDs, skipping bad entries
+func parseIDs(val string) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(val, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// parseList parses a comma-separated list, skipping empty entries
+func parseList(val string) []string {
+	var items []string
+	for _, part := range strings.Split(val, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProcessedCommand - застосована команда зовнішньої системи і відповідь на неї
+type ProcessedCommand struct {
+	IdempotencyKey string `gorm:"primaryKey;size:100"`
+	Type           string `gorm:"size:30;not null"`
+	Reply          string `gorm:"type:text"`
+	CreatedAt      time.Time
+}
+
+// ApplyCommand виконує apply в транзакції разом із записом ключа
+// ідемпотентності. Якщо команду з цим ключем вже застосовано, apply не
+// викликається і повертається збережена відповідь з duplicate=true. Помилка
+// apply відкочує транзакцію, тож ключ не запам'ятовується і команду можна
+// повторити.
+func (db *DB) ApplyCommand(key, commandType string, apply func(tx *DB) (string, error)) (reply string, duplicate bool, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&ProcessedCommand{IdempotencyKey: key, Type: commandType})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			// Паралельна обробка того самого ключа чекає на коміт першої
+			var processed ProcessedCommand
+			if err := tx.Where("idempotency_key = ?", key).First(&processed).Error; err != nil {
+				return err
+			}
+			reply, duplicate = processed.Reply, true
+			return nil
+		}
+
+		var err error
+		reply, err = apply(&DB{tx})
+		if err != nil {
+			return err
+		}
+		return tx.Model(&ProcessedCommand{}).
+			Where("idempotency_key = ?", key).
+			Update("reply", reply).Error
+	})
+	return reply, duplicate, err
+}
@@ -1,9 +1,12 @@
 package database
 
 import (
+	"time"
+
 	"olx-hunter/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func (db *DB) CreateOrUpdateUser(telegramID int64, username, firstName string) (*User, error) {
@@ -80,6 +83,12 @@ func (db *DB) UpdateFilter(filterID, userID uint, name, query string, minPrice,
 		}).Error
 }
 
+func (db *DB) UpdateFilterOption(filterID, userID uint, column string, value interface{}) error {
+	return db.Model(&UserFilter{}).
+		Where("id = ? AND user_id = ?", filterID, userID).
+		Update(column, value).Error
+}
+
 func (db *DB) DeleteFilter(filterID, userID uint) error {
 	return db.Where("id = ? AND user_id = ?", filterID, userID).Delete(&UserFilter{}).Error
 }
@@ -96,22 +105,60 @@ func (db *DB) GetActiveFilters() ([]*UserFilter, error) {
 	return filters, err
 }
 
+// SaveListing зберігає оголошення (одне на URL) і запам'ятовує, що його
+// знайшов фільтр filterID
 func (db *DB) SaveListing(filterID uint, listing models.Listing) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return saveMatch(tx, filterID, listing, &FilterMatch{})
+	})
+}
+
+// saveMatch зберігає оголошення, якщо його ще немає, і збіг match з фільтром
+func saveMatch(tx *gorm.DB, filterID uint, listing models.Listing, match *FilterMatch) error {
 	savedListing := SavedListing{
 		FilterID: filterID,
 		URL:      listing.URL,
 		Title:    listing.Title,
 		Price:    listing.Price,
 		Location: listing.Location,
+		City:     listing.City,
+		District: listing.District,
+		Promoted: listing.Promoted,
+
+		PriceInfo: listing.PriceInfo,
+
+		AdID:        listing.AdID,
+		Description: listing.Description,
+		Photos:      listing.Photos,
+		SellerName:  listing.SellerName,
+		SellerURL:   listing.SellerURL,
+		Views:       listing.Views,
+		Category:    listing.Category,
+
+		Fingerprint: listing.Fingerprint,
+		PhotoHash:   int64(listing.PhotoHash),
+	}
+	if !listing.PostedAt.IsZero() {
+		savedListing.PostedAt = &listing.PostedAt
 	}
+	savedListing.LastSeenAt = time.Now()
 
-	result := db.Where("url = ?", listing.URL).FirstOrCreate(&savedListing)
-	return result.Error
+	if err := tx.Where("url = ?", listing.URL).FirstOrCreate(&savedListing).Error; err != nil {
+		return err
+	}
+
+	match.FilterID = filterID
+	match.ListingID = savedListing.ID
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(match).Error
 }
 
+// GetExistingURLs повертає URL оголошень, які вже бачив фільтр
 func (db *DB) GetExistingURLs(filterID uint) ([]string, error) {
 	var urls []string
-	err := db.Model(&SavedListing{}).Where("filter_id = ?", filterID).Pluck("url", &urls).Error
+	err := db.Model(&SavedListing{}).
+		Joins("JOIN filter_matches ON filter_matches.listing_id = saved_listings.id").
+		Where("filter_matches.filter_id = ?", filterID).
+		Pluck("saved_listings.url", &urls).Error
 	return urls, err
 }
 
@@ -124,16 +171,311 @@ func (db *DB) GetFilterWithUser(filterID, userID uint) (*UserFilter, error) {
 	return &filter, err
 }
 
-func (db *DB) IsListingNotified(url string) (bool, error) {
-	var savedListing SavedListing
-	err := db.Where("url = ?", url).Select("is_notified").First(&savedListing).Error
+// IsListingNotified перевіряє, чи надсилали власнику фільтра сповіщення
+// про оголошення
+func (db *DB) IsListingNotified(filterID uint, url string) (bool, error) {
+	var match FilterMatch
+	err := db.Joins("JOIN saved_listings ON saved_listings.id = filter_matches.listing_id").
+		Where("filter_matches.filter_id = ? AND saved_listings.url = ?", filterID, url).
+		First(&match).Error
 	if err == gorm.ErrRecordNotFound {
 		return false, nil
-	} else {
-		return savedListing.IsNotified, nil
 	}
+	if err != nil {
+		return false, err
+	}
+	return match.IsNotified, nil
+}
+
+// MarkListingAsNotified позначає оголошення надісланим лише для фільтра filterID
+func (db *DB) MarkListingAsNotified(filterID uint, url string) error {
+	return db.Model(&FilterMatch{}).
+		Where("filter_id = ? AND listing_id IN (?)", filterID,
+			db.Model(&SavedListing{}).Select("id").Where("url = ?", url)).
+		Updates(map[string]interface{}{
+			"is_notified": true,
+			"notified_at": gorm.Expr("NOW()"),
+		}).Error
+}
+
+// priceChanged - чи відрізняється ціна настільки, щоб записати її в історію
+func priceChanged(old, current models.Price) bool {
+	return old.Amount != current.Amount || old.Currency != current.Currency ||
+		old.Free != current.Free || old.Negotiable != current.Negotiable
+}
+
+// priceKnown - чи збережено структуровану ціну. Оголошення, збережені до її
+// появи, мають нульову суму без жодних ознак, і порівнювати з ними не можна.
+func priceKnown(price models.Price) bool {
+	return price.Amount != 0 || price.Free || price.Negotiable || price.Exchange
+}
+
+// priceColumns - поля ціни оголошення для оновлення
+func priceColumns(listing models.Listing) map[string]interface{} {
+	return map[string]interface{}{
+		"price":            listing.Price,
+		"price_amount":     listing.PriceInfo.Amount,
+		"price_currency":   listing.PriceInfo.Currency,
+		"price_negotiable": listing.PriceInfo.Negotiable,
+		"price_free":       listing.PriceInfo.Free,
+		"price_exchange":   listing.PriceInfo.Exchange,
+	}
+}
+
+// RecordPriceChanges порівнює ціни оголошень зі збереженими, оновлює змінені
+// і записує зміни в історію. Повертає зміни за URL; нові оголошення пропускаються.
+// Невідома збережена ціна лише заповнюється, без запису в історію. Сповіщення,
+// які alerts створює за змінами, кладуться в outbox у тій самій транзакції:
+// або зміна повториться наступного разу, або сповіщення точно дійде.
+func (db *DB) RecordPriceChanges(listings []models.Listing, alerts func(changes map[string]PriceHistory) ([]*OutboxMessage, error)) (map[string]PriceHistory, error) {
+	if len(listings) == 0 {
+		return nil, nil
+	}
+
+	urls := make([]string, 0, len(listings))
+	for _, listing := range listings {
+		urls = append(urls, listing.URL)
+	}
+
+	changes := make(map[string]PriceHistory)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Блокування не дає двом групам з тим самим оголошенням записати зміну двічі
+		var saved []SavedListing
+		if err := tx.Select("id", "url", "price", "price_amount", "price_currency", "price_negotiable", "price_free", "price_exchange").
+			Where("url IN ?", urls).Order("id").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Find(&saved).Error; err != nil {
+			return err
+		}
+		savedByURL := make(map[string]SavedListing, len(saved))
+		for _, listing := range saved {
+			savedByURL[listing.URL] = listing
+		}
+
+		for _, listing := range listings {
+			old, exists := savedByURL[listing.URL]
+			if !exists || !priceChanged(old.PriceInfo, listing.PriceInfo) {
+				continue
+			}
+			if err := tx.Model(&SavedListing{ID: old.ID}).Updates(priceColumns(listing)).Error; err != nil {
+				return err
+			}
+			// Та сама URL може трапитись у видачі двічі
+			savedByURL[listing.URL] = SavedListing{ID: old.ID, URL: old.URL, Price: listing.Price, PriceInfo: listing.PriceInfo}
+			if !priceKnown(old.PriceInfo) {
+				continue
+			}
+
+			change := PriceHistory{
+				ListingID:    old.ID,
+				OldPrice:     old.Price,
+				OldPriceInfo: old.PriceInfo,
+				NewPrice:     listing.Price,
+				NewPriceInfo: listing.PriceInfo,
+			}
+			if err := tx.Create(&change).Error; err != nil {
+				return err
+			}
+			changes[listing.URL] = change
+		}
+
+		if len(changes) == 0 || alerts == nil {
+			return nil
+		}
+		messages, err := alerts(changes)
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+		return tx.Create(messages).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
 }
 
-func (db *DB) MarkListingAsNotified(url string) error {
-	return db.Model(&SavedListing{}).Where("url = ?", url).Update("is_notified", true).Error
+// RefreshFingerprints оновлює відбитки збережених оголошень, які змінили
+// назву, ціну чи місто, щоб репост шукався за актуальним текстом
+func (db *DB) RefreshFingerprints(listings []models.Listing) error {
+	fingerprints := make(map[string]string, len(listings))
+	urls := make([]string, 0, len(listings))
+	for _, listing := range listings {
+		if listing.Fingerprint != "" {
+			fingerprints[listing.URL] = listing.Fingerprint
+			urls = append(urls, listing.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	var saved []SavedListing
+	if err := db.Select("id", "url", "fingerprint").Where("url IN ?", urls).Find(&saved).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, listing := range saved {
+			fingerprint := fingerprints[listing.URL]
+			if listing.Fingerprint == fingerprint {
+				continue
+			}
+			if err := tx.Model(&SavedListing{ID: listing.ID}).Update("fingerprint", fingerprint).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetPriceHistory повертає зміни ціни оголошення, найновіші першими
+func (db *DB) GetPriceHistory(url string) ([]PriceHistory, error) {
+	var history []PriceHistory
+	err := db.Joins("JOIN saved_listings ON saved_listings.id = price_history.listing_id").
+		Where("saved_listings.url = ?", url).
+		Order("price_history.changed_at desc, price_history.id desc").
+		Find(&history).Error
+	return history, err
+}
+
+// GetPriceAlertFilters повертає активні фільтри з увімкненими сповіщеннями
+// про ціну, які вже бачили оголошення
+func (db *DB) GetPriceAlertFilters(listingID uint) ([]*UserFilter, error) {
+	return db.getWatchingFilters(listingID, "price_alerts")
+}
+
+// GetClosedAlertFilters повертає активні фільтри, які бачили оголошення
+// і хочуть знати, коли його знімуть з публікації
+func (db *DB) GetClosedAlertFilters(listingID uint) ([]*UserFilter, error) {
+	return db.getWatchingFilters(listingID, "closed_alerts")
+}
+
+func (db *DB) getWatchingFilters(listingID uint, alertColumn string) ([]*UserFilter, error) {
+	var filters []*UserFilter
+	err := db.Joins("JOIN filter_matches ON filter_matches.filter_id = user_filters.id").
+		Where("filter_matches.listing_id = ? AND user_filters.is_active = ?", listingID, true).
+		Where("user_filters."+alertColumn+" = ?", true).
+		Preload("User").
+		Find(&filters).Error
+	return filters, err
+}
+
+// GetMatchedFilterIDs повертає ID усіх фільтрів, які бачили оголошення
+func (db *DB) GetMatchedFilterIDs(listingID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&FilterMatch{}).
+		Where("listing_id = ?", listingID).
+		Order("filter_id").
+		Pluck("filter_id", &ids).Error
+	return ids, err
+}
+
+// TouchListings оновлює час, коли оголошення востаннє було у видачі.
+// Оголошення, що повернулись у видачу, знову вважаються активними.
+func (db *DB) TouchListings(urls []string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	return db.Model(&SavedListing{}).
+		Where("url IN ?", urls).
+		Updates(map[string]interface{}{
+			"last_seen_at": gorm.Expr("NOW()"),
+			"closed_at":    nil,
+		}).Error
+}
+
+// ClaimListingsToVerify повертає активні оголошення, які не траплялись у видачі
+// з seenBefore, але були там після seenAfter, і які не перевіряли з checkedBefore.
+// Перевіряються лише оголошення активних фільтрів, чий пошук уже пройшов їхнє
+// місце у видачі: після того як оголошення бачили востаннє, там траплялось
+// старіше оголошення того ж фільтра. Інакше воно, найімовірніше, лежить на
+// сторінках, до яких пагінація не дійшла.
+// Оголошення забираються на lease, тож кілька екземплярів не перевіряють те
+// саме, а якщо перевірку не завершено, після lease їх візьмуть знову.
+func (db *DB) ClaimListingsToVerify(seenAfter, seenBefore, checkedBefore time.Time, limit int, lease time.Duration) ([]SavedListing, error) {
+	passed := db.Table("filter_matches AS fm").Select("1").
+		Joins("JOIN user_filters uf ON uf.id = fm.filter_id AND uf.is_active").
+		Joins("JOIN filter_matches om ON om.filter_id = fm.filter_id").
+		Joins("JOIN saved_listings older ON older.id = om.listing_id").
+		Where("fm.listing_id = saved_listings.id").
+		Where("NOT older.promoted AND older.created_at < saved_listings.created_at AND older.last_seen_at > saved_listings.last_seen_at")
+
+	candidates := db.Model(&SavedListing{}).Select("id").
+		Where("closed_at IS NULL AND last_seen_at > ? AND last_seen_at < ?", seenAfter, seenBefore).
+		Where("checked_at IS NULL OR checked_at < ?", checkedBefore).
+		Where("verify_claimed_until IS NULL OR verify_claimed_until < NOW()").
+		Where("EXISTS (?)", passed).
+		Order("checked_at ASC NULLS FIRST, last_seen_at DESC").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var listings []SavedListing
+	err := db.Model(&listings).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", candidates).
+		Update("verify_claimed_until", gorm.Expr("NOW() + make_interval(secs => ?)", lease.Seconds())).Error
+	return listings, err
+}
+
+// ReleaseListingClaims повертає неперевірені оголошення в чергу одразу
+func (db *DB) ReleaseListingClaims(listingIDs []uint) error {
+	if len(listingIDs) == 0 {
+		return nil
+	}
+	return db.Model(&SavedListing{}).Where("id IN ?", listingIDs).Update("verify_claimed_until", nil).Error
+}
+
+// MarkListingChecked запам'ятовує, що сторінка оголошення ще доступна
+func (db *DB) MarkListingChecked(listingID uint) error {
+	return db.Model(&SavedListing{ID: listingID}).Updates(map[string]interface{}{
+		"checked_at":           gorm.Expr("NOW()"),
+		"verify_claimed_until": nil,
+	}).Error
+}
+
+// MarkListingsClosed позначає оголошення проданими або знятими з публікації
+// і кладе сповіщення про них в outbox у тій самій транзакції
+func (db *DB) MarkListingsClosed(listingIDs []uint, notifications []*OutboxMessage) error {
+	if len(listingIDs) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&SavedListing{}).Where("id IN ?", listingIDs).Updates(map[string]interface{}{
+			"checked_at":           gorm.Expr("NOW()"),
+			"closed_at":            gorm.Expr("NOW()"),
+			"verify_claimed_until": nil,
+		}).Error
+		if err != nil || len(notifications) == 0 {
+			return err
+		}
+		return tx.Create(notifications).Error
+	})
+}
+
+// FindRepost шукає серед оголошень, які фільтр бачив після since, те саме
+// за відбитком або схоже фото (dHash відрізняється не більше ніж на maxDistance біт).
+// Для відбитка продавець має збігатися, якщо він відомий з обох боків. Фото
+// без збігу тексту буває і в різних продавців (каталожні знімки), тож схоже
+// фото рахується лише в того самого відомого продавця.
+func (db *DB) FindRepost(filterID uint, url, fingerprint, seller string, photoHash uint64, maxDistance int, since time.Time) (*SavedListing, error) {
+	match := "(saved_listings.fingerprint = ? AND (? = '' OR COALESCE(saved_listings.seller_name, '') = '' OR saved_listings.seller_name = ?))"
+	args := []interface{}{fingerprint, seller, seller}
+	if photoHash != 0 && seller != "" {
+		match += " OR (saved_listings.seller_name = ? AND saved_listings.photo_hash <> 0 AND length(replace((saved_listings.photo_hash # ?)::bit(64)::text, '0', '')) <= ?)"
+		args = append(args, seller, int64(photoHash), maxDistance)
+	}
+
+	var listing SavedListing
+	err := db.Joins("JOIN filter_matches ON filter_matches.listing_id = saved_listings.id").
+		Where("filter_matches.filter_id = ? AND saved_listings.url <> ? AND saved_listings.created_at > ?", filterID, url, since).
+		Where("("+match+")", args...).
+		Order("saved_listings.created_at desc").
+		First(&listing).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &listing, nil
 }
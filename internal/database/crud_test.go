@@ -1,7 +1,12 @@
 package database
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"time"
+
+	"olx-hunter/internal/models"
 )
 
 func setupTestDB(t *testing.T) *DB {
@@ -309,4 +314,641 @@ func TestGetActiveFilters(t *testing.T) {
     defer func() {
         db.Where("telegram_id IN ?", []int64{9999999991, 9999999992}).Delete(&User{})
     }()
-}
\ No newline at end of file
+}
+func TestSameListingForTwoUsers(t *testing.T) {
+	db := setupTestDB(t)
+
+	user1, _ := db.CreateOrUpdateUser(9999999993, "watcher1", "Watcher 1")
+	user2, _ := db.CreateOrUpdateUser(9999999994, "watcher2", "Watcher 2")
+
+	filter1, err := db.CreateFilter(user1.ID, "Shared 1", "shared-item", 0, 0, "")
+	if err != nil {
+		t.Fatal("Error creating filter 1:", err)
+	}
+	filter2, err := db.CreateFilter(user2.ID, "Shared 2", "shared-item", 0, 0, "")
+	if err != nil {
+		t.Fatal("Error creating filter 2:", err)
+	}
+
+	listing := models.Listing{URL: "https://www.olx.ua/d/uk/obyavlenie/shared-item-test.html", Title: "Shared item"}
+
+	defer func() {
+		db.Where("telegram_id IN ?", []int64{9999999993, 9999999994}).Delete(&User{})
+		db.Where("url = ?", listing.URL).Delete(&SavedListing{})
+	}()
+
+	if err := db.SaveListing(filter1.ID, listing); err != nil {
+		t.Fatal("Error saving listing for filter 1:", err)
+	}
+	if err := db.MarkListingAsNotified(filter1.ID, listing.URL); err != nil {
+		t.Fatal("Error marking listing:", err)
+	}
+
+	urls, err := db.GetExistingURLs(filter2.ID)
+	if err != nil {
+		t.Fatal("Error getting existing URLs:", err)
+	}
+	if len(urls) != 0 {
+		t.Errorf("Filter 2 has not seen the listing yet, got %v", urls)
+	}
+
+	if err := db.SaveListing(filter2.ID, listing); err != nil {
+		t.Fatal("Error saving listing for filter 2:", err)
+	}
+	// Повторне збереження не дублює збіг
+	if err := db.SaveListing(filter2.ID, listing); err != nil {
+		t.Fatal("Error saving listing twice:", err)
+	}
+
+	var listingCount, matchCount int64
+	db.Model(&SavedListing{}).Where("url = ?", listing.URL).Count(&listingCount)
+	db.Model(&FilterMatch{}).Where("filter_id IN ?", []uint{filter1.ID, filter2.ID}).Count(&matchCount)
+	if listingCount != 1 || matchCount != 2 {
+		t.Errorf("Expected 1 listing and 2 matches, got %d and %d", listingCount, matchCount)
+	}
+
+	notified1, _ := db.IsListingNotified(filter1.ID, listing.URL)
+	notified2, err := db.IsListingNotified(filter2.ID, listing.URL)
+	if err != nil {
+		t.Fatal("Error checking notified state:", err)
+	}
+	if !notified1 {
+		t.Error("Listing should be notified for filter 1")
+	}
+	if notified2 {
+		t.Error("Listing should still be pending for filter 2")
+	}
+
+	var saved SavedListing
+	db.Where("url = ?", listing.URL).First(&saved)
+	filterIDs, err := db.GetMatchedFilterIDs(saved.ID)
+	if err != nil {
+		t.Fatal("Error getting matched filters:", err)
+	}
+	if len(filterIDs) != 2 || filterIDs[0] != filter1.ID || filterIDs[1] != filter2.ID {
+		t.Errorf("Expected filters %d and %d, got %v", filter1.ID, filter2.ID, filterIDs)
+	}
+
+	// Видалення першого фільтра не зачіпає другого
+	if err := db.DeleteFilter(filter1.ID, user1.ID); err != nil {
+		t.Fatal("Error deleting filter 1:", err)
+	}
+	urls, _ = db.GetExistingURLs(filter2.ID)
+	if len(urls) != 1 || urls[0] != listing.URL {
+		t.Errorf("Filter 2 should keep the listing, got %v", urls)
+	}
+}
+
+func TestRecordPriceChanges(t *testing.T) {
+	db := setupTestDB(t)
+
+	user, _ := db.CreateOrUpdateUser(9999999995, "pricewatch", "Price Watcher")
+	watching, _ := db.CreateFilter(user.ID, "Watching", "price-item", 0, 0, "")
+	silent, _ := db.CreateFilter(user.ID, "Silent", "price-item", 0, 0, "")
+	db.UpdateFilterOption(watching.ID, user.ID, "price_alerts", true)
+
+	listing := models.Listing{
+		URL:       "https://www.olx.ua/d/uk/obyavlenie/price-item-test.html",
+		Title:     "Price item",
+		Price:     "10 000 грн.",
+		PriceInfo: models.Price{Amount: 10000, Currency: "UAH"},
+	}
+
+	defer func() {
+		db.Where("telegram_id = ?", 9999999995).Delete(&User{})
+		db.Where("url = ?", listing.URL).Delete(&SavedListing{})
+	}()
+
+	// Нові оголошення не мають історії
+	changes, err := db.RecordPriceChanges([]models.Listing{listing}, nil)
+	if err != nil {
+		t.Fatal("Error recording prices:", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Unsaved listing should not have price changes, got %v", changes)
+	}
+
+	db.SaveListing(watching.ID, listing)
+	db.SaveListing(silent.ID, listing)
+
+	if changes, _ := db.RecordPriceChanges([]models.Listing{listing}, nil); len(changes) != 0 {
+		t.Errorf("Same price should not be recorded, got %v", changes)
+	}
+
+	cheaper := listing
+	cheaper.Price = "8 000 грн."
+	cheaper.PriceInfo.Amount = 8000
+
+	// Помилка під час підготовки сповіщень скасовує і зміну ціни
+	failed := errors.New("alerts failed")
+	if _, err := db.RecordPriceChanges([]models.Listing{cheaper}, func(map[string]PriceHistory) ([]*OutboxMessage, error) {
+		return nil, failed
+	}); !errors.Is(err, failed) {
+		t.Fatalf("Expected the alerts error, got %v", err)
+	}
+	if history, _ := db.GetPriceHistory(listing.URL); len(history) != 0 {
+		t.Fatalf("Rolled back change should not be in history, got %d entries", len(history))
+	}
+
+	alert, _ := NewOutboxMessage(watching.ID, models.Notification{TelegramID: 9999999995, Alert: "price-item dropped"})
+	defer db.Where("id = ?", alert.ID).Delete(&OutboxMessage{})
+	changes, err = db.RecordPriceChanges([]models.Listing{cheaper}, func(changes map[string]PriceHistory) ([]*OutboxMessage, error) {
+		return []*OutboxMessage{alert}, nil
+	})
+	if err != nil {
+		t.Fatal("Error recording prices:", err)
+	}
+	if queued, _ := db.GetOutboxMessage(alert.ID); queued == nil {
+		t.Error("Price alert should be queued with the change")
+	}
+	change, exists := changes[listing.URL]
+	if !exists {
+		t.Fatal("Price change should be recorded")
+	}
+	if change.OldPriceInfo.Amount != 10000 || change.NewPriceInfo.Amount != 8000 || change.OldPrice != "10 000 грн." {
+		t.Errorf("Unexpected price change: %+v", change)
+	}
+
+	history, _ := db.GetPriceHistory(listing.URL)
+	if len(history) != 1 {
+		t.Errorf("Expected 1 history entry, got %d", len(history))
+	}
+	if changes, _ := db.RecordPriceChanges([]models.Listing{cheaper}, nil); len(changes) != 0 {
+		t.Error("Stored price should be updated after the change")
+	}
+
+	filters, err := db.GetPriceAlertFilters(change.ListingID)
+	if err != nil {
+		t.Fatal("Error getting price alert filters:", err)
+	}
+	if len(filters) != 1 || filters[0].ID != watching.ID {
+		t.Errorf("Only the opted-in filter should get price alerts, got %d filters", len(filters))
+	}
+	if len(filters) == 1 && filters[0].User.TelegramID != 9999999995 {
+		t.Error("User should be preloaded for price alerts")
+	}
+
+	// Оголошення, збережене до структурованих цін, має нульову суму
+	legacy := listing
+	legacy.URL = "https://www.olx.ua/d/uk/obyavlenie/price-item-legacy.html"
+	defer db.Where("url = ?", legacy.URL).Delete(&SavedListing{})
+	db.SaveListing(watching.ID, legacy)
+	db.Model(&SavedListing{}).Where("url = ?", legacy.URL).Updates(map[string]interface{}{"price_amount": 0, "price_currency": "UAH"})
+
+	if changes, _ := db.RecordPriceChanges([]models.Listing{legacy}, nil); len(changes) != 0 {
+		t.Errorf("Unknown stored price should not be recorded as a change, got %v", changes)
+	}
+	if history, _ := db.GetPriceHistory(legacy.URL); len(history) != 0 {
+		t.Errorf("Unknown stored price should not create history, got %d entries", len(history))
+	}
+
+	legacy.PriceInfo.Amount = 9000
+	if changes, _ := db.RecordPriceChanges([]models.Listing{legacy}, nil); changes[legacy.URL].OldPriceInfo.Amount != 10000 {
+		t.Errorf("Backfilled price should be compared with the next one, got %v", changes)
+	}
+}
+
+func TestListingVerificationLifecycle(t *testing.T) {
+	db := setupTestDB(t)
+
+	user, _ := db.CreateOrUpdateUser(9999999996, "verifier", "Verifier User")
+	filter, _ := db.CreateFilter(user.ID, "Closed alerts", "closed-item", 0, 0, "")
+	db.UpdateFilterOption(filter.ID, user.ID, "closed_alerts", true)
+
+	listing := models.Listing{URL: "https://www.olx.ua/d/uk/obyavlenie/closed-item-test.html", Title: "Closed item"}
+	older := models.Listing{URL: "https://www.olx.ua/d/uk/obyavlenie/closed-item-older.html", Title: "Older item"}
+	tail := models.Listing{URL: "https://www.olx.ua/d/uk/obyavlenie/closed-item-tail.html", Title: "Tail item"}
+	urls := []string{listing.URL, older.URL, tail.URL}
+
+	defer func() {
+		db.Where("telegram_id = ?", 9999999996).Delete(&User{})
+		db.Where("url IN ?", urls).Delete(&SavedListing{})
+	}()
+
+	for _, l := range []models.Listing{tail, older, listing} {
+		if err := db.SaveListing(filter.ID, l); err != nil {
+			t.Fatal("Error saving listing:", err)
+		}
+	}
+	// Оголошення давно не траплялось у видачі, а старіше за нього - щойно
+	// траплялось, тобто пошук пройшов його місце. Найстаріше оголошення лежить
+	// далі, ніж дійшла пагінація.
+	db.Model(&SavedListing{}).Where("url = ?", tail.URL).Updates(map[string]interface{}{
+		"created_at": time.Now().Add(-2 * time.Hour), "last_seen_at": time.Now().Add(-3 * time.Hour)})
+	db.Model(&SavedListing{}).Where("url = ?", older.URL).Update("created_at", time.Now().Add(-time.Hour))
+	db.Model(&SavedListing{}).Where("url = ?", listing.URL).Update("last_seen_at", time.Now().Add(-3*time.Hour))
+
+	now := time.Now()
+	claimed := func() map[string]*SavedListing {
+		listings, err := db.ClaimListingsToVerify(now.Add(-24*time.Hour), now.Add(-time.Hour), now.Add(-time.Hour), 1000, time.Minute)
+		if err != nil {
+			t.Fatal("Error getting listings to verify:", err)
+		}
+		byURL := make(map[string]*SavedListing)
+		for i := range listings {
+			byURL[listings[i].URL] = &listings[i]
+		}
+		return byURL
+	}
+	toVerify := func() *SavedListing {
+		return claimed()[listing.URL]
+	}
+
+	// Поки фільтр вимкнений, його оголошення не перевіряються
+	db.ToggleFilter(filter.ID, user.ID)
+	if toVerify() != nil {
+		t.Error("Listings of inactive filters should not be verified")
+	}
+	db.ToggleFilter(filter.ID, user.ID)
+
+	first := claimed()
+	saved := first[listing.URL]
+	if saved == nil {
+		t.Fatal("Stale listing should be verified")
+	}
+	if first[tail.URL] != nil {
+		t.Error("Listing beyond the scanned pages should not be verified")
+	}
+	if saved.CheckedAt != nil || saved.VerifyClaimedUntil == nil {
+		t.Error("Claimed listing should be leased, not marked as checked")
+	}
+	if toVerify() != nil {
+		t.Error("Claimed listing should not be verified again")
+	}
+
+	// Перевірку не завершено: оголошення повертається в чергу
+	if err := db.ReleaseListingClaims([]uint{saved.ID}); err != nil {
+		t.Fatal("Error releasing listing:", err)
+	}
+	if saved = toVerify(); saved == nil {
+		t.Fatal("Released listing should be verified again")
+	}
+	// Lease, що закінчився, теж повертає оголошення в чергу
+	db.Model(&SavedListing{}).Where("url = ?", listing.URL).Update("verify_claimed_until", time.Now().Add(-time.Minute))
+	if saved = toVerify(); saved == nil {
+		t.Fatal("Listing with an expired claim should be verified again")
+	}
+
+	alert, _ := NewOutboxMessage(filter.ID, models.Notification{TelegramID: 9999999996, Alert: "closed-item closed"})
+	defer db.Where("id = ?", alert.ID).Delete(&OutboxMessage{})
+	if err := db.MarkListingsClosed([]uint{saved.ID}, []*OutboxMessage{alert}); err != nil {
+		t.Fatal("Error closing listing:", err)
+	}
+	if queued, _ := db.GetOutboxMessage(alert.ID); queued == nil {
+		t.Error("Closed alert should be queued with the listing")
+	}
+	var closed SavedListing
+	db.Where("url = ?", listing.URL).First(&closed)
+	if closed.ClosedAt == nil {
+		t.Error("Closed listing should have a timestamp")
+	}
+
+	filters, _ := db.GetClosedAlertFilters(saved.ID)
+	if len(filters) != 1 || filters[0].ID != filter.ID {
+		t.Errorf("Expected the watching filter to be alerted, got %d filters", len(filters))
+	}
+
+	// Оголошення повернулось у видачу
+	db.TouchListings([]string{listing.URL})
+	db.Where("url = ?", listing.URL).First(&closed)
+	if closed.ClosedAt != nil {
+		t.Error("Listing seen in search again should be reopened")
+	}
+}
+
+func TestFindRepost(t *testing.T) {
+	db := setupTestDB(t)
+
+	user, _ := db.CreateOrUpdateUser(9999999997, "reposts", "Repost User")
+	filter, _ := db.CreateFilter(user.ID, "Reposts", "repost-item", 0, 0, "")
+	other, _ := db.CreateFilter(user.ID, "Other", "repost-other", 0, 0, "")
+
+	original := models.Listing{
+		URL:         "https://www.olx.ua/d/uk/obyavlenie/repost-item-original.html",
+		Title:       "Repost item",
+		Price:       "5 000 грн.",
+		SellerName:  "Олег",
+		Fingerprint: "repost-test-fingerprint",
+		PhotoHash:   0xF0F0F0F0F0F0F0F0,
+	}
+	repost := models.Listing{
+		URL:         "https://www.olx.ua/d/uk/obyavlenie/repost-item-copy.html",
+		Title:       "Repost item",
+		SellerName:  "Олег",
+		Fingerprint: "repost-test-other-price",
+		PhotoHash:   0xF0F0F0F0F0F0F0F3, // те саме фото після стиснення
+	}
+
+	defer func() {
+		db.Where("telegram_id = ?", 9999999997).Delete(&User{})
+		db.Where("url IN ?", []string{original.URL, repost.URL}).Delete(&SavedListing{})
+	}()
+
+	if err := db.SaveListing(filter.ID, original); err != nil {
+		t.Fatal("Error saving original:", err)
+	}
+	db.SaveListing(filter.ID, repost)
+
+	since := time.Now().Add(-time.Hour)
+
+	found, err := db.FindRepost(filter.ID, repost.URL, repost.Fingerprint, repost.SellerName, repost.PhotoHash, 6, since)
+	if err != nil {
+		t.Fatal("Error finding repost:", err)
+	}
+	if found == nil || found.URL != original.URL {
+		t.Fatalf("Expected the original to be found by photo, got %v", found)
+	}
+	if found.Price != "5 000 грн." {
+		t.Errorf("Expected the original price, got '%s'", found.Price)
+	}
+
+	byFingerprint, _ := db.FindRepost(filter.ID, repost.URL, original.Fingerprint, "", 0, 6, since)
+	if byFingerprint == nil {
+		t.Error("Expected the original to be found by fingerprint")
+	}
+
+	anotherSeller, _ := db.FindRepost(filter.ID, repost.URL, original.Fingerprint, "Ірина", 0, 6, since)
+	if anotherSeller != nil {
+		t.Error("Listing from another seller is not a repost")
+	}
+
+	unseen, _ := db.FindRepost(other.ID, repost.URL, original.Fingerprint, "", repost.PhotoHash, 6, since)
+	if unseen != nil {
+		t.Error("Filter that never saw the original should not treat the listing as a repost")
+	}
+
+	unknownSeller, _ := db.FindRepost(filter.ID, repost.URL, repost.Fingerprint, "", repost.PhotoHash, 6, since)
+	if unknownSeller != nil {
+		t.Error("Similar photo alone should not match without a known seller")
+	}
+	photoOfAnotherSeller, _ := db.FindRepost(filter.ID, repost.URL, repost.Fingerprint, "Ірина", repost.PhotoHash, 6, since)
+	if photoOfAnotherSeller != nil {
+		t.Error("Similar photo of another seller is not a repost")
+	}
+
+	// Відредаговане оголошення шукається за новим відбитком
+	edited := original
+	edited.Fingerprint = "repost-test-edited"
+	if err := db.RefreshFingerprints([]models.Listing{edited}); err != nil {
+		t.Fatal("Error refreshing fingerprints:", err)
+	}
+	if found, _ := db.FindRepost(filter.ID, repost.URL, edited.Fingerprint, "", 0, 6, since); found == nil || found.URL != original.URL {
+		t.Errorf("Expected the edited original to be found by its new fingerprint, got %v", found)
+	}
+	if stale, _ := db.FindRepost(filter.ID, repost.URL, original.Fingerprint, "", 0, 6, since); stale != nil {
+		t.Error("Old fingerprint should be replaced")
+	}
+}
+
+func TestFilterLeases(t *testing.T) {
+	db := setupTestDB(t)
+
+	user, _ := db.CreateOrUpdateUser(9999999998, "leases", "Lease User")
+	filter, _ := db.CreateFilter(user.ID, "Leased", "leased-item", 0, 0, "")
+
+	defer func() {
+		db.ReleaseInstance("test-instance-a")
+		db.ReleaseInstance("test-instance-b")
+		db.Where("telegram_id = ?", 9999999998).Delete(&User{})
+	}()
+
+	for _, instance := range []string{"test-instance-a", "test-instance-b"} {
+		if live, err := db.Heartbeat(instance, time.Minute); err != nil || live < 1 {
+			t.Fatalf("Heartbeat of %s failed: %d, %v", instance, live, err)
+		}
+	}
+
+	claimed, err := db.ClaimLease("test-instance-a", filter.ID, time.Minute)
+	if err != nil || !claimed {
+		t.Fatalf("Instance A should claim a free filter: %v", err)
+	}
+	if claimed, _ := db.ClaimLease("test-instance-b", filter.ID, time.Minute); claimed {
+		t.Error("Instance B should not claim a leased filter")
+	}
+	if claimed, _ := db.ClaimLease("test-instance-a", filter.ID, time.Minute); !claimed {
+		t.Error("Owner should keep its lease")
+	}
+
+	renewed, err := db.RenewLeases("test-instance-a", time.Minute)
+	if err != nil {
+		t.Fatal("Error renewing leases:", err)
+	}
+	if len(renewed) != 1 || renewed[0] != filter.ID {
+		t.Errorf("Expected the leased filter to be renewed, got %v", renewed)
+	}
+
+	// Оренда мертвого екземпляра закінчилась
+	db.Model(&FilterLease{}).Where("filter_id = ?", filter.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if claimed, _ := db.ClaimLease("test-instance-b", filter.ID, time.Minute); !claimed {
+		t.Error("Expired lease should be taken over")
+	}
+	if renewed, _ := db.RenewLeases("test-instance-a", time.Minute); len(renewed) != 0 {
+		t.Errorf("Previous owner should lose the lease, got %v", renewed)
+	}
+
+	if err := db.ReleaseInstance("test-instance-b"); err != nil {
+		t.Fatal("Error releasing instance:", err)
+	}
+	if claimed, _ := db.ClaimLease("test-instance-a", filter.ID, time.Minute); !claimed {
+		t.Error("Released filter should be free again")
+	}
+}
+
+func TestApplyCommandIsIdempotent(t *testing.T) {
+	db := setupTestDB(t)
+
+	user, _ := db.CreateOrUpdateUser(9999999999, "commands", "Command User")
+	key := "test-command-create"
+
+	defer func() {
+		db.Where("idempotency_key LIKE ?", "test-command-%").Delete(&ProcessedCommand{})
+		db.Where("telegram_id = ?", 9999999999).Delete(&User{})
+	}()
+
+	calls := 0
+	create := func(tx *DB) (string, error) {
+		calls++
+		filter, err := tx.CreateFilter(user.ID, "From Kafka", "kafka-item", 0, 0, "")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(`{"filter_id": %d}`, filter.ID), nil
+	}
+
+	reply, duplicate, err := db.ApplyCommand(key, "filter.create", create)
+	if err != nil || duplicate {
+		t.Fatalf("First command should be applied: %v, duplicate=%v", err, duplicate)
+	}
+
+	again, duplicate, err := db.ApplyCommand(key, "filter.create", create)
+	if err != nil || !duplicate {
+		t.Fatalf("Second command should be a duplicate: %v, duplicate=%v", err, duplicate)
+	}
+	if again != reply || calls != 1 {
+		t.Errorf("Duplicate should return the stored reply without applying, got %q after %d calls", again, calls)
+	}
+
+	filters, _ := db.GetUserFilters(user.ID)
+	if len(filters) != 1 {
+		t.Errorf("Expected exactly one filter, got %d", len(filters))
+	}
+
+	// Помилка відкочує транзакцію, і ключ можна використати знову
+	failKey := "test-command-fail"
+	if _, _, err := db.ApplyCommand(failKey, "filter.create", func(tx *DB) (string, error) {
+		return "", fmt.Errorf("boom")
+	}); err == nil {
+		t.Fatal("Expected the apply error to be returned")
+	}
+	if _, duplicate, err := db.ApplyCommand(failKey, "filter.delete", func(tx *DB) (string, error) {
+		return "{}", nil
+	}); err != nil || duplicate {
+		t.Errorf("Failed command should not be remembered: %v, duplicate=%v", err, duplicate)
+	}
+}
+
+func TestNotificationOutbox(t *testing.T) {
+	db := setupTestDB(t)
+
+	user, _ := db.CreateOrUpdateUser(9999999989, "outbox", "Outbox User")
+	filter, _ := db.CreateFilter(user.ID, "Outbox", "outbox-item", 0, 0, "")
+	listing := models.Listing{URL: "https://www.olx.ua/d/uk/obyavlenie/outbox-item-test.html", Title: "Outbox item"}
+
+	defer func() {
+		db.Where("telegram_id = ?", 9999999989).Delete(&OutboxMessage{})
+		db.Where("telegram_id = ?", 9999999989).Delete(&User{})
+		db.Where("url = ?", listing.URL).Delete(&SavedListing{})
+	}()
+
+	message, err := NewOutboxMessage(filter.ID, models.Notification{
+		TelegramID: user.TelegramID,
+		FilterName: filter.Name,
+		Listings:   []models.Listing{listing},
+	})
+	if err != nil {
+		t.Fatal("Error encoding notification:", err)
+	}
+	if err := db.SaveNewListings(filter.ID, []models.Listing{listing}, message); err != nil {
+		t.Fatal("Error saving listings with notification:", err)
+	}
+
+	if notified, _ := db.IsListingNotified(filter.ID, listing.URL); !notified {
+		t.Error("Listing with a queued notification should count as notified")
+	}
+
+	claimed, err := db.ClaimOutbox(100, time.Minute)
+	if err != nil {
+		t.Fatal("Error claiming outbox:", err)
+	}
+	var own *OutboxMessage
+	for i := range claimed {
+		if claimed[i].ID == message.ID {
+			own = &claimed[i]
+		}
+	}
+	if own == nil || own.Attempts != 1 {
+		t.Fatalf("Queued notification should be claimed with one attempt, got %+v", own)
+	}
+	notif, err := own.Notification()
+	if err != nil || len(notif.Listings) != 1 || notif.Listings[0].URL != listing.URL {
+		t.Errorf("Notification should round-trip, got %+v, %v", notif, err)
+	}
+
+	again, _ := db.ClaimOutbox(100, time.Minute)
+	for _, m := range again {
+		if m.ID == message.ID {
+			t.Error("Claimed notification should not be handed out twice")
+		}
+	}
+
+	if err := db.RetryOutbox(message.ID, "Too Many Requests", 0); err != nil {
+		t.Fatal("Error rescheduling notification:", err)
+	}
+	retried, _ := db.ClaimOutbox(100, time.Minute)
+	found := false
+	for _, m := range retried {
+		if m.ID == message.ID {
+			found = m.Attempts == 2 && m.LastError == "Too Many Requests"
+		}
+	}
+	if !found {
+		t.Error("Rescheduled notification should be claimed again")
+	}
+
+	if err := db.MarkOutboxDelivered(message.ID); err != nil {
+		t.Fatal("Error marking notification delivered:", err)
+	}
+	stored, _ := db.GetOutboxMessage(message.ID)
+	if stored == nil || stored.DeliveredAt == nil {
+		t.Error("Delivered notification should be kept for the Show button")
+	}
+}
+
+func TestFilterChanges(t *testing.T) {
+	db := setupTestDB(t)
+
+	position, err := db.FilterChangesHorizon()
+	if err != nil {
+		t.Fatal("Error getting filter change horizon:", err)
+	}
+
+	user, _ := db.CreateOrUpdateUser(9999999988, "changes", "Changes User")
+	defer db.Where("telegram_id = ?", 9999999988).Delete(&User{})
+
+	// Зміна з меншим номером, закомічена пізніше, не видна, поки її
+	// транзакція не завершилась, і не пропускається після цього
+	tx := db.Begin()
+	late, err := (&DB{DB: tx}).CreateFilter(user.ID, "Late", "late-item", 0, 0, "")
+	if err != nil {
+		tx.Rollback()
+		t.Fatal("Error creating filter:", err)
+	}
+
+	filter, _ := db.CreateFilter(user.ID, "Changed", "changed-item", 0, 0, "")
+	if err := db.ToggleFilter(filter.ID, user.ID); err != nil {
+		t.Fatal("Error toggling filter:", err)
+	}
+	if err := db.DeleteFilter(filter.ID, user.ID); err != nil {
+		t.Fatal("Error deleting filter:", err)
+	}
+
+	countChanges := func(changes []FilterChange, filterID uint) int {
+		count := 0
+		for _, change := range changes {
+			if change.FilterID == filterID {
+				count++
+			}
+		}
+		return count
+	}
+
+	changes, err := db.GetFilterChanges(position, 1000)
+	if err != nil {
+		t.Fatal("Error reading filter changes:", err)
+	}
+	if count := countChanges(changes, filter.ID); count != 0 {
+		t.Errorf("Changes after a running transaction should wait for it, got %d", count)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		t.Fatal("Error committing filter:", err)
+	}
+	changes, err = db.GetFilterChanges(position, 1000)
+	if err != nil {
+		t.Fatal("Error reading filter changes:", err)
+	}
+	if count := countChanges(changes, late.ID); count != 1 {
+		t.Errorf("Late commit should be read, got %d changes", count)
+	}
+	if count := countChanges(changes, filter.ID); count != 3 {
+		t.Errorf("Insert, toggle and delete should each be recorded by the trigger, got %d changes", count)
+	}
+	if len(changes) > 0 && changes[0].FilterID != late.ID {
+		t.Error("Changes should be ordered by transaction")
+	}
+
+	horizon, _ := db.FilterChangesHorizon()
+	if rest, _ := db.GetFilterChanges(horizon, 100); len(rest) != 0 {
+		t.Errorf("No changes expected after the horizon, got %d", len(rest))
+	}
+}
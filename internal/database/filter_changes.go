@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FilterChangesChannel - канал NOTIFY, в який тригер user_filters_changed
+// пише ID зміненого фільтра
+const FilterChangesChannel = "filter_changes"
+
+// FilterChange - запис про те, що фільтр створено, змінено або видалено.
+// Записи створює тригер на user_filters, тож жодна зміна не проходить повз.
+type FilterChange struct {
+	ID        uint64 `gorm:"primaryKey"`
+	TxID      uint64 `gorm:"column:txid;->"` // транзакція, що зробила зміну
+	FilterID  uint   `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// Position - місце зміни в журналі
+func (c FilterChange) Position() FilterChangePosition {
+	return FilterChangePosition{TxID: c.TxID, ID: c.ID}
+}
+
+// FilterChangePosition - місце в журналі змін. Номер зміни видається до
+// коміту, тож зміна з меншим номером може з'явитися вже після прочитаних.
+// Тому журнал впорядковано за транзакцією, а читаються лише зміни транзакцій,
+// старіших за всі, що ще виконуються: після них нічого вже не з'явиться.
+type FilterChangePosition struct {
+	TxID uint64
+	ID   uint64
+}
+
+// changesHorizon - найстаріша транзакція, що ще виконується
+const changesHorizon = "pg_snapshot_xmin(pg_current_snapshot())::text::bigint"
+
+// GetFilterChanges повертає до limit завершених змін після after
+func (db *DB) GetFilterChanges(after FilterChangePosition, limit int) ([]FilterChange, error) {
+	var changes []FilterChange
+	err := db.Where("(txid, id) > (?, ?) AND txid < "+changesHorizon, after.TxID, after.ID).
+		Order("txid, id").Limit(limit).Find(&changes).Error
+	return changes, err
+}
+
+// FilterChangesHorizon повертає місце, з якого почнуться зміни транзакцій,
+// що ще не завершились. Усе до нього вже видно в user_filters.
+func (db *DB) FilterChangesHorizon() (FilterChangePosition, error) {
+	var horizon uint64
+	err := db.Raw("SELECT " + changesHorizon).Scan(&horizon).Error
+	return FilterChangePosition{TxID: horizon}, err
+}
+
+// PurgeFilterChanges видаляє зміни, записані до before
+func (db *DB) PurgeFilterChanges(before time.Time) (int64, error) {
+	result := db.Where("created_at < ?", before).Delete(&FilterChange{})
+	return result.RowsAffected, result.Error
+}
+
+// ListenFilterChanges підписується на FilterChangesChannel через окреме
+// з'єднання і викликає wake одразу після підписки та на кожну зміну.
+// Повертається, коли скасовано контекст або з'єднання втрачено.
+func ListenFilterChanges(ctx context.Context, dsn string, wake func()) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+FilterChangesChannel); err != nil {
+		return err
+	}
+	// Зміни, зроблені до підписки, видно лише в журналі
+	wake()
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return err
+		}
+		wake()
+	}
+}
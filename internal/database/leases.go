@@ -0,0 +1,139 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ScraperInstance - запущений екземпляр скрапера
+type ScraperInstance struct {
+	ID          string    `gorm:"primaryKey;size:100"`
+	StartedAt   time.Time `gorm:"autoCreateTime"`
+	HeartbeatAt time.Time
+}
+
+// FilterLease - право екземпляра скрапити фільтр до ExpiresAt
+type FilterLease struct {
+	FilterID   uint      `gorm:"primaryKey"`
+	Owner      string    `gorm:"size:100;index;not null"`
+	AcquiredAt time.Time `gorm:"autoCreateTime"`
+	ExpiresAt  time.Time `gorm:"not null"`
+}
+
+// leaseExpiry - SQL-вираз часу закінчення оренди; час береться з бази,
+// щоб розбіжність годинників екземплярів не впливала на оренду
+func leaseExpiry(ttl time.Duration) clause.Expr {
+	return gorm.Expr("NOW() + make_interval(secs => ?)", ttl.Seconds())
+}
+
+// Heartbeat оновлює пульс екземпляра, прибирає мертві екземпляри і
+// повертає кількість живих
+func (db *DB) Heartbeat(instanceID string, ttl time.Duration) (int, error) {
+	var live int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO scraper_instances (id, started_at, heartbeat_at)
+			VALUES (?, NOW(), NOW())
+			ON CONFLICT (id) DO UPDATE SET heartbeat_at = NOW()`, instanceID).Error
+		if err != nil {
+			return err
+		}
+
+		dead := gorm.Expr("NOW() - make_interval(secs => ?)", ttl.Seconds())
+		if err := tx.Where("heartbeat_at < ?", dead).Delete(&ScraperInstance{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ScraperInstance{}).Count(&live).Error
+	})
+	return int(live), err
+}
+
+// RenewLeases продовжує всі оренди екземпляра і повертає їхні фільтри
+func (db *DB) RenewLeases(owner string, ttl time.Duration) ([]uint, error) {
+	var leases []FilterLease
+	err := db.Model(&leases).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "filter_id"}}}).
+		Where("owner = ?", owner).
+		Update("expires_at", leaseExpiry(ttl)).Error
+	return leaseFilterIDs(leases), err
+}
+
+// ClaimLeases забирає до limit активних фільтрів без чинної оренди
+func (db *DB) ClaimLeases(owner string, ttl time.Duration, limit int) ([]uint, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var leases []FilterLease
+	err := db.Raw(`
+		INSERT INTO filter_leases (filter_id, owner, acquired_at, expires_at)
+		SELECT f.id, ?, NOW(), NOW() + make_interval(secs => ?)
+		FROM user_filters f
+		LEFT JOIN filter_leases l ON l.filter_id = f.id
+		WHERE f.is_active AND (l.filter_id IS NULL OR l.expires_at < NOW())
+		ORDER BY f.id
+		LIMIT ?
+		ON CONFLICT (filter_id) DO UPDATE
+		SET owner = EXCLUDED.owner, acquired_at = EXCLUDED.acquired_at, expires_at = EXCLUDED.expires_at
+		WHERE filter_leases.expires_at < NOW()
+		RETURNING filter_id`, owner, ttl.Seconds(), limit).Scan(&leases).Error
+	return leaseFilterIDs(leases), err
+}
+
+// ClaimLease забирає конкретний фільтр, якщо ним ніхто не володіє.
+// Повертає true, якщо фільтр належить екземпляру.
+func (db *DB) ClaimLease(owner string, filterID uint, ttl time.Duration) (bool, error) {
+	var leases []FilterLease
+	err := db.Raw(`
+		INSERT INTO filter_leases (filter_id, owner, acquired_at, expires_at)
+		VALUES (?, ?, NOW(), NOW() + make_interval(secs => ?))
+		ON CONFLICT (filter_id) DO UPDATE
+		SET owner = EXCLUDED.owner, acquired_at = EXCLUDED.acquired_at, expires_at = EXCLUDED.expires_at
+		WHERE filter_leases.expires_at < NOW() OR filter_leases.owner = EXCLUDED.owner
+		RETURNING filter_id`, filterID, owner, ttl.Seconds()).Scan(&leases).Error
+	return len(leases) > 0, err
+}
+
+// ReleaseLeases віддає фільтри іншим екземплярам
+func (db *DB) ReleaseLeases(owner string, filterIDs []uint) error {
+	if len(filterIDs) == 0 {
+		return nil
+	}
+	return db.Where("owner = ? AND filter_id IN ?", owner, filterIDs).Delete(&FilterLease{}).Error
+}
+
+// ReleaseInstance віддає всі оренди екземпляра і прибирає його з живих
+func (db *DB) ReleaseInstance(owner string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("owner = ?", owner).Delete(&FilterLease{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", owner).Delete(&ScraperInstance{}).Error
+	})
+}
+
+func (db *DB) CountActiveFilters() (int, error) {
+	var count int64
+	err := db.Model(&UserFilter{}).Where("is_active = ?", true).Count(&count).Error
+	return int(count), err
+}
+
+// GetActiveFiltersByIDs повертає активні фільтри з переліку разом з власниками
+func (db *DB) GetActiveFiltersByIDs(filterIDs []uint) ([]*UserFilter, error) {
+	var filters []*UserFilter
+	if len(filterIDs) == 0 {
+		return filters, nil
+	}
+	err := db.Where("id IN ? AND is_active = ?", filterIDs, true).Preload("User").Find(&filters).Error
+	return filters, err
+}
+
+func leaseFilterIDs(leases []FilterLease) []uint {
+	ids := make([]uint, 0, len(leases))
+	for _, lease := range leases {
+		ids = append(ids, lease.FilterID)
+	}
+	return ids
+}
@@ -3,6 +3,8 @@ package database
 import (
 	"time"
 
+	"olx-hunter/internal/models"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -28,19 +30,114 @@ type UserFilter struct {
 	IsActive  bool      `json:"is_active" gorm:"default:true"`
 	CreatedAt time.Time `json:"created_at"`
 
+	// Налаштування, що змінюються через /set
+	MaxPages          int    `json:"max_pages" gorm:"default:0"`
+	SortOrder         string `json:"sort_order" gorm:"size:30"`
+	IncludeNegotiable bool   `json:"include_negotiable" gorm:"default:true"`
+	MaxAgeHours       int    `json:"max_age_hours" gorm:"default:0"`
+	SkipPromoted      bool   `json:"skip_promoted" gorm:"default:false"`
+	Backend           string `json:"backend" gorm:"size:20"`
+	ScrapeInterval    int    `json:"scrape_interval" gorm:"default:0"` // секунди, 0 - за замовчуванням
+	PriceAlerts       bool   `json:"price_alerts" gorm:"default:false"`
+	MinPriceDrop      int    `json:"min_price_drop" gorm:"default:0"` // відсотки, 0 - будь-яке зниження
+	ClosedAlerts      bool   `json:"closed_alerts" gorm:"default:false"`
+	RepostMode        string `json:"repost_mode" gorm:"size:10"`
+
 	User User `gorm:"foreignKey:UserID"`
 }
 
+// SavedListing - оголошення, спільне для всіх фільтрів, що його знайшли.
+// Чи бачив його конкретний фільтр, зберігається у FilterMatch.
 type SavedListing struct {
-	ID         uint      `gorm:"primaryKey"`
-	FilterID   uint      `gorm:"index"`
-	URL        string    `gorm:"uniqueIndex;size:500"`
-	Title      string    `gorm:"size:300"`
-	Price      string    `gorm:"size:500"`
-	Location   string    `gorm:"size:200"`
+	ID       uint   `gorm:"primaryKey"`
+	FilterID uint   `gorm:"index"` // фільтр, що знайшов оголошення першим
+	URL      string `gorm:"uniqueIndex;size:500"`
+	Title    string `gorm:"size:300"`
+	Price    string `gorm:"size:500"`
+	Location string `gorm:"size:200"`
+	City     string `gorm:"size:100"`
+	District string `gorm:"size:100"`
+	Promoted bool   `gorm:"default:false"`
+
+	PriceInfo models.Price `gorm:"embedded;embeddedPrefix:price_"`
+
+	AdID        string   `gorm:"size:30"`
+	Description string   `gorm:"type:text"`
+	Photos      []string `gorm:"type:jsonb;serializer:json"`
+	SellerName  string   `gorm:"size:100"`
+	SellerURL   string   `gorm:"size:500"`
+	PostedAt    *time.Time
+	Views       int
+	Category    []string `gorm:"type:jsonb;serializer:json"`
+
+	Fingerprint string `gorm:"size:40;index"`
+	PhotoHash   int64  `gorm:"default:0"` // dHash першого фото, 0 - немає
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	// LastSeenAt - коли оголошення востаннє було у видачі, CheckedAt - коли
+	// верифікатор відкривав його сторінку, ClosedAt - коли воно зникло з OLX
+	LastSeenAt time.Time `gorm:"index"`
+	CheckedAt  *time.Time
+	ClosedAt   *time.Time
+
+	// VerifyClaimedUntil - до якого часу сторінку перевіряє один з екземплярів
+	VerifyClaimedUntil *time.Time
+}
+
+// ToListing повертає збережене оголошення у вигляді моделі для сповіщень
+func (l *SavedListing) ToListing() models.Listing {
+	listing := models.Listing{
+		URL:       l.URL,
+		Title:     l.Title,
+		Price:     l.Price,
+		PriceInfo: l.PriceInfo,
+		Location:  l.Location,
+		City:      l.City,
+		District:  l.District,
+		Promoted:  l.Promoted,
+
+		AdID:        l.AdID,
+		Description: l.Description,
+		Photos:      l.Photos,
+		SellerName:  l.SellerName,
+		SellerURL:   l.SellerURL,
+		Views:       l.Views,
+		Category:    l.Category,
+	}
+	if l.PostedAt != nil {
+		listing.PostedAt = *l.PostedAt
+	}
+	return listing
+}
+
+// FilterMatch - оголошення, знайдене конкретним фільтром, і чи надіслано
+// про нього сповіщення власнику фільтра
+type FilterMatch struct {
+	ID         uint `gorm:"primaryKey"`
+	FilterID   uint `gorm:"uniqueIndex:idx_filter_listing;not null"`
+	ListingID  uint `gorm:"uniqueIndex:idx_filter_listing;not null"`
+	IsNotified bool `gorm:"default:false"`
+	NotifiedAt *time.Time
 	CreatedAt  time.Time `gorm:"autoCreateTime"`
-	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
-	IsNotified bool      `gorm:"default:false;index"`
+}
+
+// PriceHistory - зміна ціни збереженого оголошення
+type PriceHistory struct {
+	ID        uint `gorm:"primaryKey"`
+	ListingID uint `gorm:"index;not null"`
+
+	OldPrice     string       `gorm:"size:500"`
+	OldPriceInfo models.Price `gorm:"embedded;embeddedPrefix:old_price_"`
+	NewPrice     string       `gorm:"size:500"`
+	NewPriceInfo models.Price `gorm:"embedded;embeddedPrefix:new_price_"`
+
+	ChangedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (PriceHistory) TableName() string {
+	return "price_history"
 }
 
 type DB struct {
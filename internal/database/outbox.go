@@ -0,0 +1,160 @@
+package database
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"olx-hunter/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxMessage - сповіщення, що чекає на доставку в Telegram
+type OutboxMessage struct {
+	ID            uint      `gorm:"primaryKey"`
+	TelegramID    int64     `gorm:"not null"`
+	FilterID      uint      // фільтр може бути вже видалений, сповіщення все одно доставляється
+	Payload       string    `gorm:"type:text;not null"` // models.Notification у JSON
+	Attempts      int       `gorm:"default:0"`
+	NextAttemptAt time.Time `gorm:"default:now()"` // час бази, як і в ClaimOutbox
+	LastError     string    `gorm:"size:500"`
+	DeliveredAt   *time.Time
+	FailedAt      *time.Time // доставку припинено після помилки, яку повтор не виправить
+	CreatedAt     time.Time
+}
+
+func (OutboxMessage) TableName() string {
+	return "notification_outbox"
+}
+
+// NewOutboxMessage кодує сповіщення фільтра для outbox
+func NewOutboxMessage(filterID uint, notif models.Notification) (*OutboxMessage, error) {
+	payload, err := json.Marshal(notif)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxMessage{
+		TelegramID: notif.TelegramID,
+		FilterID:   filterID,
+		Payload:    string(payload),
+	}, nil
+}
+
+// Notification розбирає збережене сповіщення
+func (m *OutboxMessage) Notification() (models.Notification, error) {
+	var notif models.Notification
+	err := json.Unmarshal([]byte(m.Payload), &notif)
+	return notif, err
+}
+
+// SaveNewListings зберігає нові для фільтра оголошення і, якщо notification
+// не nil, кладе сповіщення про них в outbox в тій самій транзакції. Збіги
+// одразу вважаються обробленими: доставку далі гарантує outbox.
+func (db *DB) SaveNewListings(filterID uint, listings []models.Listing, notification *OutboxMessage) error {
+	if len(listings) == 0 && notification == nil {
+		return nil
+	}
+
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, listing := range listings {
+			match := FilterMatch{IsNotified: true, NotifiedAt: &now}
+			if err := saveMatch(tx, filterID, listing, &match); err != nil {
+				return err
+			}
+		}
+		if notification == nil {
+			return nil
+		}
+		return tx.Create(notification).Error
+	})
+}
+
+// EnqueueNotifications кладе сповіщення в outbox
+func (db *DB) EnqueueNotifications(messages []*OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return db.Create(messages).Error
+}
+
+// ClaimOutbox забирає до limit сповіщень, час доставки яких настав. Спроба
+// одразу зараховується, а наступна відкладається на lease, тож інший
+// екземпляр не візьме те саме сповіщення, а після падіння воно повториться.
+func (db *DB) ClaimOutbox(limit int, lease time.Duration) ([]OutboxMessage, error) {
+	due := db.Model(&OutboxMessage{}).Select("id").
+		Where("delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()").
+		Order("id").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var messages []OutboxMessage
+	err := db.Model(&messages).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": gorm.Expr("NOW() + make_interval(secs => ?)", lease.Seconds()),
+		}).Error
+
+	// RETURNING не зберігає порядок, а сповіщення мають іти так, як їх створили
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, err
+}
+
+// MarkOutboxDelivered позначає сповіщення доставленим
+func (db *DB) MarkOutboxDelivered(id uint) error {
+	return db.Model(&OutboxMessage{ID: id}).Update("delivered_at", gorm.Expr("NOW()")).Error
+}
+
+// RetryOutbox відкладає наступну спробу доставки на delay
+func (db *DB) RetryOutbox(id uint, lastError string, delay time.Duration) error {
+	return db.Model(&OutboxMessage{ID: id}).Updates(map[string]interface{}{
+		"last_error":      truncate(lastError, 500),
+		"next_attempt_at": gorm.Expr("NOW() + make_interval(secs => ?)", delay.Seconds()),
+	}).Error
+}
+
+// FailOutbox припиняє доставку сповіщення
+func (db *DB) FailOutbox(id uint, lastError string) error {
+	return db.Model(&OutboxMessage{ID: id}).Updates(map[string]interface{}{
+		"last_error": truncate(lastError, 500),
+		"failed_at":  gorm.Expr("NOW()"),
+	}).Error
+}
+
+// GetOutboxMessage повертає сповіщення за ID або nil, якщо його вже видалено
+func (db *DB) GetOutboxMessage(id uint) (*OutboxMessage, error) {
+	var message OutboxMessage
+	err := db.First(&message, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &message, err
+}
+
+// CountPendingOutbox повертає кількість сповіщень, що чекають на доставку
+func (db *DB) CountPendingOutbox() (int, error) {
+	var count int64
+	err := db.Model(&OutboxMessage{}).
+		Where("delivered_at IS NULL AND failed_at IS NULL").
+		Count(&count).Error
+	return int(count), err
+}
+
+// PurgeOutbox видаляє доставлені і покинуті сповіщення, створені до before
+func (db *DB) PurgeOutbox(before time.Time) (int64, error) {
+	result := db.Where("created_at < ? AND (delivered_at IS NOT NULL OR failed_at IS NOT NULL)", before).
+		Delete(&OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
+
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit])
+}
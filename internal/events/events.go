@@ -0,0 +1,147 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"olx-hunter/internal/models"
+)
+
+// SchemaVersion - версія формату подій. Збільшується при несумісних змінах
+// полів, щоб споживачі могли розрізнити старі й нові повідомлення.
+const SchemaVersion = 1
+
+// Типи подій
+const (
+	TypeListingDiscovered = "listing.discovered"
+	TypePriceChanged      = "listing.price_changed"
+	TypeListingClosed     = "listing.closed"
+)
+
+// Publisher надсилає події зовнішнім споживачам
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+	Close() error
+}
+
+// Event - конверт події. Data містить один з типів нижче відповідно до Type.
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Version    int         `json:"version"`
+	FilterID   uint        `json:"filter_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// ListingDiscovered - фільтр вперше побачив оголошення. Baseline означає
+// перший прохід фільтра, про такі оголошення користувач не сповіщається.
+type ListingDiscovered struct {
+	Listing  models.Listing `json:"listing"`
+	Baseline bool           `json:"baseline"`
+}
+
+// PriceChanged - змінилась ціна оголошення, яке фільтр вже бачив
+type PriceChanged struct {
+	URL          string       `json:"url"`
+	Title        string       `json:"title"`
+	OldPrice     string       `json:"old_price"`
+	OldPriceInfo models.Price `json:"old_price_info"`
+	NewPrice     string       `json:"new_price"`
+	NewPriceInfo models.Price `json:"new_price_info"`
+}
+
+// ListingClosed - оголошення продане або зняте з публікації
+type ListingClosed struct {
+	URL         string     `json:"url"`
+	Title       string     `json:"title"`
+	Price       string     `json:"price"`
+	PostedAt    *time.Time `json:"posted_at,omitempty"`
+	FirstSeenAt time.Time  `json:"first_seen_at"`
+	ClosedAt    time.Time  `json:"closed_at"`
+}
+
+func NewListingDiscovered(filterID uint, listing models.Listing, baseline bool) Event {
+	return newEvent(TypeListingDiscovered, filterID, ListingDiscovered{Listing: listing, Baseline: baseline})
+}
+
+func NewPriceChanged(filterID uint, change PriceChanged) Event {
+	return newEvent(TypePriceChanged, filterID, change)
+}
+
+func NewListingClosed(filterID uint, closed ListingClosed) Event {
+	return newEvent(TypeListingClosed, filterID, closed)
+}
+
+func newEvent(eventType string, filterID uint, data interface{}) Event {
+	return Event{
+		ID:         newEventID(),
+		Type:       eventType,
+		Version:    SchemaVersion,
+		FilterID:   filterID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// newEventID повертає випадковий ID, за яким споживачі відкидають дублікати
+func newEventID() string {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// Topics - топіки Kafka для кожного типу подій
+type Topics struct {
+	Discovered   string
+	PriceChanged string
+	Closed       string
+}
+
+func (t Topics) topic(eventType string) (string, error) {
+	var topic string
+	switch eventType {
+	case TypeListingDiscovered:
+		topic = t.Discovered
+	case TypePriceChanged:
+		topic = t.PriceChanged
+	case TypeListingClosed:
+		topic = t.Closed
+	}
+	if topic == "" {
+		return "", fmt.Errorf("no topic for event type %q", eventType)
+	}
+	return topic, nil
+}
+
+// Message - закодована подія, готова до відправки
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// encode кодує подію в JSON. Ключ - ID фільтра, тож події одного фільтра
+// потрапляють в одну партицію і читаються по порядку.
+func encode(topics Topics, event Event) (Message, error) {
+	topic, err := topics.topic(event.Type)
+	if err != nil {
+		return Message{}, err
+	}
+	value, err := json.Marshal(event)
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+	}
+	return Message{
+		Topic: topic,
+		Key:   []byte(strconv.FormatUint(uint64(event.FilterID), 10)),
+		Value: value,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"olx-hunter/internal/models"
+)
+
+var testTopics = Topics{
+	Discovered:   "listings",
+	PriceChanged: "prices",
+	Closed:       "closed",
+}
+
+func TestMemoryPublisherRoutesEvents(t *testing.T) {
+	publisher := NewMemoryPublisher(testTopics)
+	listing := models.Listing{URL: "https://www.olx.ua/d/obyavlenie/iphone-IDabc.html", Title: "iPhone 15", Price: "30 000 грн."}
+
+	err := publisher.Publish(context.Background(),
+		NewListingDiscovered(42, listing, false),
+		NewPriceChanged(42, PriceChanged{URL: listing.URL, OldPrice: "30 000 грн.", NewPrice: "28 000 грн."}),
+		NewListingClosed(7, ListingClosed{URL: listing.URL}),
+	)
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	messages := publisher.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+
+	expected := []struct{ topic, key string }{
+		{"listings", "42"},
+		{"prices", "42"},
+		{"closed", "7"},
+	}
+	for i, want := range expected {
+		if messages[i].Topic != want.topic || string(messages[i].Key) != want.key {
+			t.Errorf("Message %d: expected %s/%s, got %s/%s", i, want.topic, want.key, messages[i].Topic, messages[i].Key)
+		}
+	}
+
+	var decoded struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Version int    `json:"version"`
+		Data    struct {
+			Listing  models.Listing `json:"listing"`
+			Baseline bool           `json:"baseline"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(messages[0].Value, &decoded); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if decoded.ID == "" || decoded.Type != TypeListingDiscovered || decoded.Version != SchemaVersion {
+		t.Errorf("Unexpected envelope: %+v", decoded)
+	}
+	if decoded.Data.Listing.Title != "iPhone 15" {
+		t.Errorf("Listing was not encoded: %+v", decoded.Data)
+	}
+}
+
+func TestMemoryPublisherRejectsUnknownTopics(t *testing.T) {
+	publisher := NewMemoryPublisher(Topics{Discovered: "listings"})
+
+	if err := publisher.Publish(context.Background(), NewListingClosed(1, ListingClosed{})); err == nil {
+		t.Error("Expected an error for an event without a topic")
+	}
+	if len(publisher.Messages()) != 0 {
+		t.Error("Nothing should be published on error")
+	}
+
+	publisher.Close()
+	if err := publisher.Publish(context.Background(), NewListingDiscovered(1, models.Listing{}, true)); err != ErrPublisherClosed {
+		t.Errorf("Expected ErrPublisherClosed, got %v", err)
+	}
+}
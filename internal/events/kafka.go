@@ -0,0 +1,89 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// writeTimeout обмежує, скільки публікація може чекати на метадані брокера
+const writeTimeout = 5 * time.Second
+
+// KafkaPublisher надсилає події в Kafka асинхронно: скрапінг не чекає на
+// підтвердження брокера, а помилки доставки рахуються для /status
+type KafkaPublisher struct {
+	writer *kafka.Writer
+	topics Topics
+
+	mutex     sync.Mutex
+	published int
+	failed    int
+	lastError string
+}
+
+func NewKafkaPublisher(brokers []string, topics Topics) *KafkaPublisher {
+	p := &KafkaPublisher{topics: topics}
+	p.writer = &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: 100 * time.Millisecond,
+		Async:        true,
+		Completion:   p.completion,
+	}
+	return p
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, events ...Event) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		msg, err := encode(p.topics, event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{Topic: msg.Topic, Key: msg.Key, Value: msg.Value})
+	}
+
+	// Подія вже сталася, тож зупинка сервісу не повинна її загубити
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+	defer cancel()
+	if err := p.writer.WriteMessages(ctx, messages...); err != nil {
+		p.completion(messages, err)
+		return err
+	}
+	return nil
+}
+
+func (p *KafkaPublisher) completion(messages []kafka.Message, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err != nil {
+		p.failed += len(messages)
+		p.lastError = err.Error()
+		log.Printf("Failed to deliver %d events to Kafka: %v", len(messages), err)
+		return
+	}
+	p.published += len(messages)
+}
+
+// Close доставляє події з буфера і закриває з'єднання
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// StatusReport - стан публікації для команди /status
+func (p *KafkaPublisher) StatusReport() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	text := fmt.Sprintf("📤 Kafka: доставлено %d подій, помилок %d\n", p.published, p.failed)
+	if p.lastError != "" {
+		text += fmt.Sprintf("   остання помилка: %s\n", p.lastError)
+	}
+	return text
+}
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPublisherClosed повертається при публікації після Close
+var ErrPublisherClosed = errors.New("publisher is closed")
+
+// MemoryPublisher зберігає закодовані події в пам'яті, для тестів
+type MemoryPublisher struct {
+	topics Topics
+
+	mutex    sync.Mutex
+	messages []Message
+	closed   bool
+}
+
+func NewMemoryPublisher(topics Topics) *MemoryPublisher {
+	return &MemoryPublisher{topics: topics}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, events ...Event) error {
+	messages := make([]Message, 0, len(events))
+	for _, event := range events {
+		msg, err := encode(p.topics, event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, msg)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return ErrPublisherClosed
+	}
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+// Messages повертає всі опубліковані повідомлення по порядку
+func (p *MemoryPublisher) Messages() []Message {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]Message(nil), p.messages...)
+}
+
+func (p *MemoryPublisher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	return nil
+}
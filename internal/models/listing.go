@@ -1,11 +1,42 @@
 package models
 
+import "time"
+
 type Listing struct {
-	URL      string `json:"url"`
-	Title    string `json:"title"`
-	Price    string `json:"price"`
-	PriceInt int    `json:"price_int"`
-	Location string `json:"location"`
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Price     string `json:"price"`
+	PriceInfo Price  `json:"price_info"`
+	Location  string `json:"location"`
+
+	// Розібраний рядок локації та дати з картки
+	City     string    `json:"city,omitempty"`
+	District string    `json:"district,omitempty"`
+	Region   string    `json:"region,omitempty"` // лише з API, картки видачі області не показують
+	PostedAt time.Time `json:"posted_at"`
+	Promoted bool      `json:"promoted,omitempty"`
+
+	// Поля нижче заповнюються зі сторінки оголошення (FetchDetails)
+	AdID        string   `json:"ad_id,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Photos      []string `json:"photos,omitempty"`
+	SellerName  string   `json:"seller_name,omitempty"`
+	SellerURL   string   `json:"seller_url,omitempty"`
+	Views       int      `json:"views,omitempty"`
+	Category    []string `json:"category,omitempty"`
+
+	// Fingerprint і PhotoHash визначають репости того самого товару
+	Fingerprint string `json:"-"`
+	PhotoHash   uint64 `json:"-"`
+	// Repost заповнюється, якщо фільтр вже бачив цей товар
+	Repost *Repost `json:"repost,omitempty"`
+}
+
+// Repost - попереднє оголошення того самого товару
+type Repost struct {
+	URL    string    `json:"url"`
+	SeenAt time.Time `json:"seen_at"`
+	Price  string    `json:"price"`
 }
 
 type SearchFilters struct {
@@ -13,10 +44,42 @@ type SearchFilters struct {
 	MinPrice int    `json:"min_price"`
 	MaxPrice int    `json:"max_price"`
 	City     string `json:"city"`
+
+	// SortOrder - значення search[order] для OLX, порожнє означає найновіші
+	SortOrder string `json:"sort_order"`
+
+	// IncludeNegotiable - чи показувати оголошення з ціною "Договірна"
+	IncludeNegotiable bool `json:"include_negotiable"`
+
+	// MaxAgeHours відкидає оголошення, опубліковані раніше ніж N годин тому
+	MaxAgeHours int `json:"max_age_hours"`
+
+	// SkipPromoted відкидає рекламні (ТОП) оголошення
+	SkipPromoted bool `json:"skip_promoted"`
+
+	// MaxPages обмежує глибину пагінації, KnownURLs зупиняє її на першому
+	// вже збереженому оголошенні. Відомі оголошення зі сторінок, які встигли
+	// відкрити, теж повертаються.
+	MaxPages  int             `json:"max_pages"`
+	KnownURLs map[string]bool `json:"-"`
 }
 
 type Notification struct {
-	TelegramID int64
-	FilterName string
-	Listings   []Listing
+	TelegramID int64     `json:"telegram_id"`
+	FilterName string    `json:"filter_name"`
+	Listings   []Listing `json:"listings,omitempty"`
+
+	// PriceDrops - оголошення, що подешевшали (замість нових оголошень)
+	PriceDrops []PriceDrop `json:"price_drops,omitempty"`
+	// Closed - оголошення, зняті з публікації або продані
+	Closed []Listing `json:"closed,omitempty"`
+	// Alert - службове повідомлення адміністратору від окремого скрапера
+	Alert string `json:"alert,omitempty"`
+}
+
+// PriceDrop - зниження ціни вже відомого оголошення
+type PriceDrop struct {
+	Listing  Listing `json:"listing"`
+	OldPrice string  `json:"old_price"`
+	Percent  float64 `json:"percent"`
 }
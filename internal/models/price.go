@@ -0,0 +1,34 @@
+package models
+
+// Price - розібрана ціна оголошення
+type Price struct {
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+	Negotiable bool    `json:"negotiable"`
+	Free       bool    `json:"free"`
+	Exchange   bool    `json:"exchange"`
+}
+
+// CurrencyRates - курс валют у гривнях за одиницю, наприклад {"USD": 41.5}
+type CurrencyRates map[string]float64
+
+const CurrencyUAH = "UAH"
+
+// InUAH повертає суму в гривнях. ok=false, якщо суми немає (обмін,
+// договірна без ціни) або курс валюти невідомий.
+func (p Price) InUAH(rates CurrencyRates) (amount float64, ok bool) {
+	if p.Free {
+		return 0, true
+	}
+	if p.Amount <= 0 {
+		return 0, false
+	}
+	if p.Currency == "" || p.Currency == CurrencyUAH {
+		return p.Amount, true
+	}
+	rate, exists := rates[p.Currency]
+	if !exists || rate <= 0 {
+		return 0, false
+	}
+	return p.Amount * rate, true
+}
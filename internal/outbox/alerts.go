@@ -0,0 +1,33 @@
+package outbox
+
+import (
+	"log"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/models"
+)
+
+// AdminAlerts повертає обробник службових повідомлень, який кладе їх в
+// outbox для кожного адміністратора. Ним користується скрапер, запущений
+// окремо від бота: повідомлення доставить диспетчер у процесі бота.
+func AdminAlerts(db *database.DB, adminIDs []int64) func(text string) {
+	return func(text string) {
+		if len(adminIDs) == 0 {
+			log.Printf("No admins configured, alert dropped: %s", text)
+			return
+		}
+
+		messages := make([]*database.OutboxMessage, 0, len(adminIDs))
+		for _, adminID := range adminIDs {
+			message, err := database.NewOutboxMessage(0, models.Notification{TelegramID: adminID, Alert: text})
+			if err != nil {
+				log.Printf("Failed to encode admin alert: %v", err)
+				return
+			}
+			messages = append(messages, message)
+		}
+		if err := db.EnqueueNotifications(messages); err != nil {
+			log.Printf("Failed to queue admin alert: %v", err)
+		}
+	}
+}
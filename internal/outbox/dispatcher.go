@@ -0,0 +1,243 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/models"
+)
+
+// claimLease - на скільки відкладається сповіщення, поки його доставляють.
+// Якщо процес впаде посеред доставки, сповіщення повториться після цього часу.
+const claimLease = 2 * time.Minute
+
+// Sender доставляє сповіщення користувачу. id - номер сповіщення в outbox.
+type Sender interface {
+	Deliver(id uint, notif models.Notification) error
+}
+
+// permanentError - помилка, яку повтор не виправить (бота заблоковано)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent позначає помилку доставки як остаточну
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// retryAfterError - помилка, після якої одержувач просить почекати
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// RetryAfter просить повторити доставку не раніше ніж через delay
+func RetryAfter(err error, delay time.Duration) error {
+	return &retryAfterError{err: err, delay: delay}
+}
+
+// Store - черга сповіщень у базі. Її реалізує *database.DB.
+type Store interface {
+	ClaimOutbox(limit int, lease time.Duration) ([]database.OutboxMessage, error)
+	MarkOutboxDelivered(id uint) error
+	RetryOutbox(id uint, lastError string, delay time.Duration) error
+	FailOutbox(id uint, lastError string) error
+	CountPendingOutbox() (int, error)
+	PurgeOutbox(before time.Time) (int64, error)
+}
+
+// Options - налаштування доставки
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseDelay    time.Duration // пауза після першої невдачі, далі подвоюється
+	MaxDelay     time.Duration
+	Retention    time.Duration // скільки зберігати доставлені сповіщення
+}
+
+// Dispatcher доставляє сповіщення з outbox і позначає їх доставленими лише
+// після того, як Telegram прийняв повідомлення. Кілька екземплярів можуть
+// працювати одночасно: кожне сповіщення забирає лише один.
+type Dispatcher struct {
+	db     Store
+	sender Sender
+	opts   Options
+
+	mutex     sync.Mutex
+	delivered int
+	retried   int
+	failed    int
+	lastPurge time.Time
+}
+
+func NewDispatcher(db Store, sender Sender, opts Options) *Dispatcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 20
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 10
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = 5 * time.Second
+	}
+	if opts.MaxDelay < opts.BaseDelay {
+		opts.MaxDelay = time.Hour
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 7 * 24 * time.Hour
+	}
+	return &Dispatcher{db: db, sender: sender, opts: opts}
+}
+
+// Run доставляє сповіщення, поки не скасовано контекст
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Println("Notification dispatcher started")
+
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.DispatchOnce(ctx)
+		d.purge()
+
+		select {
+		case <-ctx.Done():
+			log.Println("Notification dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce доставляє всі сповіщення, час яких настав
+func (d *Dispatcher) DispatchOnce(ctx context.Context) {
+	for ctx.Err() == nil {
+		messages, err := d.db.ClaimOutbox(d.opts.BatchSize, claimLease)
+		if err != nil {
+			log.Printf("Failed to claim notifications: %v", err)
+			return
+		}
+
+		for i := range messages {
+			if ctx.Err() != nil {
+				// Незавершені сповіщення повторяться після claimLease
+				return
+			}
+			d.deliver(&messages[i])
+		}
+
+		if len(messages) < d.opts.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(message *database.OutboxMessage) {
+	notif, err := message.Notification()
+	if err != nil {
+		log.Printf("Notification %d is corrupted: %v", message.ID, err)
+		d.fail(message, err)
+		return
+	}
+
+	err = d.sender.Deliver(message.ID, notif)
+	if err == nil {
+		if err := d.db.MarkOutboxDelivered(message.ID); err != nil {
+			log.Printf("Failed to mark notification %d as delivered: %v", message.ID, err)
+		}
+		d.count(&d.delivered)
+		return
+	}
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) || message.Attempts >= d.opts.MaxAttempts {
+		log.Printf("Giving up on notification %d to %d after %d attempts: %v",
+			message.ID, message.TelegramID, message.Attempts, err)
+		d.fail(message, err)
+		return
+	}
+
+	delay := retryDelay(message.Attempts, d.opts.BaseDelay, d.opts.MaxDelay)
+	var retryAfter *retryAfterError
+	if errors.As(err, &retryAfter) && retryAfter.delay > delay {
+		delay = retryAfter.delay
+	}
+	log.Printf("Failed to deliver notification %d (attempt %d), retrying in %v: %v",
+		message.ID, message.Attempts, delay, err)
+	if err := d.db.RetryOutbox(message.ID, err.Error(), delay); err != nil {
+		log.Printf("Failed to reschedule notification %d: %v", message.ID, err)
+	}
+	d.count(&d.retried)
+}
+
+func (d *Dispatcher) fail(message *database.OutboxMessage, cause error) {
+	if err := d.db.FailOutbox(message.ID, cause.Error()); err != nil {
+		log.Printf("Failed to mark notification %d as failed: %v", message.ID, err)
+	}
+	d.count(&d.failed)
+}
+
+// purge раз на годину прибирає старі доставлені сповіщення
+func (d *Dispatcher) purge() {
+	if time.Since(d.lastPurge) < time.Hour {
+		return
+	}
+	d.lastPurge = time.Now()
+
+	removed, err := d.db.PurgeOutbox(time.Now().Add(-d.opts.Retention))
+	if err != nil {
+		log.Printf("Failed to purge notification outbox: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Purged %d old notifications from outbox", removed)
+	}
+}
+
+func (d *Dispatcher) count(counter *int) {
+	d.mutex.Lock()
+	*counter++
+	d.mutex.Unlock()
+}
+
+// retryDelay - експоненційна пауза перед спробою attempt+1
+func retryDelay(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// StatusReport - стан доставки для команди /status
+func (d *Dispatcher) StatusReport() string {
+	pending, err := d.db.CountPendingOutbox()
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	text := fmt.Sprintf("📬 Сповіщення: доставлено %d, повторів %d, не доставлено %d\n", d.delivered, d.retried, d.failed)
+	if err == nil {
+		text += fmt.Sprintf("   у черзі: %d\n", pending)
+	}
+	return text
+}
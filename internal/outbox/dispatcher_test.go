@@ -0,0 +1,273 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/models"
+)
+
+func TestRetryDelay(t *testing.T) {
+	base, max := 5*time.Second, time.Minute
+
+	expected := []time.Duration{
+		5 * time.Second,  // після першої спроби
+		10 * time.Second, // після другої
+		20 * time.Second,
+		40 * time.Second,
+		time.Minute, // далі не більше max
+		time.Minute,
+	}
+	for i, want := range expected {
+		if got := retryDelay(i+1, base, max); got != want {
+			t.Errorf("Attempt %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+}
+
+func TestErrorWrappers(t *testing.T) {
+	cause := errors.New("Forbidden: bot was blocked by the user")
+
+	var permanent *permanentError
+	if !errors.As(Permanent(cause), &permanent) || !errors.Is(Permanent(cause), cause) {
+		t.Error("Permanent error should be detectable and keep the cause")
+	}
+	if errors.As(cause, &permanent) {
+		t.Error("Plain error should not be permanent")
+	}
+
+	var retryAfter *retryAfterError
+	if !errors.As(RetryAfter(cause, 30*time.Second), &retryAfter) || retryAfter.delay != 30*time.Second {
+		t.Error("RetryAfter should carry the delay")
+	}
+}
+
+// memStore - outbox у пам'яті з годинником, який рухає тест
+type memStore struct {
+	mutex    sync.Mutex
+	now      time.Time
+	messages []*database.OutboxMessage
+}
+
+func newMemStore(t *testing.T, count int) *memStore {
+	t.Helper()
+	store := &memStore{now: time.Now()}
+	for i := 1; i <= count; i++ {
+		message, err := database.NewOutboxMessage(uint(i), models.Notification{TelegramID: int64(100 + i), Alert: fmt.Sprintf("alert %d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		message.ID = uint(i)
+		message.NextAttemptAt = store.now
+		store.messages = append(store.messages, message)
+	}
+	return store
+}
+
+func (m *memStore) advance(d time.Duration) {
+	m.mutex.Lock()
+	m.now = m.now.Add(d)
+	m.mutex.Unlock()
+}
+
+func (m *memStore) message(id uint) database.OutboxMessage {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return *m.messages[id-1]
+}
+
+func (m *memStore) ClaimOutbox(limit int, lease time.Duration) ([]database.OutboxMessage, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var claimed []database.OutboxMessage
+	for _, message := range m.messages {
+		if len(claimed) == limit {
+			break
+		}
+		if message.DeliveredAt != nil || message.FailedAt != nil || message.NextAttemptAt.After(m.now) {
+			continue
+		}
+		message.Attempts++
+		message.NextAttemptAt = m.now.Add(lease)
+		claimed = append(claimed, *message)
+	}
+	return claimed, nil
+}
+
+func (m *memStore) MarkOutboxDelivered(id uint) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := m.now
+	m.messages[id-1].DeliveredAt = &now
+	return nil
+}
+
+func (m *memStore) RetryOutbox(id uint, lastError string, delay time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.messages[id-1].LastError = lastError
+	m.messages[id-1].NextAttemptAt = m.now.Add(delay)
+	return nil
+}
+
+func (m *memStore) FailOutbox(id uint, lastError string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := m.now
+	m.messages[id-1].LastError = lastError
+	m.messages[id-1].FailedAt = &now
+	return nil
+}
+
+func (m *memStore) CountPendingOutbox() (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	pending := 0
+	for _, message := range m.messages {
+		if message.DeliveredAt == nil && message.FailedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *memStore) PurgeOutbox(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// senderFunc - відправник з функції, що рахує спроби
+type senderFunc func(id uint, notif models.Notification) error
+
+func (f senderFunc) Deliver(id uint, notif models.Notification) error {
+	return f(id, notif)
+}
+
+func newTestDispatcher(store Store, sender Sender) *Dispatcher {
+	return NewDispatcher(store, sender, Options{BatchSize: 2, MaxAttempts: 3, BaseDelay: 5 * time.Second, MaxDelay: time.Minute})
+}
+
+func TestDispatchDeliversInOrder(t *testing.T) {
+	store := newMemStore(t, 3)
+	var delivered []uint
+	dispatcher := newTestDispatcher(store, senderFunc(func(id uint, notif models.Notification) error {
+		if notif.TelegramID != int64(100+id) {
+			t.Errorf("Notification %d was sent to %d", id, notif.TelegramID)
+		}
+		delivered = append(delivered, id)
+		return nil
+	}))
+
+	// Пачка з двох, тож третє сповіщення забирається другим запитом
+	dispatcher.DispatchOnce(context.Background())
+
+	if len(delivered) != 3 || delivered[0] != 1 || delivered[1] != 2 || delivered[2] != 3 {
+		t.Errorf("Expected notifications 1, 2, 3 in order, got %v", delivered)
+	}
+	if pending, _ := store.CountPendingOutbox(); pending != 0 {
+		t.Errorf("Expected nothing pending, got %d", pending)
+	}
+}
+
+func TestDispatchRetriesWithBackoff(t *testing.T) {
+	store := newMemStore(t, 2)
+	calls := make(map[uint]int)
+	dispatcher := newTestDispatcher(store, senderFunc(func(id uint, notif models.Notification) error {
+		calls[id]++
+		if calls[id] > 1 {
+			return nil
+		}
+		if id == 2 {
+			return RetryAfter(errors.New("Too Many Requests"), 30*time.Second)
+		}
+		return errors.New("connection reset")
+	}))
+
+	dispatcher.DispatchOnce(context.Background())
+	if first := store.message(1); first.DeliveredAt != nil || first.LastError != "connection reset" {
+		t.Fatalf("Failed notification should wait for a retry, got %+v", first)
+	}
+
+	// Повтор не раніше BaseDelay
+	dispatcher.DispatchOnce(context.Background())
+	if calls[1] != 1 {
+		t.Fatalf("Notification should not be retried before the delay, got %d calls", calls[1])
+	}
+
+	store.advance(5 * time.Second)
+	dispatcher.DispatchOnce(context.Background())
+	if store.message(1).DeliveredAt == nil {
+		t.Error("Notification should be delivered on retry")
+	}
+	// Одержувач попросив почекати довше за BaseDelay
+	if store.message(2).DeliveredAt != nil {
+		t.Error("Notification should wait as long as the recipient asked")
+	}
+
+	store.advance(25 * time.Second)
+	dispatcher.DispatchOnce(context.Background())
+	if store.message(2).DeliveredAt == nil || calls[2] != 2 {
+		t.Errorf("Notification should be delivered after retry-after, got %d calls", calls[2])
+	}
+}
+
+func TestDispatchStopsOnPermanentFailure(t *testing.T) {
+	store := newMemStore(t, 1)
+	calls := 0
+	dispatcher := newTestDispatcher(store, senderFunc(func(id uint, notif models.Notification) error {
+		calls++
+		return Permanent(errors.New("Forbidden: bot was blocked by the user"))
+	}))
+
+	dispatcher.DispatchOnce(context.Background())
+	store.advance(time.Hour)
+	dispatcher.DispatchOnce(context.Background())
+
+	message := store.message(1)
+	if message.FailedAt == nil || message.DeliveredAt != nil {
+		t.Errorf("Permanent failure should stop delivery, got %+v", message)
+	}
+	if calls != 1 {
+		t.Errorf("Permanent failure should not be retried, got %d calls", calls)
+	}
+}
+
+func TestDispatchGivesUpAfterMaxAttempts(t *testing.T) {
+	store := newMemStore(t, 1)
+	calls := 0
+	dispatcher := newTestDispatcher(store, senderFunc(func(id uint, notif models.Notification) error {
+		calls++
+		return errors.New("connection reset")
+	}))
+
+	for i := 0; i < 5; i++ {
+		dispatcher.DispatchOnce(context.Background())
+		store.advance(time.Minute)
+	}
+
+	message := store.message(1)
+	if calls != 3 || message.Attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d calls and %d attempts", calls, message.Attempts)
+	}
+	if message.FailedAt == nil {
+		t.Error("Notification should be given up after MaxAttempts")
+	}
+}
+
+func TestDispatchFailsCorruptedNotification(t *testing.T) {
+	store := newMemStore(t, 1)
+	store.messages[0].Payload = "{"
+	dispatcher := newTestDispatcher(store, senderFunc(func(id uint, notif models.Notification) error {
+		t.Error("Corrupted notification should not be sent")
+		return nil
+	}))
+
+	dispatcher.DispatchOnce(context.Background())
+	if store.message(1).FailedAt == nil {
+		t.Error("Corrupted notification should be failed")
+	}
+}
@@ -0,0 +1,234 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"olx-hunter/internal/models"
+	"olx-hunter/internal/utils"
+)
+
+// apiPageSize - кількість оголошень на одну сторінку API
+const apiPageSize = 40
+
+// OLXAPIScraper читає публічний JSON ендпоінт оголошень OLX замість HTML.
+// Використовується як запасний варіант, коли змінюється верстка сайту.
+type OLXAPIScraper struct {
+	client  *http.Client
+	baseURL string
+	rates   models.CurrencyRates
+}
+
+func NewOLXAPIScraper(opts Options) *OLXAPIScraper {
+	return &OLXAPIScraper{
+		client:  &http.Client{Timeout: opts.requestTimeout(), Transport: opts.transport()},
+		baseURL: opts.baseURL(),
+		rates:   opts.Rates,
+	}
+}
+
+type apiOffersResponse struct {
+	Data  []apiOffer `json:"data"`
+	Links struct {
+		Next *struct {
+			Href string `json:"href"`
+		} `json:"next"`
+	} `json:"links"`
+}
+
+type apiOffer struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	CreatedTime time.Time `json:"created_time"`
+	Promotion   struct {
+		TopAd bool `json:"top_ad"`
+	} `json:"promotion"`
+	Params []struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	} `json:"params"`
+	Location struct {
+		City *struct {
+			Name string `json:"name"`
+		} `json:"city"`
+		District *struct {
+			Name string `json:"name"`
+		} `json:"district"`
+		Region *struct {
+			Name string `json:"name"`
+		} `json:"region"`
+	} `json:"location"`
+	Photos []struct {
+		Link string `json:"link"`
+	} `json:"photos"`
+	User struct {
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+type apiPriceValue struct {
+	Value      float64 `json:"value"`
+	Type       string  `json:"type"`
+	Currency   string  `json:"currency"`
+	Negotiable bool    `json:"negotiable"`
+	Label      string  `json:"label"`
+}
+
+func (s *OLXAPIScraper) SearchListings(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error) {
+	maxPages := filters.MaxPages
+	if maxPages < 1 {
+		maxPages = 1
+	}
+
+	urlMap := make(map[string]bool)
+	var listings []models.Listing
+
+	var partial error
+	for page := 0; page < maxPages; page++ {
+		response, err := s.fetchOffers(ctx, filters, page*apiPageSize)
+		if err != nil {
+			if page == 0 {
+				return nil, err
+			}
+			log.Printf("Failed to fetch API page %d for query '%s': %v", page+1, filters.Query, err)
+			partial = &PartialError{Page: page + 1, Err: err}
+			break
+		}
+
+		reachedKnown := false
+		for _, offer := range response.Data {
+			if offer.URL == "" || urlMap[offer.URL] {
+				continue
+			}
+			urlMap[offer.URL] = true
+
+			listing := offer.toListing()
+
+			if filters.KnownURLs[listing.URL] && !listing.Promoted {
+				reachedKnown = true
+			}
+
+			if !matchesFilters(listing, filters, s.rates) {
+				continue
+			}
+			listings = append(listings, listing)
+		}
+
+		if reachedKnown || len(response.Data) == 0 || response.Links.Next == nil {
+			break
+		}
+	}
+
+	return listings, partial
+}
+
+func (s *OLXAPIScraper) fetchOffers(ctx context.Context, filters models.SearchFilters, offset int) (*apiOffersResponse, error) {
+	order := filters.SortOrder
+	if order == "" {
+		order = SortNewest
+	}
+
+	params := url.Values{}
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(apiPageSize))
+	params.Set("query", strings.Join(strings.Fields(filters.Query), " "))
+	params.Set("sort_by", order)
+	if filters.MinPrice > 0 {
+		params.Set("filter_float_price:from", strconv.Itoa(filters.MinPrice))
+	}
+	if filters.MaxPrice > 0 {
+		params.Set("filter_float_price:to", strconv.Itoa(filters.MaxPrice))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/api/v1/offers/?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Language", "uk")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, classifyResponse(0, req.URL.String(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, classifyResponse(resp.StatusCode, req.URL.String(),
+			fmt.Errorf("unexpected status from OLX API: %s", resp.Status))
+	}
+
+	var response apiOffersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode OLX API response: %w", err)
+	}
+	return &response, nil
+}
+
+func (o apiOffer) toListing() models.Listing {
+	listing := models.Listing{
+		URL:         o.URL,
+		Title:       strings.TrimSpace(o.Title),
+		AdID:        strconv.FormatInt(o.ID, 10),
+		Description: strings.TrimSpace(o.Description),
+		SellerName:  o.User.Name,
+		Promoted:    o.Promotion.TopAd,
+	}
+
+	if !o.CreatedTime.IsZero() {
+		listing.PostedAt = o.CreatedTime.In(utils.Kyiv)
+	}
+
+	if o.Location.City != nil {
+		listing.City = o.Location.City.Name
+	}
+	if o.Location.District != nil {
+		listing.District = o.Location.District.Name
+	}
+	if o.Location.Region != nil {
+		listing.Region = o.Location.Region.Name
+	}
+	listing.Location = listing.City
+	if listing.District != "" {
+		listing.Location += ", " + listing.District
+	}
+
+	for _, photo := range o.Photos {
+		// Посилання містять шаблон розміру, напр. ".../image;s={width}x{height}"
+		link := strings.ReplaceAll(photo.Link, "{width}", "1000")
+		link = strings.ReplaceAll(link, "{height}", "700")
+		listing.Photos = append(listing.Photos, link)
+	}
+
+	for _, param := range o.Params {
+		if param.Key != "price" {
+			continue
+		}
+		var value apiPriceValue
+		if err := json.Unmarshal(param.Value, &value); err != nil {
+			continue
+		}
+		listing.Price = value.Label
+		listing.PriceInfo = models.Price{
+			Amount:     value.Value,
+			Currency:   strings.ToUpper(value.Currency),
+			Negotiable: value.Negotiable || value.Type == "arranged",
+			Free:       value.Type == "free",
+			Exchange:   value.Type == "exchange",
+		}
+		if listing.PriceInfo.Currency == "" {
+			listing.PriceInfo.Currency = models.CurrencyUAH
+		}
+	}
+
+	return listing
+}
@@ -0,0 +1,170 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen повертається, поки скрапінг призупинено через блокування
+var ErrCircuitOpen = errors.New("scraping paused: circuit breaker is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker - глобальний запобіжник: після кількох ознак бану поспіль
+// (429, 403, капча) зупиняє всі запити до OLX на час охолодження, а потім
+// пропускає один пробний запит.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	alert     func(text string)
+
+	mutex     sync.Mutex
+	state     breakerState
+	failures  int
+	openUntil time.Time
+	probing   bool
+	lastBan   string
+	trips     int64
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 3
+	}
+	if cooldown <= 0 {
+		cooldown = 15 * time.Minute
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// SetAlertHandler задає функцію для сповіщення адміністраторів
+func (b *CircuitBreaker) SetAlertHandler(alert func(text string)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.alert = alert
+}
+
+// Allow перевіряє, чи можна зараз робити запит до OLX
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Now().Before(b.openUntil) {
+			return ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		log.Println("Circuit breaker half-open, sending a probe request")
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record враховує результат запиту, дозволеного через Allow
+func (b *CircuitBreaker) Record(err error) {
+	if b == nil || errors.Is(err, ErrCircuitOpen) {
+		return
+	}
+
+	kind := KindOf(err)
+
+	b.mutex.Lock()
+	var alert func(text string)
+	var text string
+
+	switch {
+	case err == nil:
+		if b.state == breakerHalfOpen {
+			log.Println("✅ Circuit breaker closed, OLX responds again")
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		b.probing = false
+	case kind.IsBan():
+		b.failures++
+		b.lastBan = err.Error()
+		if b.state == breakerHalfOpen || b.failures >= b.threshold {
+			b.state = breakerOpen
+			b.openUntil = time.Now().Add(b.cooldown)
+			b.failures = 0
+			b.probing = false
+			b.trips++
+			alert = b.alert
+			text = fmt.Sprintf("🛑 Скрапінг OLX призупинено до %s\n❗ %s",
+				b.openUntil.Format("15:04"), b.lastBan)
+			log.Printf("🛑 Circuit breaker open for %v: %s", b.cooldown, b.lastBan)
+		}
+	default:
+		// Звичайна помилка не говорить про бан, але пробний запит треба повторити
+		b.probing = false
+	}
+	b.mutex.Unlock()
+
+	if alert != nil {
+		alert(text)
+	}
+}
+
+// OpenUntil повертає час, до якого скрапінг призупинено
+func (b *CircuitBreaker) OpenUntil() (time.Time, bool) {
+	if b == nil {
+		return time.Time{}, false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == breakerOpen && time.Now().Before(b.openUntil) {
+		return b.openUntil, true
+	}
+	return time.Time{}, false
+}
+
+// StatusReport - стан запобіжника для команди /status
+func (b *CircuitBreaker) StatusReport() string {
+	if b == nil {
+		return "🛡 Запобіжник: ⚪ вимкнено\n"
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var status string
+	switch {
+	case b.state == breakerOpen && time.Now().Before(b.openUntil):
+		status = fmt.Sprintf("🔴 пауза до %s", b.openUntil.Format("15:04"))
+	case b.state != breakerClosed:
+		status = "🟡 пробний запит"
+	default:
+		status = fmt.Sprintf("🟢 працює (ознак бану поспіль: %d/%d)", b.failures, b.threshold)
+	}
+
+	text := fmt.Sprintf("🛡 Запобіжник: %s\n   спрацювань: %d\n", status, b.trips)
+	if b.lastBan != "" {
+		text += fmt.Sprintf("   остання ознака бану: %s\n", b.lastBan)
+	}
+	return text
+}
@@ -0,0 +1,261 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"olx-hunter/internal/database"
+)
+
+const (
+	// changesBatchSize - скільки змін фільтрів читається за один запит
+	changesBatchSize = 100
+	// changesRetention - скільки зберігаються прочитані зміни
+	changesRetention = 24 * time.Hour
+	// listenRetryDelay - пауза перед повторним підключенням LISTEN
+	listenRetryDelay = 5 * time.Second
+)
+
+// FollowerOptions - налаштування стеження за змінами фільтрів
+type FollowerOptions struct {
+	DSN               string        // для окремого з'єднання LISTEN, порожній - лише опитування
+	PollInterval      time.Duration // як часто читати журнал, якщо сповіщень немає
+	ReconcileInterval time.Duration // як часто звіряти всі фільтри з базою
+}
+
+// FollowerStore - журнал змін і фільтри в базі. Його реалізує *database.DB.
+type FollowerStore interface {
+	GetFilterChanges(after database.FilterChangePosition, limit int) ([]database.FilterChange, error)
+	FilterChangesHorizon() (database.FilterChangePosition, error)
+	PurgeFilterChanges(before time.Time) (int64, error)
+	GetActiveFilters() ([]*database.UserFilter, error)
+	GetActiveFiltersByIDs(filterIDs []uint) ([]*database.UserFilter, error)
+}
+
+// FilterChangeFollower доносить до сервісу зміни фільтрів, зроблені будь-де:
+// у боті, командами з Kafka, іншим процесом чи вручну в базі. Тригер пише
+// кожну зміну в журнал filter_changes і надсилає NOTIFY, після якого журнал
+// одразу дочитується. Журнал читається і за таймером, на випадок втрати
+// з'єднання, а повна звірка з базою виправляє все, що пропущено.
+type FilterChangeFollower struct {
+	db      FollowerStore
+	service *ScraperService
+	opts    FollowerOptions
+	wake    chan struct{}
+
+	position  database.FilterChangePosition
+	lastPurge time.Time
+
+	mutex         sync.Mutex
+	listening     bool
+	applied       int
+	lastReconcile time.Time
+}
+
+func NewFilterChangeFollower(db FollowerStore, service *ScraperService, opts FollowerOptions) *FilterChangeFollower {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 30 * time.Second
+	}
+	if opts.ReconcileInterval <= 0 {
+		opts.ReconcileInterval = 5 * time.Minute
+	}
+	return &FilterChangeFollower{
+		db:      db,
+		service: service,
+		opts:    opts,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Seek пропускає зміни завершених транзакцій. Його треба викликати до
+// завантаження фільтрів, щоб не пропустити зміни, зроблені під час завантаження.
+func (f *FilterChangeFollower) Seek() error {
+	position, err := f.db.FilterChangesHorizon()
+	if err != nil {
+		return err
+	}
+	f.position = position
+	return nil
+}
+
+// Run застосовує зміни, поки не скасовано контекст
+func (f *FilterChangeFollower) Run(ctx context.Context) {
+	log.Printf("Following filter changes (poll every %v, reconcile every %v)",
+		f.opts.PollInterval, f.opts.ReconcileInterval)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	if f.opts.DSN != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.listen(ctx)
+		}()
+	}
+
+	poll := time.NewTicker(f.opts.PollInterval)
+	defer poll.Stop()
+	reconcile := time.NewTicker(f.opts.ReconcileInterval)
+	defer reconcile.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reconcile.C:
+			if err := f.reconcile(); err != nil {
+				log.Printf("Failed to reconcile filters: %v", err)
+			}
+			continue
+		case <-f.wake:
+		case <-poll.C:
+		}
+
+		if err := f.poll(); err != nil {
+			log.Printf("Failed to read filter changes: %v", err)
+		}
+		f.purge()
+	}
+}
+
+// listen тримає підписку на NOTIFY і перепідключається після втрати з'єднання
+func (f *FilterChangeFollower) listen(ctx context.Context) {
+	for {
+		err := database.ListenFilterChanges(ctx, f.opts.DSN, f.notify)
+		f.setListening(false)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Filter change notifications lost, reconnecting in %v: %v", listenRetryDelay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+// notify будить Run, щоб той дочитав журнал
+func (f *FilterChangeFollower) notify() {
+	f.setListening(true)
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (f *FilterChangeFollower) setListening(listening bool) {
+	f.mutex.Lock()
+	f.listening = listening
+	f.mutex.Unlock()
+}
+
+// poll читає зміни пачками, поки не дочитає журнал
+func (f *FilterChangeFollower) poll() error {
+	for {
+		changes, err := f.db.GetFilterChanges(f.position, changesBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := f.apply(changes); err != nil {
+			return err
+		}
+		f.position = changes[len(changes)-1].Position()
+
+		f.mutex.Lock()
+		f.applied += len(changes)
+		f.mutex.Unlock()
+
+		if len(changes) < changesBatchSize {
+			return nil
+		}
+	}
+}
+
+// apply перечитує змінені фільтри: активні додаються з новими
+// налаштуваннями, вимкнені й видалені прибираються
+func (f *FilterChangeFollower) apply(changes []database.FilterChange) error {
+	seen := make(map[uint]bool, len(changes))
+	var filterIDs []uint
+	for _, change := range changes {
+		if !seen[change.FilterID] {
+			seen[change.FilterID] = true
+			filterIDs = append(filterIDs, change.FilterID)
+		}
+	}
+
+	filters, err := f.db.GetActiveFiltersByIDs(filterIDs)
+	if err != nil {
+		return err
+	}
+	active := make(map[uint]*database.UserFilter, len(filters))
+	for _, filter := range filters {
+		active[filter.ID] = filter
+	}
+
+	for _, filterID := range filterIDs {
+		if filter, ok := active[filterID]; ok {
+			f.service.AddFilter(filter)
+		} else if f.service.owns(filterID) {
+			f.service.RemoveFilter(filterID)
+		}
+	}
+	return nil
+}
+
+// reconcile звіряє фільтри сервісу з базою і замінює закешовані фільтри
+// свіжими, на випадок якщо зміну з журналу було втрачено. З орендами
+// фільтри перечитує координатор, щоб не взяти чужі.
+func (f *FilterChangeFollower) reconcile() error {
+	if f.service.leases != nil {
+		if err := f.service.leases.sync(); err != nil {
+			return err
+		}
+	} else {
+		filters, err := f.db.GetActiveFilters()
+		if err != nil {
+			return err
+		}
+		f.service.replaceFilters(filters)
+	}
+
+	f.mutex.Lock()
+	f.lastReconcile = time.Now()
+	f.mutex.Unlock()
+	return nil
+}
+
+// purge раз на годину прибирає старі зміни
+func (f *FilterChangeFollower) purge() {
+	if time.Since(f.lastPurge) < time.Hour {
+		return
+	}
+	f.lastPurge = time.Now()
+
+	if _, err := f.db.PurgeFilterChanges(time.Now().Add(-changesRetention)); err != nil {
+		log.Printf("Failed to purge filter changes: %v", err)
+	}
+}
+
+// StatusReport - стан стеження за змінами для команди /status
+func (f *FilterChangeFollower) StatusReport() string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	state := "🟢 LISTEN"
+	if !f.listening {
+		state = "🟡 лише опитування"
+	}
+	text := fmt.Sprintf("📝 Зміни фільтрів: %s, застосовано %d\n", state, f.applied)
+	if !f.lastReconcile.IsZero() {
+		text += fmt.Sprintf("   звірка: %s\n", f.lastReconcile.Format("15:04:05"))
+	}
+	return text
+}
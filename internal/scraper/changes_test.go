@@ -0,0 +1,328 @@
+package scraper
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"olx-hunter/internal/database"
+)
+
+// memChanges - фільтри і журнал змін у пам'яті
+type memChanges struct {
+	mutex   sync.Mutex
+	filters map[uint]*database.UserFilter
+	changes []database.FilterChange
+}
+
+func newMemChanges(filters ...*database.UserFilter) *memChanges {
+	m := &memChanges{filters: make(map[uint]*database.UserFilter)}
+	for _, filter := range filters {
+		m.filters[filter.ID] = filter
+	}
+	return m
+}
+
+// change зберігає нову версію фільтра і записує зміну, як тригер
+func (m *memChanges) change(txID uint64, filter *database.UserFilter) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.filters[filter.ID] = filter
+	m.changes = append(m.changes, database.FilterChange{ID: uint64(len(m.changes) + 1), TxID: txID, FilterID: filter.ID})
+}
+
+func (m *memChanges) GetFilterChanges(after database.FilterChangePosition, limit int) ([]database.FilterChange, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var changes []database.FilterChange
+	for _, change := range m.changes {
+		if change.TxID > after.TxID || (change.TxID == after.TxID && change.ID > after.ID) {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].TxID != changes[j].TxID {
+			return changes[i].TxID < changes[j].TxID
+		}
+		return changes[i].ID < changes[j].ID
+	})
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	return changes, nil
+}
+
+func (m *memChanges) FilterChangesHorizon() (database.FilterChangePosition, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var horizon uint64
+	for _, change := range m.changes {
+		if change.TxID >= horizon {
+			horizon = change.TxID + 1
+		}
+	}
+	return database.FilterChangePosition{TxID: horizon}, nil
+}
+
+func (m *memChanges) PurgeFilterChanges(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *memChanges) GetActiveFilters() ([]*database.UserFilter, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var filters []*database.UserFilter
+	for _, filter := range m.filters {
+		if filter.IsActive {
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+func (m *memChanges) GetActiveFiltersByIDs(filterIDs []uint) ([]*database.UserFilter, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var filters []*database.UserFilter
+	for _, id := range filterIDs {
+		if filter, ok := m.filters[id]; ok && filter.IsActive {
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+// scrapedQuery - запит, з яким сервіс зараз скрапить фільтр
+func scrapedQuery(service *ScraperService, filterID uint) (string, bool) {
+	service.filtersMutex.RLock()
+	defer service.filtersMutex.RUnlock()
+	filter, ok := service.activeFilters[filterID]
+	if !ok {
+		return "", false
+	}
+	return filter.Query, true
+}
+
+func TestFollowerAppliesChanges(t *testing.T) {
+	store := newMemChanges(
+		&database.UserFilter{ID: 1, Query: "iphone 15", IsActive: true},
+		&database.UserFilter{ID: 2, Query: "macbook", IsActive: true},
+	)
+	service := newTestService(newMemStore(), nil, ServiceOptions{})
+	follower := NewFilterChangeFollower(store, service, FollowerOptions{})
+	if err := follower.Seek(); err != nil {
+		t.Fatal(err)
+	}
+	filters, _ := store.GetActiveFilters()
+	service.replaceFilters(filters)
+
+	// Фільтр 1 змінили двічі, 2 вимкнули, 3 створили
+	store.change(10, &database.UserFilter{ID: 1, Query: "iphone 15 pro", IsActive: true})
+	store.change(10, &database.UserFilter{ID: 2, Query: "macbook", IsActive: false})
+	store.change(11, &database.UserFilter{ID: 3, Query: "ipad", IsActive: true})
+	store.change(12, &database.UserFilter{ID: 1, Query: "iphone 15 pro max", IsActive: true})
+
+	if err := follower.poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	if query, ok := scrapedQuery(service, 1); !ok || query != "iphone 15 pro max" {
+		t.Errorf("Changed filter should be scraped with the latest settings, got %q", query)
+	}
+	if service.owns(2) {
+		t.Error("Disabled filter should be removed")
+	}
+	if query, ok := scrapedQuery(service, 3); !ok || query != "ipad" {
+		t.Errorf("New filter should be added, got %q", query)
+	}
+	if follower.applied != 4 {
+		t.Errorf("Expected 4 applied changes, got %d", follower.applied)
+	}
+
+	// Прочитані зміни не застосовуються вдруге
+	service.RemoveFilter(3)
+	if err := follower.poll(); err != nil {
+		t.Fatal(err)
+	}
+	if service.owns(3) || follower.applied != 4 {
+		t.Error("Changes behind the position should not be applied again")
+	}
+
+	// Зміна транзакції, що почалась раніше, але закомічена пізніше
+	store.change(9, &database.UserFilter{ID: 4, Query: "airpods", IsActive: true})
+	follower.position = database.FilterChangePosition{TxID: 8}
+	if err := follower.poll(); err != nil {
+		t.Fatal(err)
+	}
+	if !service.owns(4) {
+		t.Error("A change after the position should be applied")
+	}
+}
+
+// dueTime - час, на який фільтр стоїть у черзі
+func dueTime(service *ScraperService, filterID uint) (time.Time, bool) {
+	service.scheduler.mutex.Lock()
+	defer service.scheduler.mutex.Unlock()
+	item, ok := service.scheduler.items[filterID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return item.due, true
+}
+
+func TestFollowerKeepsScheduleForOptionChanges(t *testing.T) {
+	store := newMemChanges(&database.UserFilter{ID: 1, Name: "iPhone", Query: "iphone 15", IsActive: true})
+	service := newTestService(newMemStore(), nil, ServiceOptions{})
+	follower := NewFilterChangeFollower(store, service, FollowerOptions{})
+	if err := follower.Seek(); err != nil {
+		t.Fatal(err)
+	}
+	filters, _ := store.GetActiveFilters()
+	service.replaceFilters(filters)
+	scheduled, _ := dueTime(service, 1)
+
+	// Назва і сповіщення не змінюють запиту
+	store.change(10, &database.UserFilter{ID: 1, Name: "Айфон", Query: "iphone 15", IsActive: true, PriceAlerts: true, SkipPromoted: true, RepostMode: "skip"})
+	if err := follower.poll(); err != nil {
+		t.Fatal(err)
+	}
+	if due, ok := dueTime(service, 1); !ok || !due.Equal(scheduled) {
+		t.Errorf("An option change should keep the due time %v, got %v", scheduled, due)
+	}
+	if filter := service.current(&database.UserFilter{ID: 1}); !filter.PriceAlerts || filter.Name != "Айфон" {
+		t.Error("The cached filter should be replaced")
+	}
+
+	// Новий запит скрапиться одразу
+	store.change(11, &database.UserFilter{ID: 1, Name: "Айфон", Query: "iphone 15 pro", IsActive: true, PriceAlerts: true})
+	if err := follower.poll(); err != nil {
+		t.Fatal(err)
+	}
+	if due, _ := dueTime(service, 1); due.After(time.Now()) {
+		t.Errorf("A query change should be scraped now, due %v", due)
+	}
+
+	// Відновлений фільтр теж
+	store.change(12, &database.UserFilter{ID: 1, Query: "iphone 15 pro", IsActive: false})
+	if err := follower.poll(); err != nil {
+		t.Fatal(err)
+	}
+	store.change(13, &database.UserFilter{ID: 1, Query: "iphone 15 pro", IsActive: true})
+	if err := follower.poll(); err != nil {
+		t.Fatal(err)
+	}
+	if due, ok := dueTime(service, 1); !ok || due.After(time.Now()) {
+		t.Errorf("A resumed filter should be scraped now, due %v", due)
+	}
+}
+
+func TestFollowerSeekSkipsFinishedChanges(t *testing.T) {
+	store := newMemChanges(&database.UserFilter{ID: 1, Query: "iphone 15", IsActive: true})
+	store.change(5, &database.UserFilter{ID: 1, Query: "iphone 15", IsActive: true})
+
+	service := newTestService(newMemStore(), nil, ServiceOptions{})
+	follower := NewFilterChangeFollower(store, service, FollowerOptions{})
+	if err := follower.Seek(); err != nil {
+		t.Fatal(err)
+	}
+	if err := follower.poll(); err != nil {
+		t.Fatal(err)
+	}
+	// Фільтри вже завантажені після Seek, тож старі зміни не потрібні
+	if service.owns(1) || follower.applied != 0 {
+		t.Error("Changes before Seek should be skipped")
+	}
+}
+
+func TestReconcileReplacesCachedFilters(t *testing.T) {
+	store := newMemChanges(
+		&database.UserFilter{ID: 1, Query: "iphone 15", IsActive: true},
+		&database.UserFilter{ID: 2, Query: "macbook", IsActive: true},
+	)
+	service := newTestService(newMemStore(), nil, ServiceOptions{})
+	follower := NewFilterChangeFollower(store, service, FollowerOptions{})
+	if err := follower.reconcile(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Зміни зроблено в обхід журналу
+	store.filters[1] = &database.UserFilter{ID: 1, Query: "iphone 15 pro", IsActive: true}
+	store.filters[2] = &database.UserFilter{ID: 2, Query: "macbook", IsActive: false}
+	store.filters[3] = &database.UserFilter{ID: 3, Query: "ipad", IsActive: true}
+
+	if err := follower.reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	if query, _ := scrapedQuery(service, 1); query != "iphone 15 pro" {
+		t.Errorf("Cached filter should be replaced with the stored one, got %q", query)
+	}
+	if service.owns(2) {
+		t.Error("Disabled filter should be removed")
+	}
+	if !service.owns(3) {
+		t.Error("Missed filter should be added")
+	}
+	if follower.lastReconcile.IsZero() {
+		t.Error("Reconcile time should be recorded")
+	}
+}
+
+func TestReconcileWithLeasesRefreshesOwnedFilters(t *testing.T) {
+	leases := newMemLeases(1, 2, 3)
+	instance := newLeaseInstance(t, leases, "a", nil)
+	follower := NewFilterChangeFollower(newMemChanges(), instance.service, FollowerOptions{})
+
+	// Фільтр 3 належить іншому екземпляру
+	if _, err := leases.ClaimLease("b", 3, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	instance.sync(t)
+
+	leases.mutex.Lock()
+	leases.filters[1] = &database.UserFilter{ID: 1, Query: "iphone 15 pro", IsActive: true}
+	leases.filters[2] = &database.UserFilter{ID: 2, Query: "iphone 15", IsActive: false}
+	leases.mutex.Unlock()
+
+	if err := follower.reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	if query, _ := scrapedQuery(instance.service, 1); query != "iphone 15 pro" {
+		t.Errorf("Owned filter should be refreshed, got %q", query)
+	}
+	if instance.service.owns(2) {
+		t.Error("Disabled filter should be removed")
+	}
+	if instance.service.owns(3) {
+		t.Error("A filter leased to another instance should not be taken")
+	}
+	if leases.owner(2) != "" {
+		t.Error("Disabled filter should be released")
+	}
+}
+
+func TestApplyWithLeasesTakesOnlyFreeFilters(t *testing.T) {
+	leases := newMemLeases(1, 2)
+	instance := newLeaseInstance(t, leases, "a", nil)
+	if _, err := leases.ClaimLease("b", 2, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	instance.sync(t)
+
+	leases.mutex.Lock()
+	leases.filters[3] = &database.UserFilter{ID: 3, Query: "ipad", IsActive: true}
+	store := newMemChanges(leases.filters[2], leases.filters[3])
+	leases.mutex.Unlock()
+	follower := NewFilterChangeFollower(store, instance.service, FollowerOptions{})
+
+	if err := follower.apply([]database.FilterChange{{FilterID: 2}, {FilterID: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if instance.service.owns(2) {
+		t.Error("A changed filter leased to another instance should stay there")
+	}
+	if !instance.service.owns(3) || leases.owner(3) != "a" {
+		t.Error("A new free filter should be claimed and scraped")
+	}
+}
@@ -0,0 +1,131 @@
+package scraper
+
+import (
+	"sort"
+	"strings"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/models"
+)
+
+// searchGroup - активні фільтри, що відрізняються лише локальними умовами
+// (ціна, договірна, свіжість, ТОП) і тому обслуговуються одним запитом до OLX
+type searchGroup struct {
+	Key     string
+	Backend string
+	Filters []*database.UserFilter
+
+	// reserved - токен на перший запит групи вже взято з бюджету
+	reserved bool
+}
+
+// normalizeQuery зводить "iPhone-15", "iphone  15" та "iphone 15" до одного
+// запиту, бо в URL вони все одно стають "q-iphone-15"
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.ReplaceAll(query, "-", " ")), " "))
+}
+
+// groupKey - ключ запиту фільтра: те, що потрапляє в шлях URL OLX і визначає
+// порядок видачі
+func groupKey(filter *database.UserFilter, backend string) string {
+	order := filter.SortOrder
+	if order == "" {
+		order = SortNewest
+	}
+	location := citySlug(filter.City)
+	if location == "" {
+		location = normalizeQuery(filter.City)
+	}
+	return strings.Join([]string{backend, normalizeQuery(filter.Query), location, order}, "|")
+}
+
+// groupFilters об'єднує фільтри з однаковим запитом. resolveBackend повертає
+// фактичну назву бекенда фільтра, щоб "" і явний бекенд за замовчуванням
+// потрапили в одну групу.
+func groupFilters(filters []*database.UserFilter, resolveBackend func(name string) string) []*searchGroup {
+	groups := make(map[string]*searchGroup)
+	var keys []string
+
+	for _, filter := range filters {
+		backend := resolveBackend(filter.Backend)
+		key := groupKey(filter, backend)
+		group, exists := groups[key]
+		if !exists {
+			group = &searchGroup{Key: key, Backend: backend}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.Filters = append(group.Filters, filter)
+	}
+
+	sort.Strings(keys)
+	result := make([]*searchGroup, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, groups[key])
+	}
+	return result
+}
+
+// filterSearch - умови пошуку одного фільтра
+func filterSearch(filter *database.UserFilter) models.SearchFilters {
+	return models.SearchFilters{
+		Query:     filter.Query,
+		MinPrice:  filter.MinPrice,
+		MaxPrice:  filter.MaxPrice,
+		City:      filter.City,
+		SortOrder: filter.SortOrder,
+		MaxPages:  filter.MaxPages,
+
+		IncludeNegotiable: filter.IncludeNegotiable,
+		MaxAgeHours:       filter.MaxAgeHours,
+		SkipPromoted:      filter.SkipPromoted,
+	}
+}
+
+// mergeSearch будує спільний запит групи: найширші з умов фільтрів, щоб
+// жоден фільтр не втратив своїх оголошень. pages - глибина для кожного
+// фільтра, known - вже збережені кожним фільтром URL. Пагінація
+// зупиняється лише на оголошенні, відомому всім фільтрам групи.
+func mergeSearch(filters []models.SearchFilters, known []map[string]bool) models.SearchFilters {
+	merged := filters[0]
+	merged.KnownURLs = nil
+
+	for _, f := range filters[1:] {
+		if f.MinPrice == 0 || f.MinPrice < merged.MinPrice {
+			merged.MinPrice = f.MinPrice
+		}
+		if f.MaxPrice == 0 || (merged.MaxPrice != 0 && f.MaxPrice > merged.MaxPrice) {
+			merged.MaxPrice = f.MaxPrice
+		}
+		if f.MaxAgeHours == 0 || (merged.MaxAgeHours != 0 && f.MaxAgeHours > merged.MaxAgeHours) {
+			merged.MaxAgeHours = f.MaxAgeHours
+		}
+		if f.MaxPages > merged.MaxPages {
+			merged.MaxPages = f.MaxPages
+		}
+		merged.IncludeNegotiable = merged.IncludeNegotiable || f.IncludeNegotiable
+		merged.SkipPromoted = merged.SkipPromoted && f.SkipPromoted
+	}
+
+	merged.KnownURLs = intersectKnown(known)
+	return merged
+}
+
+func intersectKnown(known []map[string]bool) map[string]bool {
+	if len(known) == 0 {
+		return nil
+	}
+
+	result := make(map[string]bool)
+	for url := range known[0] {
+		result[url] = true
+	}
+	for _, set := range known[1:] {
+		for url := range result {
+			if !set[url] {
+				delete(result, url)
+			}
+		}
+	}
+	return result
+}
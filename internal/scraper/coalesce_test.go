@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/models"
+)
+
+func resolveHTML(name string) string {
+	if name == "" {
+		return BackendHTML
+	}
+	return name
+}
+
+func TestGroupFiltersBySearchRequest(t *testing.T) {
+	filters := []*database.UserFilter{
+		{ID: 1, Query: "iPhone 15", MinPrice: 20000, MaxPrice: 30000},
+		{ID: 2, Query: "iphone-15", MaxPrice: 25000, Backend: BackendHTML},
+		{ID: 3, Query: "iphone  15", City: "Київ"},
+		{ID: 4, Query: "iphone 15", SortOrder: SortCheapest},
+		{ID: 5, Query: "iphone 15", Backend: BackendAPI},
+		{ID: 6, Query: "iphone 15", City: "kiev"},
+	}
+
+	groups := groupFilters(filters, resolveHTML)
+	if len(groups) != 4 {
+		t.Fatalf("Expected 4 groups, got %d", len(groups))
+	}
+
+	sizes := make(map[uint]int)
+	for _, group := range groups {
+		for _, filter := range group.Filters {
+			sizes[filter.ID] = len(group.Filters)
+		}
+	}
+	if sizes[1] != 2 || sizes[2] != 2 {
+		t.Errorf("Filters 1 and 2 should share a request, got %v", sizes)
+	}
+	if sizes[3] != 2 || sizes[6] != 2 {
+		t.Errorf("Київ and kiev should share a request, got %v", sizes)
+	}
+	if sizes[4] != 1 || sizes[5] != 1 {
+		t.Errorf("Different sort order or backend should not be grouped, got %v", sizes)
+	}
+}
+
+func TestMergeSearchUsesWidestConditions(t *testing.T) {
+	searches := []models.SearchFilters{
+		{Query: "iphone 15", MinPrice: 20000, MaxPrice: 30000, MaxPages: 1, SkipPromoted: true, MaxAgeHours: 24},
+		{Query: "iphone 15", MinPrice: 15000, MaxPrice: 25000, MaxPages: 3, SkipPromoted: true, IncludeNegotiable: true},
+	}
+	known := []map[string]bool{
+		{"a": true, "b": true},
+		{"b": true, "c": true},
+	}
+
+	merged := mergeSearch(searches, known)
+
+	if merged.MinPrice != 15000 || merged.MaxPrice != 30000 {
+		t.Errorf("Expected price range 15000-30000, got %d-%d", merged.MinPrice, merged.MaxPrice)
+	}
+	if merged.MaxPages != 3 {
+		t.Errorf("Expected the deepest pagination, got %d", merged.MaxPages)
+	}
+	if !merged.IncludeNegotiable || !merged.SkipPromoted || merged.MaxAgeHours != 0 {
+		t.Errorf("Unexpected merged flags: %+v", merged)
+	}
+	if len(merged.KnownURLs) != 1 || !merged.KnownURLs["b"] {
+		t.Errorf("Pagination should stop only at URLs known to every filter, got %v", merged.KnownURLs)
+	}
+
+	unbounded := mergeSearch([]models.SearchFilters{{MaxPrice: 30000}, {MinPrice: 100}}, nil)
+	if unbounded.MinPrice != 0 || unbounded.MaxPrice != 0 {
+		t.Errorf("Any unbounded side should stay unbounded, got %d-%d", unbounded.MinPrice, unbounded.MaxPrice)
+	}
+}
+
+func TestGroupResultsAreFilteredPerFilter(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	cheap := models.SearchFilters{Query: "iphone 15", MaxPrice: 26000}
+	wide := models.SearchFilters{Query: "iphone-15", IncludeNegotiable: true}
+
+	listings, err := s.SearchListings(context.Background(), mergeSearch([]models.SearchFilters{cheap, wide}, nil))
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+	if fs.requestCount() != 1 {
+		t.Errorf("Expected one request for the group, got %d", fs.requestCount())
+	}
+
+	count := func(search models.SearchFilters) int {
+		n := 0
+		for _, listing := range listings {
+			if matchesFilters(listing, search, s.rates) {
+				n++
+			}
+		}
+		return n
+	}
+
+	if count(wide) != len(listings) {
+		t.Errorf("Widest filter should match every listing, got %d of %d", count(wide), len(listings))
+	}
+	if count(cheap) == 0 || count(cheap) >= len(listings) {
+		t.Errorf("Price filter should keep a subset, got %d of %d", count(cheap), len(listings))
+	}
+}
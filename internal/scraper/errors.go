@@ -0,0 +1,197 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"olx-hunter/internal/cache"
+)
+
+// ErrorKind - тип помилки скрапінгу, від якого залежить, чи варто повторювати
+// запит і чи це ознака блокування з боку OLX
+type ErrorKind string
+
+const (
+	KindRateLimited ErrorKind = "rate_limited" // 429
+	KindForbidden   ErrorKind = "forbidden"    // 403
+	KindCaptcha     ErrorKind = "captcha"      // сторінка перевірки замість видачі
+	KindNoProxies   ErrorKind = "no_proxies"   // всі проксі вилучені
+	KindBudget      ErrorKind = "budget"       // вичерпано спільний ліміт запитів
+	KindTimeout     ErrorKind = "timeout"
+	KindNetwork     ErrorKind = "network"
+	KindServer      ErrorKind = "server" // 5xx
+	KindNotFound    ErrorKind = "not_found"
+	KindClosed      ErrorKind = "closed" // оголошення неактивне (продане або зняте)
+	KindOther       ErrorKind = "other"
+)
+
+// IsBan - OLX (або проксі) відмовляє нам в доступі
+func (k ErrorKind) IsBan() bool {
+	switch k {
+	case KindRateLimited, KindForbidden, KindCaptcha, KindNoProxies:
+		return true
+	}
+	return false
+}
+
+// IsTransient - помилку можна повторити через деякий час
+func (k ErrorKind) IsTransient() bool {
+	switch k {
+	case KindTimeout, KindNetwork, KindServer:
+		return true
+	}
+	return false
+}
+
+// IsClosed - оголошення більше не існує або зняте з публікації
+func (k ErrorKind) IsClosed() bool {
+	return k == KindNotFound || k == KindClosed
+}
+
+// ScrapeError - класифікована помилка запиту до OLX
+type ScrapeError struct {
+	Kind       ErrorKind
+	StatusCode int
+	URL        string
+	Err        error
+}
+
+func (e *ScrapeError) Error() string {
+	text := string(e.Kind)
+	if e.StatusCode != 0 {
+		text += fmt.Sprintf(" (HTTP %d)", e.StatusCode)
+	}
+	if e.URL != "" {
+		text += " " + e.URL
+	}
+	if e.Err != nil {
+		text += ": " + e.Err.Error()
+	}
+	return text
+}
+
+func (e *ScrapeError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError - помилка на одній з наступних сторінок видачі. Оголошення з
+// попередніх сторінок повертаються разом з нею, а запобіжник через Unwrap
+// бачить бан чи капчу так само, як на першій сторінці.
+type PartialError struct {
+	Page int
+	Err  error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("page %d: %v", e.Page, e.Err)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// IsPartial - видача неповна, але отримані оголошення можна використати
+func IsPartial(err error) bool {
+	var partial *PartialError
+	return errors.As(err, &partial)
+}
+
+// KindOf повертає тип помилки, класифікуючи некласифіковані за їх природою
+func KindOf(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+
+	var scrapeErr *ScrapeError
+	if errors.As(err, &scrapeErr) {
+		return scrapeErr.Kind
+	}
+	if errors.Is(err, ErrNoHealthyProxies) {
+		return KindNoProxies
+	}
+	if errors.Is(err, cache.ErrBudgetExhausted) {
+		return KindBudget
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return KindOther
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return KindTimeout
+		}
+		return KindNetwork
+	}
+	return KindOther
+}
+
+// classifyResponse перетворює відповідь з помилкою на ScrapeError
+func classifyResponse(statusCode int, pageURL string, err error) error {
+	kind := kindForStatus(statusCode)
+	if kind == "" {
+		if err == nil {
+			return nil
+		}
+		kind = KindOf(err)
+	}
+	return &ScrapeError{Kind: kind, StatusCode: statusCode, URL: pageURL, Err: err}
+}
+
+func kindForStatus(statusCode int) ErrorKind {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return KindRateLimited
+	case statusCode == http.StatusForbidden:
+		return KindForbidden
+	case statusCode == http.StatusNotFound || statusCode == http.StatusGone:
+		return KindNotFound
+	case statusCode >= 500:
+		return KindServer
+	case statusCode >= 400:
+		return KindOther
+	}
+	return ""
+}
+
+// captchaMarkers - фрагменти сторінок перевірки Cloudflare та reCAPTCHA
+var captchaMarkers = [][]byte{
+	[]byte("cf-chl-"),
+	[]byte("challenge-platform"),
+	[]byte("g-recaptcha"),
+	[]byte("<title>Just a moment"),
+}
+
+// inactiveMarkers - фрагменти сторінки неактивного оголошення
+var inactiveMarkers = [][]byte{
+	[]byte(`data-testid="ad-inactive-msg"`),
+	[]byte("Це оголошення більше не доступне"),
+	[]byte("Объявление больше не доступно"),
+}
+
+// isInactivePage перевіряє, чи OLX показав заглушку замість оголошення
+func isInactivePage(body []byte) bool {
+	for _, marker := range inactiveMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCaptchaPage перевіряє, чи віддали нам сторінку перевірки замість видачі
+func isCaptchaPage(body []byte) bool {
+	for _, marker := range captchaMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package scraper
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"time"
+
+	"olx-hunter/internal/models"
+)
+
+// RetryPolicy - повтори тимчасових помилок з експоненційною затримкою
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy використовується, якщо в Options не задано іншої
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   2 * time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// backoff повертає затримку перед повтором номер attempt (з 1) з повним
+// випадковим розкидом у [0, min(BaseDelay*2^(attempt-1), MaxDelay)),
+// щоб воркери не повторювали запити синхронно
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+// Do виконує fn, поки вона повертає тимчасову помилку і спроби не вичерпано.
+// Запобіжник перевіряється перед кожною спробою і отримує кожен результат.
+// Очікування між спробами переривається скасуванням контексту.
+func (p RetryPolicy) Do(ctx context.Context, breaker *CircuitBreaker, fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err = breaker.Allow(); err != nil {
+			return err
+		}
+
+		err = fn()
+		breaker.Record(err)
+
+		// Неповну видачу не повторюємо: отримані сторінки вже використані
+		if err == nil || !KindOf(err).IsTransient() || IsPartial(err) || attempt == attempts {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		log.Printf("Retrying after %s error in %v (attempt %d/%d): %v",
+			KindOf(err), delay.Round(time.Millisecond), attempt+1, attempts, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// guardedScraper обгортає бекенд повторами та глобальним запобіжником
+type guardedScraper struct {
+	backend Scraper
+	breaker *CircuitBreaker
+	retry   RetryPolicy
+}
+
+// guardedFetcher - те саме для бекендів, що вміють відкривати сторінку оголошення
+type guardedFetcher struct {
+	guardedScraper
+	fetcher DetailFetcher
+}
+
+// Guard додає до бекенду повтори та запобіжник, зберігаючи підтримку DetailFetcher
+func Guard(backend Scraper, breaker *CircuitBreaker, retry RetryPolicy) Scraper {
+	guarded := guardedScraper{backend: backend, breaker: breaker, retry: retry}
+	if fetcher, ok := backend.(DetailFetcher); ok {
+		return &guardedFetcher{guardedScraper: guarded, fetcher: fetcher}
+	}
+	return &guarded
+}
+
+func (g *guardedScraper) SearchListings(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error) {
+	var listings []models.Listing
+	err := g.retry.Do(ctx, g.breaker, func() error {
+		var err error
+		listings, err = g.backend.SearchListings(ctx, filters)
+		return err
+	})
+	return listings, err
+}
+
+func (g *guardedFetcher) FetchDetails(ctx context.Context, listing *models.Listing) error {
+	if err := g.breaker.Allow(); err != nil {
+		return err
+	}
+	err := g.fetcher.FetchDetails(ctx, listing)
+	g.breaker.Record(err)
+	return err
+}
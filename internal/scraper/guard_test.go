@@ -0,0 +1,232 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"olx-hunter/internal/models"
+)
+
+// stubScraper повертає заздалегідь задані помилки по черзі
+type stubScraper struct {
+	errs  []error
+	calls int
+}
+
+func (s *stubScraper) SearchListings(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error) {
+	s.calls++
+	if len(s.errs) == 0 {
+		return []models.Listing{{URL: "http://olx.test/d/uk/obyavlenie/a.html"}}, nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return nil, err
+}
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestClassifyResponse(t *testing.T) {
+	tests := []struct {
+		status   int
+		expected ErrorKind
+	}{
+		{http.StatusTooManyRequests, KindRateLimited},
+		{http.StatusForbidden, KindForbidden},
+		{http.StatusNotFound, KindNotFound},
+		{http.StatusBadGateway, KindServer},
+		{http.StatusBadRequest, KindOther},
+	}
+
+	for _, tt := range tests {
+		err := classifyResponse(tt.status, "http://olx.test/", errors.New(http.StatusText(tt.status)))
+		if KindOf(err) != tt.expected {
+			t.Errorf("Status %d: expected %s, got %s", tt.status, tt.expected, KindOf(err))
+		}
+	}
+
+	if KindOf(ErrNoHealthyProxies) != KindNoProxies || !KindNoProxies.IsBan() {
+		t.Error("Running out of proxies should be a ban signal")
+	}
+}
+
+func TestBackoffStaysWithinMaxDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Second}
+	for attempt := 1; attempt <= 5; attempt++ {
+		limit := policy.BaseDelay << (attempt - 1)
+		if limit > policy.MaxDelay {
+			limit = policy.MaxDelay
+		}
+		for i := 0; i < 100; i++ {
+			if delay := policy.backoff(attempt); delay < 0 || delay >= limit {
+				t.Fatalf("Attempt %d: delay %v outside [0, %v)", attempt, delay, limit)
+			}
+		}
+	}
+	if delay := (RetryPolicy{}).backoff(1); delay != 0 {
+		t.Errorf("A zero policy should not wait, got %v", delay)
+	}
+}
+
+func TestSearchListingsDetectsBans(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("search[filter_float_price:from]") == "1" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`<html><head><title>Just a moment...</title></head><body><div id="cf-chl-widget"></div></body></html>`))
+	}))
+	t.Cleanup(server.Close)
+
+	s := NewOLXScraper(Options{BaseURL: server.URL})
+
+	_, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone", MinPrice: 1})
+	if KindOf(err) != KindRateLimited {
+		t.Errorf("Expected rate_limited, got %v", err)
+	}
+
+	_, err = s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone"})
+	if KindOf(err) != KindCaptcha {
+		t.Errorf("Expected captcha, got %v", err)
+	}
+}
+
+func TestGuardRetriesTransientErrors(t *testing.T) {
+	stub := &stubScraper{errs: []error{
+		&ScrapeError{Kind: KindTimeout},
+		&ScrapeError{Kind: KindServer, StatusCode: http.StatusBadGateway},
+	}}
+
+	listings, err := Guard(stub, nil, fastRetry).SearchListings(context.Background(), models.SearchFilters{})
+	if err != nil {
+		t.Fatal("Expected success after retries:", err)
+	}
+	if stub.calls != 3 || len(listings) != 1 {
+		t.Errorf("Expected 3 calls and 1 listing, got %d calls and %d listings", stub.calls, len(listings))
+	}
+
+	stub = &stubScraper{errs: []error{&ScrapeError{Kind: KindNotFound}}}
+	if _, err := Guard(stub, nil, fastRetry).SearchListings(context.Background(), models.SearchFilters{}); KindOf(err) != KindNotFound {
+		t.Errorf("Expected not_found, got %v", err)
+	}
+	if stub.calls != 1 {
+		t.Errorf("Permanent errors should not be retried, got %d calls", stub.calls)
+	}
+}
+
+func TestCircuitBreakerPausesScraping(t *testing.T) {
+	breaker := NewCircuitBreaker(2, 20*time.Millisecond)
+
+	alerts := 0
+	breaker.SetAlertHandler(func(text string) {
+		alerts++
+	})
+
+	ban := &ScrapeError{Kind: KindForbidden, StatusCode: http.StatusForbidden}
+	stub := &stubScraper{errs: []error{ban, ban}}
+	backend := Guard(stub, breaker, fastRetry)
+
+	backend.SearchListings(context.Background(), models.SearchFilters{})
+	backend.SearchListings(context.Background(), models.SearchFilters{})
+	if _, open := breaker.OpenUntil(); !open {
+		t.Fatal("Breaker should open after 2 ban signals")
+	}
+	if alerts != 1 {
+		t.Errorf("Expected 1 admin alert, got %d", alerts)
+	}
+
+	if _, err := backend.SearchListings(context.Background(), models.SearchFilters{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen while open, got %v", err)
+	}
+	if stub.calls != 2 {
+		t.Errorf("No requests should be made while open, got %d calls", stub.calls)
+	}
+
+	// Після охолодження пробний запит вдається і запобіжник закривається
+	time.Sleep(30 * time.Millisecond)
+	if _, err := backend.SearchListings(context.Background(), models.SearchFilters{}); err != nil {
+		t.Fatal("Probe request should pass:", err)
+	}
+	if err := breaker.Allow(); err != nil {
+		t.Errorf("Breaker should be closed after a successful probe, got %v", err)
+	}
+}
+
+func TestDisabledCircuitBreaker(t *testing.T) {
+	var breaker *CircuitBreaker
+
+	if err := breaker.Allow(); err != nil {
+		t.Errorf("A disabled breaker should allow requests, got %v", err)
+	}
+	if _, open := breaker.OpenUntil(); open {
+		t.Error("A disabled breaker should never open")
+	}
+	if report := breaker.StatusReport(); !strings.Contains(report, "вимкнено") {
+		t.Errorf("/status should say the breaker is disabled, got %q", report)
+	}
+}
+
+func TestCircuitBreakerReopensOnFailedProbe(t *testing.T) {
+	breaker := NewCircuitBreaker(1, 20*time.Millisecond)
+
+	breaker.Record(&ScrapeError{Kind: KindCaptcha})
+	time.Sleep(30 * time.Millisecond)
+
+	if err := breaker.Allow(); err != nil {
+		t.Fatal("Expected a probe to be allowed after cool-down:", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Error("Only one probe should be in flight")
+	}
+
+	breaker.Record(&ScrapeError{Kind: KindRateLimited})
+	if _, open := breaker.OpenUntil(); !open {
+		t.Error("Failed probe should reopen the breaker")
+	}
+}
+
+func TestLaterPageBanReachesBreaker(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, err := os.ReadFile(filepath.Join("testdata", "search_page1.html"))
+		if err != nil {
+			t.Errorf("Failed to read fixture: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	breaker := NewCircuitBreaker(1, time.Minute)
+	backend := Guard(NewOLXScraper(Options{BaseURL: server.URL}), breaker, fastRetry)
+
+	listings, err := backend.SearchListings(context.Background(), models.SearchFilters{
+		Query:             "iphone 15",
+		MaxPages:          3,
+		IncludeNegotiable: true,
+	})
+	if !IsPartial(err) || KindOf(err) != KindForbidden {
+		t.Fatalf("Expected a partial forbidden error, got %v", err)
+	}
+	if len(listings) == 0 {
+		t.Error("Listings from page 1 should be returned with a partial error")
+	}
+	if requests != 2 {
+		t.Errorf("Partial results should not be retried, got %d requests", requests)
+	}
+	if _, open := breaker.OpenUntil(); !open {
+		t.Error("A ban on page 2 should open the breaker")
+	}
+}
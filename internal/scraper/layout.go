@@ -0,0 +1,209 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Селектори HTML-верстки OLX, за якими ведеться статистика
+const (
+	SelectorCard     = "[data-cy='l-card']"
+	SelectorTitle    = "h4"
+	SelectorPrice    = "p[data-testid='ad-price']"
+	SelectorLocation = "p[data-testid='location-date']"
+)
+
+const (
+	// busyQueryThreshold - середня кількість карток, після якої запит
+	// вважається "жвавим" і порожня видача стає підозрілою
+	busyQueryThreshold = 5.0
+	// historyWeight - вага нового значення в ковзному середньому
+	historyWeight = 0.3
+	// alertCooldown - як часто можна надсилати сповіщення по одному запиту
+	alertCooldown = time.Hour
+)
+
+// SelectorCounter - скільки разів селектор знайшов дані і скільки повернув порожнечу
+type SelectorCounter struct {
+	Found int64
+	Empty int64
+}
+
+// pageStats - статистика розбору однієї сторінки пошуку
+type pageStats struct {
+	Cards          int
+	MissingCards   int
+	EmptyTitles    int
+	EmptyPrices    int
+	EmptyLocations int
+}
+
+// LayoutMonitor відстежує зміни верстки OLX: рахує спрацювання селекторів,
+// знаходить аномалії, зберігає HTML сторінки на диск і сповіщає адміністраторів.
+type LayoutMonitor struct {
+	snapshotDir string
+	alert       func(text string)
+
+	mutex     sync.Mutex
+	counters  map[string]*SelectorCounter
+	history   map[string]float64
+	lastAlert map[string]time.Time
+	anomalies int64
+}
+
+func NewLayoutMonitor(snapshotDir string) *LayoutMonitor {
+	return &LayoutMonitor{
+		snapshotDir: snapshotDir,
+		counters:    make(map[string]*SelectorCounter),
+		history:     make(map[string]float64),
+		lastAlert:   make(map[string]time.Time),
+	}
+}
+
+// SetAlertHandler задає функцію для сповіщення адміністраторів
+func (m *LayoutMonitor) SetAlertHandler(alert func(text string)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.alert = alert
+}
+
+// Inspect перевіряє статистику першої сторінки пошуку за ключем запиту
+func (m *LayoutMonitor) Inspect(queryKey, pageURL string, stats pageStats, body []byte) {
+	if m == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	m.count(SelectorCard, stats.Cards-stats.MissingCards, stats.MissingCards)
+	m.count(SelectorTitle, stats.Cards-stats.EmptyTitles, stats.EmptyTitles)
+	m.count(SelectorPrice, stats.Cards-stats.EmptyPrices, stats.EmptyPrices)
+	m.count(SelectorLocation, stats.Cards-stats.EmptyLocations, stats.EmptyLocations)
+
+	average, known := m.history[queryKey]
+	if known {
+		m.history[queryKey] = (1-historyWeight)*average + historyWeight*float64(stats.Cards)
+	} else {
+		m.history[queryKey] = float64(stats.Cards)
+	}
+	m.mutex.Unlock()
+
+	var reasons []string
+	switch {
+	case stats.Cards == 0 && known && average >= busyQueryThreshold:
+		reasons = append(reasons, fmt.Sprintf("zero cards for a busy query (avg %.1f)", average))
+	case stats.Cards > 0:
+		if stats.MissingCards*2 >= stats.Cards {
+			reasons = append(reasons, fmt.Sprintf("%d/%d links outside %s", stats.MissingCards, stats.Cards, SelectorCard))
+		}
+		if stats.EmptyTitles*2 >= stats.Cards {
+			reasons = append(reasons, fmt.Sprintf("%d/%d cards with empty %s", stats.EmptyTitles, stats.Cards, SelectorTitle))
+		}
+		if stats.EmptyPrices*2 >= stats.Cards {
+			reasons = append(reasons, fmt.Sprintf("%d/%d cards with empty %s", stats.EmptyPrices, stats.Cards, SelectorPrice))
+		}
+	}
+
+	if len(reasons) == 0 {
+		return
+	}
+
+	m.report(queryKey, pageURL, strings.Join(reasons, "; "), body)
+}
+
+func (m *LayoutMonitor) count(selector string, found, empty int) {
+	counter, exists := m.counters[selector]
+	if !exists {
+		counter = &SelectorCounter{}
+		m.counters[selector] = counter
+	}
+	counter.Found += int64(found)
+	counter.Empty += int64(empty)
+}
+
+func (m *LayoutMonitor) report(queryKey, pageURL, reason string, body []byte) {
+	log.Printf("⚠️ Layout anomaly for query '%s': %s", queryKey, reason)
+
+	snapshotPath, err := m.saveSnapshot(queryKey, body)
+	if err != nil {
+		log.Printf("Failed to save layout snapshot: %v", err)
+	}
+
+	m.mutex.Lock()
+	m.anomalies++
+	alert := m.alert
+	canAlert := time.Since(m.lastAlert[queryKey]) > alertCooldown
+	if canAlert {
+		m.lastAlert[queryKey] = time.Now()
+	}
+	m.mutex.Unlock()
+
+	if alert == nil || !canAlert {
+		return
+	}
+
+	text := fmt.Sprintf("⚠️ Можлива зміна верстки OLX\n\n🔍 Запит: %s\n❗ %s\n🔗 %s", queryKey, reason, pageURL)
+	if snapshotPath != "" {
+		text += "\n💾 " + snapshotPath
+	}
+	alert(text)
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^\p{L}\p{N}_-]+`)
+
+func (m *LayoutMonitor) saveSnapshot(queryKey string, body []byte) (string, error) {
+	if m.snapshotDir == "" || len(body) == 0 {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(m.snapshotDir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s_%s.html",
+		time.Now().Format("20060102-150405"),
+		strings.Trim(unsafeFileChars.ReplaceAllString(queryKey, "_"), "_"))
+	path := filepath.Join(m.snapshotDir, name)
+
+	return path, os.WriteFile(path, body, 0o644)
+}
+
+// Counters повертає копію лічильників по селекторах
+func (m *LayoutMonitor) Counters() map[string]SelectorCounter {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	result := make(map[string]SelectorCounter, len(m.counters))
+	for selector, counter := range m.counters {
+		result[selector] = *counter
+	}
+	return result
+}
+
+// StatusReport - короткий звіт для команди /status
+func (m *LayoutMonitor) StatusReport() string {
+	counters := m.Counters()
+
+	m.mutex.Lock()
+	anomalies := m.anomalies
+	m.mutex.Unlock()
+
+	selectors := make([]string, 0, len(counters))
+	for selector := range counters {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	text := fmt.Sprintf("🧩 Верстка OLX (аномалій: %d)\n", anomalies)
+	for _, selector := range selectors {
+		counter := counters[selector]
+		text += fmt.Sprintf("   %s: ✅ %d / ❌ %d\n", selector, counter.Found, counter.Empty)
+	}
+	return text
+}
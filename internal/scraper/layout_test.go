@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLayoutMonitorDetectsEmptyTitles(t *testing.T) {
+	dir := t.TempDir()
+	monitor := NewLayoutMonitor(dir)
+
+	var alerts []string
+	monitor.SetAlertHandler(func(text string) {
+		alerts = append(alerts, text)
+	})
+
+	monitor.Inspect("iphone|", "http://olx.test/uk/list/q-iphone/", pageStats{Cards: 10, EmptyTitles: 1}, []byte("<html>ok</html>"))
+	if len(alerts) != 0 {
+		t.Fatalf("Expected no alerts for a healthy page, got %d", len(alerts))
+	}
+
+	monitor.Inspect("iphone|", "http://olx.test/uk/list/q-iphone/", pageStats{Cards: 10, EmptyTitles: 8}, []byte("<html>broken</html>"))
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(alerts))
+	}
+	if !strings.Contains(alerts[0], SelectorTitle) {
+		t.Errorf("Alert should mention the broken selector: %s", alerts[0])
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal("Error reading snapshot dir:", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 snapshot, got %d", len(files))
+	}
+
+	counters := monitor.Counters()
+	if counters[SelectorTitle].Found != 11 || counters[SelectorTitle].Empty != 9 {
+		t.Errorf("Unexpected title counters: %+v", counters[SelectorTitle])
+	}
+}
+
+func TestLayoutMonitorDetectsZeroCardsForBusyQuery(t *testing.T) {
+	monitor := NewLayoutMonitor("")
+
+	alerts := 0
+	monitor.SetAlertHandler(func(text string) {
+		alerts++
+	})
+
+	// Рідкісний запит: порожня видача - це норма
+	monitor.Inspect("vintage amp|", "", pageStats{Cards: 1}, nil)
+	monitor.Inspect("vintage amp|", "", pageStats{Cards: 0}, nil)
+	if alerts != 0 {
+		t.Errorf("Empty result for a quiet query should not alert")
+	}
+
+	monitor.Inspect("iphone|", "", pageStats{Cards: 40}, nil)
+	monitor.Inspect("iphone|", "", pageStats{Cards: 0}, nil)
+	if alerts != 1 {
+		t.Errorf("Expected 1 alert for a busy query returning zero cards, got %d", alerts)
+	}
+
+	// Повторне сповіщення по тому ж запиту придушується
+	monitor.Inspect("iphone|", "", pageStats{Cards: 0}, nil)
+	if alerts != 1 {
+		t.Errorf("Repeated alert should be suppressed, got %d", alerts)
+	}
+}
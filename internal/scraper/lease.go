@@ -0,0 +1,217 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"olx-hunter/internal/database"
+)
+
+// LeaseStore - оренди фільтрів у базі. Його реалізує *database.DB.
+type LeaseStore interface {
+	Heartbeat(instanceID string, ttl time.Duration) (int, error)
+	RenewLeases(owner string, ttl time.Duration) ([]uint, error)
+	ClaimLeases(owner string, ttl time.Duration, limit int) ([]uint, error)
+	ClaimLease(owner string, filterID uint, ttl time.Duration) (bool, error)
+	ReleaseLeases(owner string, filterIDs []uint) error
+	ReleaseInstance(owner string) error
+	CountActiveFilters() (int, error)
+	GetActiveFiltersByIDs(filterIDs []uint) ([]*database.UserFilter, error)
+}
+
+// LeaseCoordinator розподіляє активні фільтри між екземплярами скрапера
+// через оренди в Postgres. Кожен екземпляр тримає приблизно рівну частку
+// фільтрів; оренди мертвого екземпляра закінчуються і їх забирають інші.
+type LeaseCoordinator struct {
+	db         LeaseStore
+	service    *ScraperService
+	instanceID string
+	ttl        time.Duration
+
+	// syncMutex не дає Run і звірці фільтрів перерозподіляти оренди одночасно
+	syncMutex sync.Mutex
+
+	mutex       sync.Mutex
+	owned       map[uint]bool
+	instances   int
+	lastRenewal time.Time
+}
+
+func NewLeaseCoordinator(db LeaseStore, service *ScraperService, instanceID string, ttl time.Duration) *LeaseCoordinator {
+	if ttl < 10*time.Second {
+		ttl = 30 * time.Second
+	}
+	c := &LeaseCoordinator{
+		db:         db,
+		service:    service,
+		instanceID: instanceID,
+		ttl:        ttl,
+		owned:      make(map[uint]bool),
+	}
+	service.leases = c
+	return c
+}
+
+// Run оновлює оренди втричі частіше за їхній строк. Під час зупинки
+// оренди віддаються одразу, щоб інші екземпляри не чекали закінчення строку.
+func (c *LeaseCoordinator) Run(ctx context.Context) {
+	log.Printf("Lease coordinator started as '%s' (lease TTL %v)", c.instanceID, c.ttl)
+
+	ticker := time.NewTicker(c.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		if err := c.sync(); err != nil {
+			log.Printf("Failed to sync filter leases: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			if err := c.db.ReleaseInstance(c.instanceID); err != nil {
+				log.Printf("Failed to release filter leases: %v", err)
+			} else {
+				log.Println("Filter leases released")
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fairShare - скільки фільтрів має тримати кожен з instances екземплярів
+func fairShare(filters, instances int) int {
+	if instances < 1 {
+		instances = 1
+	}
+	return (filters + instances - 1) / instances
+}
+
+// sync оновлює пульс і оренди, віддає зайві фільтри або забирає вільні
+// і передає сервісу актуальний перелік фільтрів
+func (c *LeaseCoordinator) sync() error {
+	c.syncMutex.Lock()
+	defer c.syncMutex.Unlock()
+
+	instances, err := c.db.Heartbeat(c.instanceID, c.ttl)
+	if err == nil {
+		var renewed []uint
+		renewed, err = c.db.RenewLeases(c.instanceID, c.ttl)
+		if err == nil {
+			return c.rebalance(instances, renewed)
+		}
+	}
+
+	// Без бази не можна бути певним, що оренда ще наша
+	c.mutex.Lock()
+	expired := !c.lastRenewal.IsZero() && time.Since(c.lastRenewal) > c.ttl
+	if expired && len(c.owned) > 0 {
+		log.Printf("⚠️ Leases expired without renewal, dropping %d filters", len(c.owned))
+		c.owned = make(map[uint]bool)
+	}
+	c.mutex.Unlock()
+
+	if expired {
+		c.service.replaceFilters(nil)
+	}
+	return err
+}
+
+func (c *LeaseCoordinator) rebalance(instances int, owned []uint) error {
+	total, err := c.db.CountActiveFilters()
+	if err != nil {
+		return err
+	}
+	share := fairShare(total, instances)
+
+	// Запас в один фільтр, щоб оренди не перекидались туди-сюди
+	if excess := len(owned) - share - 1; excess > 0 {
+		sort.Slice(owned, func(i, j int) bool { return owned[i] < owned[j] })
+		released := owned[len(owned)-excess:]
+		if err := c.db.ReleaseLeases(c.instanceID, released); err != nil {
+			return err
+		}
+		owned = owned[:len(owned)-excess]
+		log.Printf("Released %d filters for other instances (%d instances alive)", excess, instances)
+	} else if missing := share - len(owned); missing > 0 {
+		claimed, err := c.db.ClaimLeases(c.instanceID, c.ttl, missing)
+		if err != nil {
+			return err
+		}
+		if len(claimed) > 0 {
+			log.Printf("Claimed %d filters (%d instances alive)", len(claimed), instances)
+		}
+		owned = append(owned, claimed...)
+	}
+
+	filters, err := c.db.GetActiveFiltersByIDs(owned)
+	if err != nil {
+		return err
+	}
+
+	// Вимкнені фільтри віддаємо, щоб вони не займали частку
+	active := make(map[uint]bool, len(filters))
+	for _, filter := range filters {
+		active[filter.ID] = true
+	}
+	var inactive []uint
+	for _, filterID := range owned {
+		if !active[filterID] {
+			inactive = append(inactive, filterID)
+		}
+	}
+	if err := c.db.ReleaseLeases(c.instanceID, inactive); err != nil {
+		log.Printf("Failed to release inactive filters: %v", err)
+	}
+
+	c.mutex.Lock()
+	c.owned = active
+	c.instances = instances
+	c.lastRenewal = time.Now()
+	c.mutex.Unlock()
+
+	c.service.replaceFilters(filters)
+	return nil
+}
+
+// claim забирає щойно створений або змінений фільтр, якщо ним ніхто не володіє
+func (c *LeaseCoordinator) claim(filterID uint) bool {
+	claimed, err := c.db.ClaimLease(c.instanceID, filterID, c.ttl)
+	if err != nil {
+		log.Printf("Failed to claim filter %d: %v", filterID, err)
+		return false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if claimed {
+		c.owned[filterID] = true
+	}
+	return claimed
+}
+
+// release віддає видалений або вимкнений фільтр
+func (c *LeaseCoordinator) release(filterID uint) {
+	if err := c.db.ReleaseLeases(c.instanceID, []uint{filterID}); err != nil {
+		log.Printf("Failed to release filter %d: %v", filterID, err)
+	}
+
+	c.mutex.Lock()
+	delete(c.owned, filterID)
+	c.mutex.Unlock()
+}
+
+// StatusReport - розподіл фільтрів для команди /status
+func (c *LeaseCoordinator) StatusReport() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	text := fmt.Sprintf("🧩 Екземпляр %s: %d фільтрів, живих екземплярів %d\n", c.instanceID, len(c.owned), c.instances)
+	if !c.lastRenewal.IsZero() {
+		text += fmt.Sprintf("   оренди оновлено: %s\n", c.lastRenewal.Format("15:04:05"))
+	}
+	return text
+}
@@ -0,0 +1,380 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/models"
+)
+
+func TestFairShare(t *testing.T) {
+	tests := []struct {
+		filters, instances, expected int
+	}{
+		{10, 1, 10},
+		{10, 3, 4},
+		{9, 3, 3},
+		{0, 2, 0},
+		{5, 0, 5},
+	}
+
+	for _, tt := range tests {
+		if got := fairShare(tt.filters, tt.instances); got != tt.expected {
+			t.Errorf("fairShare(%d, %d) = %d, expected %d", tt.filters, tt.instances, got, tt.expected)
+		}
+	}
+}
+
+type memLease struct {
+	owner   string
+	expires time.Time
+}
+
+// memLeases - оренди в пам'яті з годинником, який рухає тест
+type memLeases struct {
+	mutex sync.Mutex
+	now   time.Time
+	fail  bool
+
+	filters    map[uint]*database.UserFilter
+	leases     map[uint]memLease
+	heartbeats map[string]time.Time
+}
+
+var errLeaseStore = errors.New("lease store unavailable")
+
+func newMemLeases(filterIDs ...uint) *memLeases {
+	m := &memLeases{
+		now:        time.Now(),
+		filters:    make(map[uint]*database.UserFilter),
+		leases:     make(map[uint]memLease),
+		heartbeats: make(map[string]time.Time),
+	}
+	for _, id := range filterIDs {
+		m.filters[id] = &database.UserFilter{ID: id, Query: "iphone 15", IsActive: true, IncludeNegotiable: true}
+	}
+	return m
+}
+
+func (m *memLeases) advance(d time.Duration) {
+	m.mutex.Lock()
+	m.now = m.now.Add(d)
+	m.mutex.Unlock()
+}
+
+func (m *memLeases) setFail(fail bool) {
+	m.mutex.Lock()
+	m.fail = fail
+	m.mutex.Unlock()
+}
+
+// owner повертає власника чинної оренди фільтра
+func (m *memLeases) owner(filterID uint) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if lease, ok := m.leases[filterID]; ok && lease.expires.After(m.now) {
+		return lease.owner
+	}
+	return ""
+}
+
+func (m *memLeases) free(filterID uint) bool {
+	lease, ok := m.leases[filterID]
+	return !ok || !lease.expires.After(m.now)
+}
+
+func (m *memLeases) Heartbeat(instanceID string, ttl time.Duration) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.fail {
+		return 0, errLeaseStore
+	}
+	m.heartbeats[instanceID] = m.now
+	for id, beat := range m.heartbeats {
+		if !beat.After(m.now.Add(-ttl)) {
+			delete(m.heartbeats, id)
+		}
+	}
+	return len(m.heartbeats), nil
+}
+
+func (m *memLeases) RenewLeases(owner string, ttl time.Duration) ([]uint, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.fail {
+		return nil, errLeaseStore
+	}
+	var renewed []uint
+	for id, lease := range m.leases {
+		if lease.owner == owner {
+			m.leases[id] = memLease{owner: owner, expires: m.now.Add(ttl)}
+			renewed = append(renewed, id)
+		}
+	}
+	return renewed, nil
+}
+
+func (m *memLeases) ClaimLeases(owner string, ttl time.Duration, limit int) ([]uint, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.fail {
+		return nil, errLeaseStore
+	}
+	ids := make([]uint, 0, len(m.filters))
+	for id, filter := range m.filters {
+		if filter.IsActive && m.free(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	for _, id := range ids {
+		m.leases[id] = memLease{owner: owner, expires: m.now.Add(ttl)}
+	}
+	return ids, nil
+}
+
+func (m *memLeases) ClaimLease(owner string, filterID uint, ttl time.Duration) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.fail {
+		return false, errLeaseStore
+	}
+	if !m.free(filterID) && m.leases[filterID].owner != owner {
+		return false, nil
+	}
+	m.leases[filterID] = memLease{owner: owner, expires: m.now.Add(ttl)}
+	return true, nil
+}
+
+func (m *memLeases) ReleaseLeases(owner string, filterIDs []uint) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, id := range filterIDs {
+		if m.leases[id].owner == owner {
+			delete(m.leases, id)
+		}
+	}
+	return nil
+}
+
+func (m *memLeases) ReleaseInstance(owner string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for id, lease := range m.leases {
+		if lease.owner == owner {
+			delete(m.leases, id)
+		}
+	}
+	delete(m.heartbeats, owner)
+	return nil
+}
+
+func (m *memLeases) CountActiveFilters() (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.fail {
+		return 0, errLeaseStore
+	}
+	count := 0
+	for _, filter := range m.filters {
+		if filter.IsActive {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memLeases) GetActiveFiltersByIDs(filterIDs []uint) ([]*database.UserFilter, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.fail {
+		return nil, errLeaseStore
+	}
+	var filters []*database.UserFilter
+	for _, id := range filterIDs {
+		if filter, ok := m.filters[id]; ok && filter.IsActive {
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+// leaseInstance - екземпляр скрапера зі спільними орендами
+type leaseInstance struct {
+	service *ScraperService
+	leases  *LeaseCoordinator
+}
+
+func newLeaseInstance(t *testing.T, store *memLeases, id string, backend Scraper) *leaseInstance {
+	t.Helper()
+	if backend == nil {
+		backend = searchFunc(func(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error) {
+			return nil, nil
+		})
+	}
+	service := newTestService(newMemStore(), backend, ServiceOptions{})
+	return &leaseInstance{
+		service: service,
+		leases:  NewLeaseCoordinator(store, service, id, 30*time.Second),
+	}
+}
+
+func (i *leaseInstance) sync(t *testing.T) {
+	t.Helper()
+	if err := i.leases.sync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// filterIDs - відсортовані фільтри, які скрапить екземпляр
+func (i *leaseInstance) filterIDs() []uint {
+	i.service.filtersMutex.RLock()
+	defer i.service.filtersMutex.RUnlock()
+	ids := make([]uint, 0, len(i.service.activeFilters))
+	for id := range i.service.activeFilters {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	return ids
+}
+
+// searchFunc - бекенд пошуку з функції
+type searchFunc func(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error)
+
+func (f searchFunc) SearchListings(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error) {
+	return f(ctx, filters)
+}
+
+func TestLeaseHandoverToNewInstance(t *testing.T) {
+	store := newMemLeases(1, 2, 3, 4, 5, 6)
+	first := newLeaseInstance(t, store, "a", nil)
+	second := newLeaseInstance(t, store, "b", nil)
+
+	first.sync(t)
+	if got := first.filterIDs(); len(got) != 6 {
+		t.Fatalf("The only instance should take every filter, got %v", got)
+	}
+
+	// Другий екземпляр з'явився, але всі фільтри ще зайняті
+	second.sync(t)
+	if got := second.filterIDs(); len(got) != 0 {
+		t.Fatalf("Leased filters must not be taken, got %v", got)
+	}
+
+	// Перший віддає надлишок, другий його забирає
+	first.sync(t)
+	second.sync(t)
+
+	a, b := first.filterIDs(), second.filterIDs()
+	if len(a)+len(b) != 6 || len(b) == 0 {
+		t.Fatalf("Filters should be split between instances, got %v and %v", a, b)
+	}
+	for _, id := range a {
+		if store.owner(id) != "a" {
+			t.Errorf("Filter %d is scraped by a but leased to %q", id, store.owner(id))
+		}
+	}
+	for _, id := range b {
+		if store.owner(id) != "b" {
+			t.Errorf("Filter %d is scraped by b but leased to %q", id, store.owner(id))
+		}
+	}
+
+	// Новий фільтр дістається першому, хто його забрав
+	store.filters[7] = &database.UserFilter{ID: 7, Query: "macbook", IsActive: true}
+	second.service.AddFilter(store.filters[7])
+	first.service.AddFilter(store.filters[7])
+	if !second.service.owns(7) || first.service.owns(7) {
+		t.Error("A claimed filter should be scraped only by its owner")
+	}
+}
+
+func TestLeaseExpiryHandsFiltersOver(t *testing.T) {
+	store := newMemLeases(1, 2, 3, 4)
+	first := newLeaseInstance(t, store, "a", nil)
+	second := newLeaseInstance(t, store, "b", nil)
+
+	first.sync(t)
+	second.sync(t)
+	if got := second.filterIDs(); len(got) != 0 {
+		t.Fatalf("Leased filters must not be taken, got %v", got)
+	}
+
+	// Перший завис: пульс і оренди не оновлюються
+	store.advance(31 * time.Second)
+	second.sync(t)
+	if got := second.filterIDs(); len(got) != 4 {
+		t.Fatalf("Expired leases should be claimed, got %v", got)
+	}
+
+	// Перший ожив і дізнається, що фільтри вже не його
+	first.sync(t)
+	if got := first.filterIDs(); len(got) != 0 {
+		t.Errorf("Lost leases should stop scraping, got %v", got)
+	}
+}
+
+func TestLeaseExpiryWithoutDatabase(t *testing.T) {
+	store := newMemLeases(1, 2)
+	instance := newLeaseInstance(t, store, "a", nil)
+	instance.sync(t)
+
+	store.setFail(true)
+	if err := instance.leases.sync(); err == nil {
+		t.Fatal("Expected the store error")
+	}
+	if got := instance.filterIDs(); len(got) != 2 {
+		t.Fatalf("Filters should be kept while the lease is valid, got %v", got)
+	}
+
+	// Строк оренди минув без оновлення: інший екземпляр уже міг її забрати
+	instance.leases.mutex.Lock()
+	instance.leases.lastRenewal = time.Now().Add(-time.Minute)
+	instance.leases.mutex.Unlock()
+	instance.leases.sync()
+	if got := instance.filterIDs(); len(got) != 0 {
+		t.Errorf("Filters should be dropped after the lease expires, got %v", got)
+	}
+}
+
+func TestScrapeGroupSkipsFilterLostDuringSearch(t *testing.T) {
+	store := newMemLeases(1, 2)
+
+	var first *leaseInstance
+	backend := searchFunc(func(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error) {
+		// Поки йде запит, оренда фільтра 1 минула і її забрав інший екземпляр
+		store.advance(31 * time.Second)
+		if _, err := store.ClaimLease("b", 1, 30*time.Second); err != nil {
+			return nil, err
+		}
+		first.sync(t)
+		return []models.Listing{{Title: "iPhone 15", URL: "https://www.olx.ua/d/uk/obyavlenie/iphone-ID1.html"}}, nil
+	})
+	first = newLeaseInstance(t, store, "a", backend)
+	first.sync(t)
+
+	filters, _ := store.GetActiveFiltersByIDs([]uint{1, 2})
+	groups := groupFilters(filters, first.service.backends.Resolve)
+	if len(groups) != 1 {
+		t.Fatalf("Expected one shared group, got %d", len(groups))
+	}
+	if _, err := first.service.scrapeGroup(context.Background(), groups[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := first.service.db.(*memStore)
+	if urls := saved.savedURLs(1); len(urls) != 0 {
+		t.Errorf("A filter leased to another instance must not be saved, got %v", urls)
+	}
+	if urls := saved.savedURLs(2); len(urls) != 1 {
+		t.Errorf("The filter still owned should be saved, got %v", urls)
+	}
+}
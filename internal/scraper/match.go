@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"strings"
+	"time"
+
+	"olx-hunter/internal/models"
+)
+
+// matchesFilters перевіряє оголошення на стороні клієнта. Ціни порівнюються
+// в гривнях за таблицею курсів.
+func matchesFilters(listing models.Listing, filters models.SearchFilters, rates models.CurrencyRates) bool {
+	if filters.SkipPromoted && listing.Promoted {
+		return false
+	}
+
+	if !matchesPrice(listing.PriceInfo, filters, rates) {
+		return false
+	}
+
+	if filters.City != "" && !matchesCity(listing, filters.City) {
+		return false
+	}
+
+	if filters.MaxAgeHours > 0 && !listing.PostedAt.IsZero() {
+		if time.Since(listing.PostedAt) > time.Duration(filters.MaxAgeHours)*time.Hour {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesCity порівнює місто оголошення з містом фільтра через slug OLX,
+// тож "Київ" і "kiev" - одне місто. Картки видачі не показують область,
+// тому для області покладаємося на URL, який OLX вже обмежив нею.
+func matchesCity(listing models.Listing, city string) bool {
+	slug := citySlug(city)
+	if slug == "" {
+		// Назви немає в таблиці, URL не обмежено: шукаємо її в тексті локації
+		return strings.Contains(strings.ToLower(listing.Location), strings.ToLower(strings.TrimSpace(city)))
+	}
+
+	if regionSlugs[slug] {
+		return listing.Region == "" || citySlug(listing.Region) == slug
+	}
+
+	if listingSlug := citySlug(listing.City); listingSlug != "" {
+		return listingSlug == slug
+	}
+	// Місто не розібралось: шукаємо в локації будь-яку назву цього міста
+	location := strings.ToLower(listing.Location)
+	for name, nameSlug := range citySlugs {
+		if nameSlug == slug && strings.Contains(location, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPrice(price models.Price, filters models.SearchFilters, rates models.CurrencyRates) bool {
+	if price.Negotiable && !filters.IncludeNegotiable {
+		return false
+	}
+
+	if filters.MinPrice == 0 && filters.MaxPrice == 0 {
+		return true
+	}
+
+	amount, ok := price.InUAH(rates)
+	if !ok {
+		// Суми немає або валюта невідома: пропускаємо тільки договірні,
+		// якщо користувач їх дозволив
+		return price.Negotiable
+	}
+
+	if filters.MinPrice > 0 && amount < float64(filters.MinPrice) {
+		return false
+	}
+	if filters.MaxPrice > 0 && amount > float64(filters.MaxPrice) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package scraper
+
+import (
+	"fmt"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/models"
+)
+
+// notificationBatch збирає сповіщення по фільтрах у порядку появи
+type notificationBatch struct {
+	byFilter map[uint]*models.Notification
+	order    []uint
+}
+
+func newNotificationBatch() *notificationBatch {
+	return &notificationBatch{byFilter: make(map[uint]*models.Notification)}
+}
+
+// get повертає сповіщення фільтра, створюючи його за потреби
+func (b *notificationBatch) get(filter *database.UserFilter) *models.Notification {
+	notif, exists := b.byFilter[filter.ID]
+	if !exists {
+		notif = &models.Notification{TelegramID: filter.User.TelegramID, FilterName: filter.Name}
+		b.byFilter[filter.ID] = notif
+		b.order = append(b.order, filter.ID)
+	}
+	return notif
+}
+
+func (b *notificationBatch) len() int {
+	return len(b.order)
+}
+
+// messages кодує сповіщення для outbox
+func (b *notificationBatch) messages() ([]*database.OutboxMessage, error) {
+	messages := make([]*database.OutboxMessage, 0, len(b.order))
+	for _, filterID := range b.order {
+		message, err := database.NewOutboxMessage(filterID, *b.byFilter[filterID])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode notification for filter %d: %w", filterID, err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
@@ -0,0 +1,59 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"olx-hunter/internal/models"
+)
+
+var amountRegex = regexp.MustCompile(`\d[\d\s\x{00a0}\x{202f}]*(?:[.,]\d{1,2})?`)
+
+// parsePrice розбирає текст ціни з картки OLX, наприклад
+// "12 500 грн.", "$ 500", "450 €", "1 200.50 грн.Договірна", "Обмін"
+func parsePrice(priceStr string) models.Price {
+	text := strings.ToLower(priceStr)
+
+	price := models.Price{
+		Currency:   detectCurrency(text),
+		Negotiable: strings.Contains(text, "договірна"),
+		Free:       strings.Contains(text, "безкоштовно"),
+		Exchange:   strings.Contains(text, "обмін"),
+	}
+
+	if price.Free {
+		return price
+	}
+
+	match := amountRegex.FindString(text)
+	if match == "" {
+		return price
+	}
+
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\t', '\n':
+			return -1
+		case ',':
+			return '.'
+		}
+		return r
+	}, match)
+
+	if amount, err := strconv.ParseFloat(cleaned, 64); err == nil {
+		price.Amount = amount
+	}
+	return price
+}
+
+func detectCurrency(text string) string {
+	switch {
+	case strings.Contains(text, "$") || strings.Contains(text, "usd"):
+		return "USD"
+	case strings.Contains(text, "€") || strings.Contains(text, "eur"):
+		return "EUR"
+	default:
+		return models.CurrencyUAH
+	}
+}
@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/events"
+	"olx-hunter/internal/models"
+)
+
+// priceDropPercent повертає, на скільки відсотків подешевшало оголошення.
+// ok=false, якщо ціна не знизилась або її не можна порівняти.
+func priceDropPercent(old, current models.Price, rates models.CurrencyRates) (float64, bool) {
+	oldUAH, ok := old.InUAH(rates)
+	if !ok || oldUAH <= 0 {
+		return 0, false
+	}
+	currentUAH, ok := current.InUAH(rates)
+	if !ok || currentUAH >= oldUAH {
+		return 0, false
+	}
+	return (oldUAH - currentUAH) / oldUAH * 100, true
+}
+
+// checkPriceChanges записує зміни цін у видачі і разом з ними кладе в outbox
+// сповіщення для фільтрів, що вже бачили оголошення і підписані на зниження
+// ціни. Фільтри шукаються за збігами в базі, тому сповіщення отримають і
+// фільтри з інших груп. Кожна записана зміна, зокрема й подорожчання,
+// публікується як подія.
+func (s *ScraperService) checkPriceChanges(ctx context.Context, listings []models.Listing) {
+	changes, err := s.db.RecordPriceChanges(listings, func(changes map[string]database.PriceHistory) ([]*database.OutboxMessage, error) {
+		return s.priceDropAlerts(listings, changes)
+	})
+	if err != nil {
+		log.Printf("Failed to record price changes: %v", err)
+		return
+	}
+	if len(changes) == 0 || s.publisher == nil {
+		return
+	}
+
+	for _, listing := range listings {
+		change, changed := changes[listing.URL]
+		if !changed {
+			continue
+		}
+		// Одне оголошення публікуємо один раз
+		delete(changes, listing.URL)
+
+		priceChanged := events.PriceChanged{
+			URL:          listing.URL,
+			Title:        listing.Title,
+			OldPrice:     change.OldPrice,
+			OldPriceInfo: change.OldPriceInfo,
+			NewPrice:     change.NewPrice,
+			NewPriceInfo: change.NewPriceInfo,
+		}
+		publishEvents(ctx, s.publisher, matchedFilterEvents(s.db, change.ListingID, func(filterID uint) events.Event {
+			return events.NewPriceChanged(filterID, priceChanged)
+		}))
+	}
+}
+
+// priceDropAlerts готує сповіщення про зниження цін. Помилка скасовує запис
+// змін, тож вони знайдуться знову під час наступного скрапінгу.
+func (s *ScraperService) priceDropAlerts(listings []models.Listing, changes map[string]database.PriceHistory) ([]*database.OutboxMessage, error) {
+	batch := newNotificationBatch()
+	notified := make(map[string]bool, len(changes))
+	for _, listing := range listings {
+		change, changed := changes[listing.URL]
+		if !changed || notified[listing.URL] {
+			continue
+		}
+		// Одне оголошення сповіщаємо один раз
+		notified[listing.URL] = true
+
+		percent, dropped := priceDropPercent(change.OldPriceInfo, change.NewPriceInfo, s.backends.Rates())
+		if !dropped {
+			continue
+		}
+		log.Printf("📉 Price dropped by %.0f%% for %s", percent, listing.URL)
+
+		filters, err := s.db.GetPriceAlertFilters(change.ListingID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get price alert filters for %s: %w", listing.URL, err)
+		}
+
+		for _, filter := range filters {
+			if percent < float64(filter.MinPriceDrop) {
+				continue
+			}
+			notif := batch.get(filter)
+			notif.PriceDrops = append(notif.PriceDrops, models.PriceDrop{
+				Listing:  listing,
+				OldPrice: change.OldPrice,
+				Percent:  percent,
+			})
+		}
+	}
+	return batch.messages()
+}
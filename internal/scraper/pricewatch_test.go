@@ -0,0 +1,34 @@
+package scraper
+
+import (
+	"math"
+	"testing"
+
+	"olx-hunter/internal/models"
+)
+
+func TestPriceDropPercent(t *testing.T) {
+	rates := models.CurrencyRates{"USD": 40}
+
+	tests := []struct {
+		name     string
+		old      models.Price
+		current  models.Price
+		expected float64
+		dropped  bool
+	}{
+		{"drop in UAH", models.Price{Amount: 10000}, models.Price{Amount: 8000}, 20, true},
+		{"rise", models.Price{Amount: 8000}, models.Price{Amount: 10000}, 0, false},
+		{"currency changed", models.Price{Amount: 500, Currency: "USD"}, models.Price{Amount: 18000}, 10, true},
+		{"now free", models.Price{Amount: 1000}, models.Price{Free: true}, 100, true},
+		{"now negotiable", models.Price{Amount: 1000}, models.Price{Negotiable: true}, 0, false},
+		{"unknown rate", models.Price{Amount: 500, Currency: "PLN"}, models.Price{Amount: 100}, 0, false},
+	}
+
+	for _, tt := range tests {
+		percent, dropped := priceDropPercent(tt.old, tt.current, rates)
+		if dropped != tt.dropped || math.Abs(percent-tt.expected) > 0.01 {
+			t.Errorf("%s: expected %.2f%% (%v), got %.2f%% (%v)", tt.name, tt.expected, tt.dropped, percent, dropped)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"olx-hunter/internal/cache"
+	"olx-hunter/internal/models"
+)
+
+// testProxy - локальний HTTP-проксі, який сам відповідає на запити
+// і запам'ятовує заголовки
+type testProxy struct {
+	*httptest.Server
+
+	mutex      sync.Mutex
+	status     int
+	userAgents []string
+}
+
+func newTestProxy(t *testing.T, status int) *testProxy {
+	t.Helper()
+
+	p := &testProxy{status: status}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mutex.Lock()
+		p.userAgents = append(p.userAgents, r.Header.Get("User-Agent"))
+		status := p.status
+		p.mutex.Unlock()
+
+		w.WriteHeader(status)
+		w.Write([]byte("<html></html>"))
+	}))
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func (p *testProxy) setStatus(status int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.status = status
+}
+
+func (p *testProxy) hits() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.userAgents)
+}
+
+func get(t *testing.T, transport http.RoundTripper) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, "http://olx.test/uk/list/q-iphone/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestRotatingTransportRoundRobin(t *testing.T) {
+	first := newTestProxy(t, http.StatusOK)
+	second := newTestProxy(t, http.StatusOK)
+
+	pool := NewProxyPool([]string{first.URL, second.URL}, "", time.Minute)
+	transport := NewRotatingTransport(pool, nil)
+
+	for i := 0; i < 4; i++ {
+		if _, err := get(t, transport); err != nil {
+			t.Fatal("Error sending request:", err)
+		}
+	}
+
+	if first.hits() != 2 || second.hits() != 2 {
+		t.Errorf("Expected 2 requests per proxy, got %d and %d", first.hits(), second.hits())
+	}
+
+	for _, ua := range first.userAgents {
+		if !strings.HasPrefix(ua, "Mozilla/5.0") {
+			t.Errorf("Expected a browser User-Agent, got '%s'", ua)
+		}
+	}
+}
+
+func TestRotatingTransportEjectsBlockedProxy(t *testing.T) {
+	good := newTestProxy(t, http.StatusOK)
+	banned := newTestProxy(t, http.StatusTooManyRequests)
+
+	pool := NewProxyPool([]string{banned.URL, good.URL}, "http://olx.test/", time.Minute)
+	transport := NewRotatingTransport(pool, nil)
+
+	for i := 0; i < 5; i++ {
+		get(t, transport)
+	}
+
+	if banned.hits() != 1 {
+		t.Errorf("Banned proxy should be ejected after the first 429, got %d requests", banned.hits())
+	}
+	if good.hits() != 4 {
+		t.Errorf("Expected the rest to go through the healthy proxy, got %d", good.hits())
+	}
+
+	// Після розбану перевірка здоров'я повертає проксі в ротацію
+	banned.setStatus(http.StatusOK)
+	pool.CheckHealth(context.Background())
+
+	before := banned.hits()
+	for i := 0; i < 2; i++ {
+		get(t, transport)
+	}
+	if banned.hits() != before+1 {
+		t.Errorf("Restored proxy should be back in rotation")
+	}
+}
+
+func TestRotatingTransportAllProxiesEjected(t *testing.T) {
+	banned := newTestProxy(t, http.StatusForbidden)
+
+	pool := NewProxyPool([]string{banned.URL}, "", time.Minute)
+	transport := NewRotatingTransport(pool, nil)
+
+	get(t, transport)
+	if _, err := get(t, transport); err != ErrNoHealthyProxies {
+		t.Errorf("Expected ErrNoHealthyProxies, got %v", err)
+	}
+}
+
+// exhaustedBudget - бюджет, у якого закінчились токени
+type exhaustedBudget struct{}
+
+func (exhaustedBudget) Acquire(ctx context.Context) error { return cache.ErrBudgetExhausted }
+func (exhaustedBudget) Available(ctx context.Context) int { return 0 }
+
+func TestRotatingTransportRespectsBudget(t *testing.T) {
+	fs := newFixtureServer(t)
+
+	s := NewOLXScraper(Options{
+		BaseURL:   fs.URL,
+		Transport: NewRotatingTransport(nil, exhaustedBudget{}),
+	})
+
+	_, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone 15"})
+	if KindOf(err) != KindBudget {
+		t.Errorf("Expected a budget error, got %v", err)
+	}
+	if fs.requestCount() != 0 {
+		t.Errorf("No request should reach OLX without a token, got %d", fs.requestCount())
+	}
+}
+
+func TestScraperUsesSharedTransport(t *testing.T) {
+	fs := newFixtureServer(t)
+
+	var mutex sync.Mutex
+	var userAgents []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		mutex.Unlock()
+		// Проксі пересилає запит на сервер з фікстурами
+		resp, err := http.Get(fs.URL + r.URL.RequestURI())
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(proxy.Close)
+
+	s := NewOLXScraper(Options{
+		BaseURL:   fs.URL,
+		Transport: NewRotatingTransport(NewProxyPool([]string{proxy.URL}, "", time.Minute), nil),
+	})
+
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone 15", IncludeNegotiable: true})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+	if len(listings) == 0 {
+		t.Error("Expected listings through the proxy")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(userAgents) != 1 {
+		t.Fatalf("Expected 1 request through the proxy, got %d", len(userAgents))
+	}
+	if strings.Contains(userAgents[0], "colly") {
+		t.Errorf("Default colly User-Agent leaked: %s", userAgents[0])
+	}
+}
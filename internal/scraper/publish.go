@@ -0,0 +1,52 @@
+package scraper
+
+import (
+	"context"
+	"log"
+
+	"olx-hunter/internal/events"
+	"olx-hunter/internal/models"
+)
+
+// publishEvents надсилає події, якщо публікацію ввімкнено. Помилка брокера
+// лише логується: скрапінг і сповіщення від Kafka не залежать.
+func publishEvents(ctx context.Context, publisher events.Publisher, evs []events.Event) {
+	if publisher == nil || len(evs) == 0 {
+		return
+	}
+	if err := publisher.Publish(ctx, evs...); err != nil {
+		log.Printf("Failed to publish %d events: %v", len(evs), err)
+	}
+}
+
+// publishDiscovered публікує нові для фільтра оголошення
+func (s *ScraperService) publishDiscovered(ctx context.Context, filterID uint, listings []models.Listing, baseline bool) {
+	if s.publisher == nil {
+		return
+	}
+	evs := make([]events.Event, 0, len(listings))
+	for _, listing := range listings {
+		evs = append(evs, events.NewListingDiscovered(filterID, listing, baseline))
+	}
+	publishEvents(ctx, s.publisher, evs)
+}
+
+// matchLookup знаходить фільтри, що бачили оголошення
+type matchLookup interface {
+	GetMatchedFilterIDs(listingID uint) ([]uint, error)
+}
+
+// matchedFilterEvents створює подію для кожного фільтра, що бачив оголошення
+func matchedFilterEvents(db matchLookup, listingID uint, newEvent func(filterID uint) events.Event) []events.Event {
+	filterIDs, err := db.GetMatchedFilterIDs(listingID)
+	if err != nil {
+		log.Printf("Failed to get filters of listing %d: %v", listingID, err)
+		return nil
+	}
+
+	evs := make([]events.Event, 0, len(filterIDs))
+	for _, filterID := range filterIDs {
+		evs = append(evs, newEvent(filterID))
+	}
+	return evs
+}
@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"olx-hunter/internal/models"
+)
+
+// Назви вбудованих бекендів
+const (
+	BackendHTML = "html"
+	BackendAPI  = "api"
+)
+
+// Registry зберігає іменовані бекенди пошуку. Фільтр може вибрати бекенд
+// за назвою, інакше використовується бекенд за замовчуванням.
+type Registry struct {
+	backends    map[string]Scraper
+	defaultName string
+	breaker     *CircuitBreaker
+	rates       models.CurrencyRates
+	mutex       sync.RWMutex
+}
+
+func NewRegistry(defaultName string) *Registry {
+	return &Registry{
+		backends:    make(map[string]Scraper),
+		defaultName: defaultName,
+	}
+}
+
+// NewDefaultRegistry реєструє HTML та JSON API бекенди OLX зі спільними
+// налаштуваннями, повторами та запобіжником
+func NewDefaultRegistry(defaultName string, opts Options) *Registry {
+	r := NewRegistry(defaultName)
+	r.breaker = opts.Breaker
+	r.rates = opts.Rates
+	r.Register(BackendHTML, Guard(NewOLXScraper(opts), opts.Breaker, opts.retry()))
+	r.Register(BackendAPI, Guard(NewOLXAPIScraper(opts), opts.Breaker, opts.retry()))
+
+	if !r.Has(defaultName) {
+		log.Printf("Unknown scraper backend '%s', falling back to '%s'", defaultName, BackendHTML)
+		r.defaultName = BackendHTML
+	}
+	return r
+}
+
+// Breaker повертає спільний запобіжник бекендів, може бути nil
+func (r *Registry) Breaker() *CircuitBreaker {
+	return r.breaker
+}
+
+// Rates повертає таблицю курсів, з якою працюють бекенди
+func (r *Registry) Rates() models.CurrencyRates {
+	return r.rates
+}
+
+func (r *Registry) Register(name string, backend Scraper) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.backends[name] = backend
+}
+
+func (r *Registry) Has(name string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, exists := r.backends[name]
+	return exists
+}
+
+// Resolve повертає назву бекенда, який фактично обслуговує name
+func (r *Registry) Resolve(name string) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if _, exists := r.backends[name]; exists {
+		return name
+	}
+	return r.defaultName
+}
+
+// Get повертає бекенд за назвою або бекенд за замовчуванням,
+// якщо назва порожня чи невідома
+func (r *Registry) Get(name string) Scraper {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if backend, exists := r.backends[name]; exists {
+		return backend
+	}
+	return r.backends[r.defaultName]
+}
+
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make([]string, 0, len(r.backends))
+	for name := range r.backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
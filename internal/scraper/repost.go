@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"math/bits"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/models"
+)
+
+// Що робити з репостами (значення user_filters.repost_mode)
+const (
+	RepostTag  = ""     // сповіщати з позначкою "репост"
+	RepostSkip = "skip" // не сповіщати
+	RepostOff  = "off"  // не перевіряти
+)
+
+// maxPhotoDistance - скільки бітів dHash можуть відрізнятись у того самого фото
+const maxPhotoDistance = 6
+
+// maxPhotoSize обмежує розмір фото, яке завантажується для хешу
+const maxPhotoSize = 5 << 20
+
+// normalizeText лишає тільки літери та цифри в нижньому регістрі
+func normalizeText(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// listingFingerprint - відбиток оголошення з нормалізованих назви, ціни і
+// локації. Репост того самого товару має той самий відбиток. Продавець
+// порівнюється окремо, бо відомий лише після FetchDetails.
+func listingFingerprint(listing models.Listing) string {
+	location := listing.City + " " + listing.District
+	if listing.City == "" {
+		location = listing.Location
+	}
+
+	parts := []string{
+		normalizeText(listing.Title),
+		fmt.Sprintf("%.0f %s %v", listing.PriceInfo.Amount, listing.PriceInfo.Currency, listing.PriceInfo.Free),
+		normalizeText(location),
+	}
+	sum := sha1.Sum([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// photoDistance - кількість різних бітів двох dHash
+func photoDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// dHash - різницевий хеш зображення: 8x8 порівнянь сусідніх пікселів
+// зменшеної сірої копії. Не змінюється від стиснення та масштабу.
+func dHash(img image.Image) uint64 {
+	const width, height = 9, 8
+
+	bounds := img.Bounds()
+	var gray [height][width]float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			y0 := bounds.Min.Y + y*bounds.Dy()/height
+			y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+			gray[y][x] = averageLuma(img, x0, y0, max(x1, x0+1), max(y1, y0+1))
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			hash <<= 1
+			if gray[y][x] > gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// averageLuma - середня яскравість прямокутника (не більше 16x16 вибірок)
+func averageLuma(img image.Image, x0, y0, x1, y1 int) float64 {
+	stepX := max(1, (x1-x0)/16)
+	stepY := max(1, (y1-y0)/16)
+
+	var sum float64
+	var count int
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			count++
+		}
+	}
+	return sum / float64(count)
+}
+
+// fetchPhotoHash завантажує фото і рахує його dHash
+func fetchPhotoHash(ctx context.Context, client *http.Client, photoURL string) (uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, photoURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	// Без webp, бо стандартна бібліотека його не декодує
+	req.Header.Set("Accept", "image/jpeg,image/png;q=0.9")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status for photo: %s", resp.Status)
+	}
+
+	img, _, err := image.Decode(io.LimitReader(resp.Body, maxPhotoSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode photo: %w", err)
+	}
+	return dHash(img), nil
+}
+
+// findRepost перевіряє, чи фільтр вже бачив цей товар в іншому оголошенні:
+// у базі або серед earlier - раніших оголошень тієї самої видачі, які ще не збережено
+func (s *ScraperService) findRepost(filter *database.UserFilter, listing models.Listing, earlier []models.Listing) *models.Repost {
+	if filter.RepostMode == RepostOff {
+		return nil
+	}
+
+	since := time.Now().Add(-s.repostWindow)
+	previous, err := s.db.FindRepost(filter.ID, listing.URL, listing.Fingerprint, listing.SellerName,
+		listing.PhotoHash, maxPhotoDistance, since)
+	if err != nil {
+		log.Printf("Failed to check repost for %s: %v", listing.URL, err)
+	}
+	if previous != nil {
+		return &models.Repost{URL: previous.URL, SeenAt: previous.CreatedAt, Price: previous.Price}
+	}
+
+	for i := len(earlier) - 1; i >= 0; i-- {
+		if !sameItem(earlier[i], listing) {
+			continue
+		}
+		seenAt := earlier[i].PostedAt
+		if seenAt.IsZero() {
+			seenAt = time.Now()
+		}
+		return &models.Repost{URL: earlier[i].URL, SeenAt: seenAt, Price: earlier[i].Price}
+	}
+	return nil
+}
+
+// sameItem порівнює два оголошення за тими самими правилами, що й FindRepost
+func sameItem(a, b models.Listing) bool {
+	if a.URL == b.URL {
+		return false
+	}
+	sameSeller := a.SellerName != "" && a.SellerName == b.SellerName
+	if a.Fingerprint != "" && a.Fingerprint == b.Fingerprint &&
+		(a.SellerName == "" || b.SellerName == "" || sameSeller) {
+		return true
+	}
+	return sameSeller && a.PhotoHash != 0 && b.PhotoHash != 0 &&
+		photoDistance(a.PhotoHash, b.PhotoHash) <= maxPhotoDistance
+}
+
+// hashPhoto рахує dHash першого фото
+func (s *ScraperService) hashPhoto(ctx context.Context, listing *models.Listing) {
+	if s.photoClient == nil || len(listing.Photos) == 0 || ctx.Err() != nil {
+		return
+	}
+
+	hash, err := fetchPhotoHash(ctx, s.photoClient, listing.Photos[0])
+	if err != nil {
+		log.Printf("Failed to hash photo of %s: %v", listing.URL, err)
+		return
+	}
+	listing.PhotoHash = hash
+}
@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/models"
+)
+
+func TestListingFingerprint(t *testing.T) {
+	original := models.Listing{
+		URL:       "https://www.olx.ua/d/uk/obyavlenie/iphone-15-IDa1.html",
+		Title:     "iPhone 15, 128GB — ідеальний стан!",
+		PriceInfo: models.Price{Amount: 25000, Currency: "UAH"},
+		City:      "Київ",
+		District:  "Печерський",
+	}
+
+	repost := original
+	repost.URL = "https://www.olx.ua/d/uk/obyavlenie/iphone-15-IDb2.html"
+	repost.Title = "IPHONE 15 128gb  ідеальний стан"
+
+	if listingFingerprint(original) != listingFingerprint(repost) {
+		t.Error("Repost with the same title, price and location should have the same fingerprint")
+	}
+
+	cheaper := original
+	cheaper.PriceInfo.Amount = 24000
+	if listingFingerprint(original) == listingFingerprint(cheaper) {
+		t.Error("Different price should change the fingerprint")
+	}
+
+	elsewhere := original
+	elsewhere.City = "Львів"
+	if listingFingerprint(original) == listingFingerprint(elsewhere) {
+		t.Error("Different city should change the fingerprint")
+	}
+}
+
+// testPhoto малює горизонтальний градієнт з темним прямокутником
+func testPhoto(width, height int, brightness uint8, box image.Rectangle) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			value := uint8(x*200/width) + brightness
+			if (image.Point{x * 100 / width, y * 100 / height}).In(box) {
+				value = 10
+			}
+			img.Set(x, y, color.Gray{Y: value})
+		}
+	}
+	return img
+}
+
+func TestDHashMatchesResizedPhoto(t *testing.T) {
+	box := image.Rect(20, 20, 60, 70)
+	original := dHash(testPhoto(400, 300, 0, box))
+	resized := dHash(testPhoto(200, 150, 30, box))
+	different := dHash(testPhoto(400, 300, 0, image.Rect(60, 0, 100, 40)))
+
+	if distance := photoDistance(original, resized); distance > maxPhotoDistance {
+		t.Errorf("Resized and brighter photo should match, distance %d", distance)
+	}
+	if distance := photoDistance(original, different); distance <= maxPhotoDistance {
+		t.Errorf("Different photo should not match, distance %d", distance)
+	}
+}
+
+func TestFetchPhotoHash(t *testing.T) {
+	img := testPhoto(90, 80, 0, image.Rect(20, 20, 60, 70))
+
+	var body bytes.Buffer
+	if err := png.Encode(&body, img); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(body.Bytes())
+	}))
+	t.Cleanup(server.Close)
+
+	hash, err := fetchPhotoHash(context.Background(), server.Client(), server.URL+"/photo.png")
+	if err != nil {
+		t.Fatal("Error hashing photo:", err)
+	}
+	if hash != dHash(img) {
+		t.Errorf("Expected hash %x, got %x", dHash(img), hash)
+	}
+}
+
+func TestRepostWithinOneSearch(t *testing.T) {
+	now := time.Now()
+	item := models.Listing{Title: "iPhone 15 128GB", Price: "30 000 грн.", PriceInfo: models.Price{Amount: 30000, Currency: "UAH"}, Location: "Київ"}
+	original, copied := item, item
+	original.URL, original.PostedAt = "https://www.olx.ua/d/uk/obyavlenie/iphone-IDold1.html", now.Add(-2*time.Hour)
+	copied.URL, copied.PostedAt = "https://www.olx.ua/d/uk/obyavlenie/iphone-IDnew1.html", now.Add(-time.Hour)
+	other := models.Listing{URL: "https://www.olx.ua/d/uk/obyavlenie/macbook-IDmac1.html", Title: "MacBook Air", Location: "Київ"}
+
+	backend := searchFunc(func(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error) {
+		// Новіша копія стоїть у видачі першою
+		return []models.Listing{copied, other, original}, nil
+	})
+
+	for _, mode := range []string{RepostTag, RepostSkip} {
+		filter := &database.UserFilter{ID: 1, Query: "iphone 15", IncludeNegotiable: true, RepostMode: mode, User: database.User{TelegramID: 101}}
+		store := newMemStore(filter)
+		store.existing[1] = []string{"https://www.olx.ua/d/uk/obyavlenie/baseline-IDbase.html"}
+
+		service := newTestService(store, backend, ServiceOptions{})
+		service.AddFilter(filter)
+		if _, err := service.scrapeGroup(context.Background(), groupFilters([]*database.UserFilter{filter}, resolveHTML)[0]); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(store.outbox) != 1 {
+			t.Fatalf("%q: expected one notification, got %d", mode, len(store.outbox))
+		}
+		notif, err := store.outbox[0].Notification()
+		if err != nil {
+			t.Fatal(err)
+		}
+		reposts := make(map[string]*models.Repost)
+		for _, listing := range notif.Listings {
+			reposts[listing.URL] = listing.Repost
+		}
+
+		if repost := reposts[original.URL]; repost != nil {
+			t.Errorf("%q: the older copy is not a repost, got %+v", mode, repost)
+		}
+		if reposts[other.URL] != nil {
+			t.Errorf("%q: a different item is not a repost", mode)
+		}
+		repost, notified := reposts[copied.URL]
+		switch mode {
+		case RepostTag:
+			if !notified || repost == nil || repost.URL != original.URL {
+				t.Errorf("The newer copy should be tagged as a repost of the older one, got %+v", repost)
+			}
+		case RepostSkip:
+			if notified {
+				t.Error("The newer copy should be skipped")
+			}
+		}
+		// Пропущений репост все одно зберігається, щоб не перевірятись щоразу
+		if saved := len(store.savedURLs(1)); saved != 3 {
+			t.Errorf("%q: expected all 3 listings saved, got %d", mode, saved)
+		}
+	}
+}
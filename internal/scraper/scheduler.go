@@ -0,0 +1,177 @@
+package scraper
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// yieldWeight - вага останнього проходу в ковзному середньому нових оголошень
+	yieldWeight = 0.3
+	// deferDelay - через скільки повторити групу, якій не вистачило бюджету
+	deferDelay = 15 * time.Second
+	// idleWait - скільки чекати, якщо черга порожня
+	idleWait = time.Minute
+)
+
+// scheduledFilter - фільтр у черзі планувальника
+type scheduledFilter struct {
+	filterID uint
+	due      time.Time
+	index    int
+}
+
+// dueQueue - мін-купа фільтрів за часом наступного скрапінгу
+type dueQueue []*scheduledFilter
+
+func (q dueQueue) Len() int           { return len(q) }
+func (q dueQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+func (q dueQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *dueQueue) Push(x any) {
+	item := x.(*scheduledFilter)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *dueQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	item.index = -1
+	*q = old[:len(old)-1]
+	return item
+}
+
+// scheduler тримає час наступного скрапінгу кожного фільтра та статистику
+// того, як часто фільтр знаходить нові оголошення
+type scheduler struct {
+	mutex  sync.Mutex
+	queue  dueQueue
+	items  map[uint]*scheduledFilter
+	yields map[uint]float64
+	wake   chan struct{}
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		items:  make(map[uint]*scheduledFilter),
+		yields: make(map[uint]float64),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// schedule ставить фільтр у чергу на час due. Якщо фільтр вже в черзі,
+// лишається раніший з двох часів.
+func (s *scheduler) schedule(filterID uint, due time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if item, exists := s.items[filterID]; exists {
+		if due.Before(item.due) {
+			item.due = due
+			heap.Fix(&s.queue, item.index)
+		}
+	} else {
+		item := &scheduledFilter{filterID: filterID, due: due}
+		heap.Push(&s.queue, item)
+		s.items[filterID] = item
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *scheduler) remove(filterID uint) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if item, exists := s.items[filterID]; exists {
+		heap.Remove(&s.queue, item.index)
+		delete(s.items, filterID)
+	}
+	delete(s.yields, filterID)
+}
+
+// nextDue повертає час найближчого фільтра
+func (s *scheduler) nextDue() (time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.queue) == 0 {
+		return time.Time{}, false
+	}
+	return s.queue[0].due, true
+}
+
+// popDue забирає з черги всі фільтри, час яких настав
+func (s *scheduler) popDue(now time.Time) []uint {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var due []uint
+	for len(s.queue) > 0 && !s.queue[0].due.After(now) {
+		item := heap.Pop(&s.queue).(*scheduledFilter)
+		delete(s.items, item.filterID)
+		due = append(due, item.filterID)
+	}
+	return due
+}
+
+// recordYield оновлює ковзне середнє кількості нових оголошень фільтра
+func (s *scheduler) recordYield(filterID uint, newListings int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	average, known := s.yields[filterID]
+	if !known {
+		s.yields[filterID] = float64(newListings)
+		return
+	}
+	s.yields[filterID] = (1-yieldWeight)*average + yieldWeight*float64(newListings)
+}
+
+func (s *scheduler) yield(filterID uint) float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.yields[filterID]
+}
+
+// prioritize впорядковує групи так, щоб першими йшли ті, що частіше
+// знаходять нові оголошення. Пріоритет групи - сума пріоритетів її фільтрів,
+// бо один запит обслуговує їх усіх.
+func (s *scheduler) prioritize(groups []*searchGroup) {
+	scores := make(map[*searchGroup]float64, len(groups))
+	for _, group := range groups {
+		for _, filter := range group.Filters {
+			scores[group] += s.yield(filter.ID)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return scores[groups[i]] > scores[groups[j]]
+	})
+}
+
+// clampInterval обмежує інтервал фільтра межами з конфігурації
+func clampInterval(intervalSec int, fallback, min, max time.Duration) time.Duration {
+	interval := time.Duration(intervalSec) * time.Second
+	if interval <= 0 {
+		interval = fallback
+	}
+	if interval < min {
+		interval = min
+	}
+	if max > 0 && interval > max {
+		interval = max
+	}
+	return interval
+}
@@ -0,0 +1,77 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+
+	"olx-hunter/internal/database"
+)
+
+func TestSchedulerPopsDueFiltersInOrder(t *testing.T) {
+	s := newScheduler()
+	now := time.Now()
+
+	s.schedule(1, now.Add(time.Minute))
+	s.schedule(2, now.Add(-time.Second))
+	s.schedule(3, now.Add(-time.Minute))
+	s.schedule(4, now.Add(time.Hour))
+
+	// Повторне планування лишає раніший час
+	s.schedule(1, now.Add(-2*time.Minute))
+	s.schedule(2, now.Add(time.Hour))
+
+	due := s.popDue(now)
+	if len(due) != 3 || due[0] != 1 || due[1] != 3 || due[2] != 2 {
+		t.Errorf("Expected due filters [1 3 2], got %v", due)
+	}
+
+	next, ok := s.nextDue()
+	if !ok || !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected filter 4 to be next, got %v", next)
+	}
+
+	s.remove(4)
+	if _, ok := s.nextDue(); ok {
+		t.Error("Queue should be empty after removing the last filter")
+	}
+}
+
+func TestSchedulerPrioritizesProductiveFilters(t *testing.T) {
+	s := newScheduler()
+
+	s.recordYield(1, 0)
+	s.recordYield(2, 10)
+	s.recordYield(3, 2)
+	s.recordYield(3, 2)
+
+	groups := []*searchGroup{
+		{Key: "vintage amp", Filters: []*database.UserFilter{{ID: 1}}},
+		{Key: "free stuff", Filters: []*database.UserFilter{{ID: 2}}},
+		{Key: "iphone", Filters: []*database.UserFilter{{ID: 3}, {ID: 4}}},
+	}
+	s.prioritize(groups)
+
+	if groups[0].Key != "free stuff" || groups[1].Key != "iphone" || groups[2].Key != "vintage amp" {
+		t.Errorf("Unexpected priority order: %s, %s, %s", groups[0].Key, groups[1].Key, groups[2].Key)
+	}
+}
+
+func TestClampInterval(t *testing.T) {
+	min, max, fallback := time.Minute, time.Hour, 5*time.Minute
+
+	tests := []struct {
+		seconds  int
+		expected time.Duration
+	}{
+		{0, fallback},
+		{10, min},
+		{600, 10 * time.Minute},
+		{86400, max},
+	}
+
+	for _, tt := range tests {
+		if got := clampInterval(tt.seconds, fallback, min, max); got != tt.expected {
+			t.Errorf("clampInterval(%d) = %v, expected %v", tt.seconds, got, tt.expected)
+		}
+	}
+}
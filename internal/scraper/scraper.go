@@ -1,21 +1,37 @@
 package scraper
 
 import (
-	"fmt"
+	"context"
+	"log"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"olx-hunter/internal/models"
+	"olx-hunter/internal/utils"
 
 	"github.com/gocolly/colly/v2"
 )
 
+// Scraper шукає оголошення. Скасування контексту перериває запити,
+// що виконуються, і пагінацію.
 type Scraper interface {
-	SearchListings(filters models.SearchFilters) ([]models.Listing, error)
+	SearchListings(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error)
 }
 
+// DetailFetcher доповнює оголошення даними з його власної сторінки
+type DetailFetcher interface {
+	FetchDetails(ctx context.Context, listing *models.Listing) error
+}
+
+var (
+	createdTimeRegex = regexp.MustCompile(`\\?"createdTime\\?":\\?"([^"\\]+)`)
+	viewsRegex       = regexp.MustCompile(`(\d[\d\s]*)`)
+	adIDRegex        = regexp.MustCompile(`(\d+)`)
+)
+
 // cleanText очищає текст від CSS, HTML та інших артефактів
 func cleanText(text string) string {
 	// Видаляємо CSS
@@ -32,77 +48,309 @@ func cleanText(text string) string {
 	return strings.TrimSpace(text)
 }
 
-type OLXScraper struct {
-	client *http.Client
+// DefaultBaseURL - адреса OLX, якщо в Options не вказано іншу
+const DefaultBaseURL = "https://www.olx.ua"
+
+// DefaultRequestTimeout - таймаут одного HTTP-запиту до OLX
+const DefaultRequestTimeout = 30 * time.Second
+
+// Options - спільні налаштування для скраперів OLX
+type Options struct {
+	BaseURL string
+	Rates   models.CurrencyRates
+
+	// Monitor відстежує зміни верстки, може бути nil
+	Monitor *LayoutMonitor
+
+	// Transport - спільний транспорт з ротацією проксі та User-Agent,
+	// якщо nil, запити йдуть напряму
+	Transport http.RoundTripper
+
+	// Breaker призупиняє всі запити при ознаках бану, може бути nil.
+	// Retry задає повтори тимчасових помилок, нульове значення - DefaultRetryPolicy.
+	Breaker *CircuitBreaker
+	Retry   RetryPolicy
+
+	// RequestTimeout обмежує кожен запит, нуль - DefaultRequestTimeout
+	RequestTimeout time.Duration
 }
 
-func NewOLXScraper() *OLXScraper {
-	return &OLXScraper{
-		client: &http.Client{},
+func (o Options) baseURL() string {
+	if o.BaseURL == "" {
+		return DefaultBaseURL
 	}
+	return strings.TrimRight(o.BaseURL, "/")
 }
 
-func parsePrice(priceStr string) int {
-	cleanedPrice := strings.ReplaceAll(priceStr, " ", "")
-	cleanedPrice = strings.ReplaceAll(cleanedPrice, "грн.", "")
+func (o Options) requestTimeout() time.Duration {
+	if o.RequestTimeout <= 0 {
+		return DefaultRequestTimeout
+	}
+	return o.RequestTimeout
+}
+
+func (o Options) retry() RetryPolicy {
+	if o.Retry.MaxAttempts == 0 {
+		return DefaultRetryPolicy
+	}
+	return o.Retry
+}
+
+func (o Options) transport() http.RoundTripper {
+	if o.Transport == nil {
+		return NewRotatingTransport(nil, nil)
+	}
+	return o.Transport
+}
+
+type OLXScraper struct {
+	client  *http.Client
+	baseURL string
+	rates   models.CurrencyRates
+	monitor *LayoutMonitor
+}
 
-	price, err := strconv.Atoi(cleanedPrice)
-	if err != nil {
-		return 0
+func NewOLXScraper(opts Options) *OLXScraper {
+	return &OLXScraper{
+		client:  &http.Client{Timeout: opts.requestTimeout(), Transport: opts.transport()},
+		baseURL: opts.baseURL(),
+		rates:   opts.Rates,
+		monitor: opts.Monitor,
 	}
-	return price
 }
 
-func (s *OLXScraper) SearchListings(filters models.SearchFilters) ([]models.Listing, error) {
-	searchURL := fmt.Sprintf("https://www.olx.ua/uk/list/q-%s/?search[order]=created_at:desc", filters.Query)
+// newCollector створює колектор, що ходить через спільний транспорт
+// і зупиняється разом з контекстом
+func (s *OLXScraper) newCollector(ctx context.Context) *colly.Collector {
+	c := colly.NewCollector(colly.StdlibContext(ctx))
+	c.WithTransport(s.client.Transport)
+	c.SetRequestTimeout(s.client.Timeout)
+	return c
+}
+
+func (s *OLXScraper) SearchListings(ctx context.Context, filters models.SearchFilters) ([]models.Listing, error) {
+	maxPages := filters.MaxPages
+	if maxPages < 1 {
+		maxPages = 1
+	}
 
-	c := colly.NewCollector()
+	c := s.newCollector(ctx)
 
 	urlMap := make(map[string]bool)
 	var listings []models.Listing
 
+	// Стан поточної сторінки: скільки карток знайдено, чи є наступна сторінка
+	// і чи дійшли ми до вже відомого оголошення.
+	pageCards := 0
+	hasNextPage := false
+	reachedKnown := false
+	var stats pageStats
+	var firstPage []byte
+
 	c.OnHTML("a[href*='/d/uk/obyavlenie/']", func(e *colly.HTMLElement) {
-		fullURL := "https://www.olx.ua" + e.Attr("href")
+		fullURL := e.Request.AbsoluteURL(e.Attr("href"))
 
 		if !urlMap[fullURL] {
 			urlMap[fullURL] = true
+			pageCards++
+
+			card := e.DOM.Closest(SelectorCard)
+
+			title := card.Find(SelectorTitle).Text()
+			priceText := card.Find(SelectorPrice).Text()
+			location := card.Find(SelectorLocation).Text()
 
-			card := e.DOM.Closest("[data-cy='l-card']")
+			stats.Cards++
+			if card.Length() == 0 {
+				stats.MissingCards++
+			}
+			if cleanText(title) == "" {
+				stats.EmptyTitles++
+			}
+			if cleanText(priceText) == "" {
+				stats.EmptyPrices++
+			}
+			if cleanText(location) == "" {
+				stats.EmptyLocations++
+			}
+
+			// Рекламні (ТОП) картки висять нагорі навіть якщо вони старі,
+			// тому вони не зупиняють пагінацію
+			promoted := card.Find("[data-testid='adCard-featured']").Length() > 0
 
-			title := card.Find("h4").Text()
-			priceText := card.Find("p[data-testid='ad-price']").Text()
-			location := card.Find("p[data-testid='location-date']").Text()
+			// Відоме оголошення теж повертається, щоб його позначили баченим
+			if filters.KnownURLs[fullURL] && !promoted {
+				reachedKnown = true
+			}
 
 			listing := models.Listing{
-				URL:      fullURL,
-				Title:    cleanText(title),
-				Price:    cleanText(priceText),
-				PriceInt: parsePrice(priceText),
-				Location: cleanText(location),
+				URL:       fullURL,
+				Title:     cleanText(title),
+				Price:     cleanText(priceText),
+				PriceInfo: parsePrice(cleanText(priceText)),
+				Location:  cleanText(location),
+				Promoted:  promoted,
 			}
+			listing.City, listing.District, listing.PostedAt = utils.ParseLocationDate(listing.Location, time.Now())
 
-			if filters.MinPrice > 0 && listing.PriceInt < filters.MinPrice {
+			// Ціну та місто вже відфільтровано в URL, перевірка лишається
+			// як страховка на випадок, якщо OLX їх проігнорує
+			if !matchesFilters(listing, filters, s.rates) {
 				return
 			}
-			if filters.MaxPrice > 0 && listing.PriceInt > filters.MaxPrice {
-				return
+			listings = append(listings, listing)
+		}
+	})
+
+	c.OnHTML("a[data-testid='pagination-forward']", func(e *colly.HTMLElement) {
+		hasNextPage = true
+	})
+
+	var lastPage []byte
+	c.OnResponse(func(r *colly.Response) {
+		if firstPage == nil {
+			firstPage = r.Body
+		}
+		lastPage = r.Body
+	})
+
+	var failure error
+	c.OnError(func(r *colly.Response, err error) {
+		failure = classifyResponse(r.StatusCode, r.Request.URL.String(), err)
+	})
+
+	var partial error
+	for page := 1; page <= maxPages; page++ {
+		pageCards = 0
+		hasNextPage = false
+		failure = nil
+
+		pageURL := buildSearchURL(s.baseURL, filters, page)
+		err := c.Visit(pageURL)
+		if err != nil && failure != nil {
+			err = failure
+		}
+		if err == nil && pageCards == 0 && isCaptchaPage(lastPage) {
+			err = &ScrapeError{Kind: KindCaptcha, URL: pageURL}
+		}
+		if err != nil {
+			if page == 1 {
+				return nil, err
 			}
+			log.Printf("Failed to fetch page %d for query '%s': %v", page, filters.Query, err)
+			partial = &PartialError{Page: page, Err: err}
+			break
+		}
+
+		if page == 1 {
+			s.monitor.Inspect(layoutKey(filters), pageURL, stats, firstPage)
+		}
+
+		if reachedKnown || pageCards == 0 || !hasNextPage {
+			break
+		}
+	}
+
+	return listings, partial
+}
+
+func (s *OLXScraper) FetchDetails(ctx context.Context, listing *models.Listing) error {
+	c := s.newCollector(ctx)
 
-			if filters.City != "" {
-				cityLower := strings.ToLower(filters.City)
-				locationLower := strings.ToLower(listing.Location)
-				if !strings.Contains(locationLower, cityLower) {
-					return
-				}
+	c.OnHTML("div[data-cy='ad_description'] div", func(e *colly.HTMLElement) {
+		if listing.Description == "" {
+			listing.Description = strings.TrimSpace(e.Text)
+		}
+	})
+
+	c.OnHTML("div[data-testid='ad-photo'] img, img[data-testid^='swiper-image']", func(e *colly.HTMLElement) {
+		src := e.Attr("src")
+		if src == "" {
+			return
+		}
+		for _, photo := range listing.Photos {
+			if photo == src {
+				return
 			}
-			listings = append(listings, listing)
 		}
+		listing.Photos = append(listing.Photos, src)
 	})
 
-	err := c.Visit(searchURL)
-	if err != nil {
-		return nil, err
+	c.OnHTML("[data-testid='user-profile-user-name']", func(e *colly.HTMLElement) {
+		listing.SellerName = cleanText(e.Text)
+	})
+
+	c.OnHTML("a[data-testid='user-profile-link']", func(e *colly.HTMLElement) {
+		listing.SellerURL = e.Request.AbsoluteURL(e.Attr("href"))
+	})
+
+	c.OnHTML("[data-testid='ad-footer-bar-section'] span", func(e *colly.HTMLElement) {
+		if listing.AdID != "" || !strings.Contains(e.Text, "ID") {
+			return
+		}
+		listing.AdID = adIDRegex.FindString(e.Text)
+	})
+
+	c.OnHTML("[data-testid='page-view-counter'], [data-testid='page-view-text']", func(e *colly.HTMLElement) {
+		match := viewsRegex.FindString(e.Text)
+		if views, err := strconv.Atoi(strings.Join(strings.Fields(match), "")); err == nil {
+			listing.Views = views
+		}
+	})
+
+	c.OnHTML("ol[data-testid='breadcrumbs'] li", func(e *colly.HTMLElement) {
+		crumb := cleanText(e.Text)
+		if crumb != "" {
+			listing.Category = append(listing.Category, crumb)
+		}
+	})
+
+	c.OnHTML("span[data-cy='ad-posted-at']", func(e *colly.HTMLElement) {
+		if !listing.PostedAt.IsZero() {
+			return
+		}
+		if postedAt, ok := utils.ParsePostedDate(cleanText(e.Text), time.Now()); ok {
+			listing.PostedAt = postedAt
+		}
+	})
+
+	// Точний час публікації є тільки у вбудованому стані сторінки
+	c.OnResponse(func(r *colly.Response) {
+		match := createdTimeRegex.FindSubmatch(r.Body)
+		if match == nil {
+			return
+		}
+		if postedAt, err := time.Parse(time.RFC3339, string(match[1])); err == nil {
+			listing.PostedAt = postedAt.In(utils.Kyiv)
+		}
+	})
+
+	inactive := false
+	c.OnResponse(func(r *colly.Response) {
+		inactive = isInactivePage(r.Body)
+	})
+
+	var failure error
+	c.OnError(func(r *colly.Response, err error) {
+		failure = classifyResponse(r.StatusCode, listing.URL, err)
+	})
+
+	listing.Photos = nil
+	listing.Category = nil
+
+	if err := c.Visit(listing.URL); err != nil {
+		if failure != nil {
+			return failure
+		}
+		return err
 	}
+	if inactive {
+		return &ScrapeError{Kind: KindClosed, URL: listing.URL}
+	}
+	return nil
+}
 
-	return listings, nil
+// layoutKey - ключ запиту для історії кількості карток
+func layoutKey(filters models.SearchFilters) string {
+	return strings.ToLower(strings.Join(strings.Fields(filters.Query), " ")) + "|" + citySlug(filters.City)
 }
@@ -0,0 +1,435 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"olx-hunter/internal/models"
+	"olx-hunter/internal/utils"
+)
+
+// fixtureServer віддає збережені сторінки OLX з testdata замість реального сайту
+type fixtureServer struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	requests []string
+}
+
+func newFixtureServer(t *testing.T) *fixtureServer {
+	t.Helper()
+
+	fs := &fixtureServer{}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mutex.Lock()
+		fs.requests = append(fs.requests, r.URL.RequestURI())
+		fs.mutex.Unlock()
+
+		fixture := "search_empty.html"
+		if !strings.Contains(r.URL.Path, "q-zzqxw") {
+			switch r.URL.Query().Get("page") {
+			case "", "1":
+				fixture = "search_page1.html"
+			case "2":
+				fixture = "search_page2.html"
+			}
+		}
+
+		body, err := os.ReadFile(filepath.Join("testdata", fixture))
+		if err != nil {
+			t.Errorf("Failed to read fixture %s: %v", fixture, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(body)
+	}))
+	t.Cleanup(fs.Close)
+
+	return fs
+}
+
+func (fs *fixtureServer) requestCount() int {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	return len(fs.requests)
+}
+
+func (fs *fixtureServer) lastRequest() string {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+	if len(fs.requests) == 0 {
+		return ""
+	}
+	return fs.requests[len(fs.requests)-1]
+}
+
+func newTestScraper(fs *fixtureServer) *OLXScraper {
+	return NewOLXScraper(Options{
+		BaseURL: fs.URL,
+		Rates:   models.CurrencyRates{"USD": 40, "EUR": 45},
+	})
+}
+
+func findListing(listings []models.Listing, urlSuffix string) *models.Listing {
+	for i := range listings {
+		if strings.HasSuffix(listings[i].URL, urlSuffix) {
+			return &listings[i]
+		}
+	}
+	return nil
+}
+
+func TestSearchListingsParsesCards(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone 15", IncludeNegotiable: true})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+
+	if len(listings) != 5 {
+		t.Fatalf("Expected 5 listings, got %d", len(listings))
+	}
+
+	listing := findListing(listings, "/d/uk/obyavlenie/iphone-15-pro-kyiv-IDa001.html")
+	if listing == nil {
+		t.Fatal("Kyiv listing not found")
+	}
+	if listing.URL != fs.URL+"/d/uk/obyavlenie/iphone-15-pro-kyiv-IDa001.html" {
+		t.Errorf("Expected absolute URL on the fixture host, got '%s'", listing.URL)
+	}
+	if listing.Title != "iPhone 15 Pro 256GB" {
+		t.Errorf("Expected Title='iPhone 15 Pro 256GB', got '%s'", listing.Title)
+	}
+	if listing.PriceInfo.Amount != 32500 || listing.PriceInfo.Currency != "UAH" || !listing.PriceInfo.Negotiable {
+		t.Errorf("Unexpected price: %+v", listing.PriceInfo)
+	}
+	if listing.City != "Київ" || listing.District != "Печерський" {
+		t.Errorf("Expected Київ, Печерський, got '%s', '%s'", listing.City, listing.District)
+	}
+	if listing.PostedAt.IsZero() {
+		t.Error("PostedAt should be parsed from the card")
+	}
+	if listing.Promoted {
+		t.Error("Regular listing should not be promoted")
+	}
+
+	top := findListing(listings, "IDtop01.html")
+	if top == nil || !top.Promoted {
+		t.Error("TOP listing should be marked as promoted")
+	}
+
+	if !strings.HasPrefix(fs.lastRequest(), "/uk/list/q-iphone-15/") {
+		t.Errorf("Unexpected search path: %s", fs.lastRequest())
+	}
+}
+
+func TestSearchListingsDedupesByURL(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	// Кожна картка має два посилання, а ТОП повторюється на другій сторінці
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone 15", MaxPages: 2, IncludeNegotiable: true})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, listing := range listings {
+		if seen[listing.URL] {
+			t.Errorf("Duplicate listing: %s", listing.URL)
+		}
+		seen[listing.URL] = true
+	}
+
+	if len(listings) != 7 {
+		t.Errorf("Expected 7 unique listings across two pages, got %d", len(listings))
+	}
+}
+
+func TestSearchListingsPriceFilter(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{
+		Query:    "iphone 15",
+		MinPrice: 20000,
+		MaxPrice: 30000,
+	})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+
+	// 25 000 грн (ТОП) та $600 * 40 = 24 000 грн; договірна 32 500 та обмін відкидаються
+	if len(listings) != 2 {
+		t.Fatalf("Expected 2 listings in price range, got %d", len(listings))
+	}
+	if findListing(listings, "IDa002.html") == nil {
+		t.Error("USD listing should match after conversion")
+	}
+
+	if !strings.Contains(fs.lastRequest(), "filter_float_price%3Afrom%5D=20000") {
+		t.Errorf("Price filter should be in the search URL: %s", fs.lastRequest())
+	}
+}
+
+func TestSearchListingsNegotiable(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone 15"})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+	if findListing(listings, "IDa001.html") != nil {
+		t.Error("Negotiable listing should be skipped when IncludeNegotiable is false")
+	}
+}
+
+func TestSearchListingsCityFilter(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone 15", City: "Львів", IncludeNegotiable: true})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+
+	if len(listings) != 1 {
+		t.Fatalf("Expected 1 listing in Lviv, got %d", len(listings))
+	}
+	if listings[0].City != "Львів" {
+		t.Errorf("Expected city Львів, got '%s'", listings[0].City)
+	}
+	if !strings.HasPrefix(fs.lastRequest(), "/uk/lvov/q-iphone-15/") {
+		t.Errorf("City slug should be in the search path: %s", fs.lastRequest())
+	}
+}
+
+func TestSearchListingsCityFilterBySlug(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone 15", City: "lvov", IncludeNegotiable: true})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+
+	if len(listings) != 1 || listings[0].City != "Львів" {
+		t.Fatalf("Latin slug should match listings in Львів, got %+v", listings)
+	}
+	if !strings.HasPrefix(fs.lastRequest(), "/uk/lvov/q-iphone-15/") {
+		t.Errorf("City slug should be in the search path: %s", fs.lastRequest())
+	}
+}
+
+func TestSearchListingsRegionFilter(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	all, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone 15", IncludeNegotiable: true})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+
+	// Картки не показують область, тож видачу, обмежену в URL, не відкидаємо
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "iphone 15", City: "Київська область", IncludeNegotiable: true})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+	if len(listings) == 0 || len(listings) != len(all) {
+		t.Errorf("Region filter should keep all %d listings of the scoped search, got %d", len(all), len(listings))
+	}
+	if !strings.HasPrefix(fs.lastRequest(), "/uk/ko/q-iphone-15/") {
+		t.Errorf("Region slug should be in the search path: %s", fs.lastRequest())
+	}
+}
+
+func TestMatchesCity(t *testing.T) {
+	tests := []struct {
+		name    string
+		listing models.Listing
+		city    string
+		match   bool
+	}{
+		{"same city", models.Listing{City: "Київ", Location: "Київ, Печерський"}, "Київ", true},
+		{"latin slug", models.Listing{City: "Київ", Location: "Київ, Печерський"}, "kiev", true},
+		{"other city", models.Listing{City: "Одеса", Location: "Одеса"}, "kiev", false},
+		{"unparsed city", models.Listing{Location: "Київ - Сьогодні о 10:00"}, "kiev", true},
+		{"region without region info", models.Listing{City: "Бровари", Location: "Бровари"}, "київська область", true},
+		{"region from API", models.Listing{City: "Бровари", Region: "Київська область"}, "київська область", true},
+		{"other region from API", models.Listing{City: "Львів", Region: "Львівська область"}, "київська область", false},
+		{"unknown name", models.Listing{City: "Вишневе", Location: "Вишневе"}, "Вишневе", true},
+		{"unknown name elsewhere", models.Listing{City: "Київ", Location: "Київ"}, "Вишневе", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesCity(tt.listing, tt.city); got != tt.match {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.match, got)
+		}
+	}
+}
+
+func TestSearchListingsEmptyPage(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{Query: "zzqxw", MaxPages: 3})
+	if err != nil {
+		t.Fatal("Empty page should not be an error:", err)
+	}
+	if len(listings) != 0 {
+		t.Errorf("Expected no listings, got %d", len(listings))
+	}
+	if fs.requestCount() != 1 {
+		t.Errorf("Expected 1 request for an empty result, got %d", fs.requestCount())
+	}
+}
+
+func TestSearchListingsStopsAtKnownListing(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	known := map[string]bool{
+		// Відомий ТОП не повинен зупиняти пагінацію
+		fs.URL + "/d/uk/obyavlenie/iphone-15-top-IDtop01.html":  true,
+		fs.URL + "/d/uk/obyavlenie/iphone-15-odesa-IDb001.html": true,
+	}
+
+	listings, err := s.SearchListings(context.Background(), models.SearchFilters{
+		Query:             "iphone 15",
+		MaxPages:          5,
+		KnownURLs:         known,
+		IncludeNegotiable: true,
+	})
+	if err != nil {
+		t.Fatal("Error searching listings:", err)
+	}
+
+	if fs.requestCount() != 2 {
+		t.Errorf("Expected to stop after page 2, got %d requests", fs.requestCount())
+	}
+	// Відомі оголошення повертаються, щоб їх позначили баченими
+	fresh := 0
+	for _, listing := range listings {
+		if !known[listing.URL] {
+			fresh++
+		}
+	}
+	if fresh != 5 {
+		t.Errorf("Expected 5 new listings, got %d", fresh)
+	}
+	for url := range known {
+		if findListing(listings, url[len(fs.URL):]) == nil {
+			t.Errorf("Known listing from a fetched page should be returned: %s", url)
+		}
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected models.Price
+	}{
+		{"12 500 грн.", models.Price{Amount: 12500, Currency: "UAH"}},
+		{"$ 500", models.Price{Amount: 500, Currency: "USD"}},
+		{"450 €", models.Price{Amount: 450, Currency: "EUR"}},
+		{"1 200.50 грн.", models.Price{Amount: 1200.5, Currency: "UAH"}},
+		{"32 500 грн.Договірна", models.Price{Amount: 32500, Currency: "UAH", Negotiable: true}},
+		{"Договірна", models.Price{Currency: "UAH", Negotiable: true}},
+		{"Безкоштовно", models.Price{Currency: "UAH", Free: true}},
+		{"Обмін", models.Price{Currency: "UAH", Exchange: true}},
+	}
+
+	for _, tt := range tests {
+		if got := parsePrice(tt.text); got != tt.expected {
+			t.Errorf("parsePrice(%q) = %+v, expected %+v", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestSearchListingsCancelled(t *testing.T) {
+	fs := newFixtureServer(t)
+	s := newTestScraper(fs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := s.SearchListings(ctx, models.SearchFilters{Query: "iphone 15"}); err == nil {
+		t.Error("Expected an error for a cancelled context")
+	}
+	if fs.requestCount() != 0 {
+		t.Errorf("No requests should reach OLX after cancellation, got %d", fs.requestCount())
+	}
+}
+
+func TestFetchDetailsParsesDetailPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := os.ReadFile(filepath.Join("testdata", "detail_page.html"))
+		if err != nil {
+			t.Errorf("Failed to read fixture: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	s := NewOLXScraper(Options{BaseURL: server.URL})
+	listing := models.Listing{
+		URL:    server.URL + "/d/uk/obyavlenie/iphone-15-pro-kyiv-IDa001.html",
+		Photos: []string{"https://example.com/stale.jpg"},
+	}
+	if err := s.FetchDetails(context.Background(), &listing); err != nil {
+		t.Fatalf("FetchDetails failed: %v", err)
+	}
+
+	if !strings.HasPrefix(listing.Description, "Продаю iPhone 15 Pro, 256 ГБ") || !strings.HasSuffix(listing.Description, "без подряпин.") {
+		t.Errorf("Unexpected description: %q", listing.Description)
+	}
+
+	expectedPhotos := []string{
+		"https://ireland.apollo.olxcdn.com/v1/files/a001-1/image;s=1000x700",
+		"https://ireland.apollo.olxcdn.com/v1/files/a001-2/image;s=1000x700",
+	}
+	if len(listing.Photos) != len(expectedPhotos) {
+		t.Fatalf("Expected %d unique photos instead of the old ones, got %v", len(expectedPhotos), listing.Photos)
+	}
+	for i, photo := range expectedPhotos {
+		if listing.Photos[i] != photo {
+			t.Errorf("Photo %d: expected %s, got %s", i, photo, listing.Photos[i])
+		}
+	}
+
+	if listing.SellerName != "Олександр" {
+		t.Errorf("Expected seller name 'Олександр', got %q", listing.SellerName)
+	}
+	if listing.SellerURL != server.URL+"/uk/list/user/abc123/" {
+		t.Errorf("Expected absolute seller URL, got %q", listing.SellerURL)
+	}
+	if listing.AdID != "881234567" {
+		t.Errorf("Expected ad ID 881234567, got %q", listing.AdID)
+	}
+	if listing.Views != 1254 {
+		t.Errorf("Expected 1254 views, got %d", listing.Views)
+	}
+	if len(listing.Category) != 4 || listing.Category[3] != "Мобільні телефони" {
+		t.Errorf("Unexpected category: %v", listing.Category)
+	}
+
+	// Точний час зі стану сторінки має перевагу над датою без часу
+	expectedPosted := time.Date(2025, 10, 12, 9, 41, 5, 0, utils.Kyiv)
+	if !listing.PostedAt.Equal(expectedPosted) {
+		t.Errorf("Expected posted at %v, got %v", expectedPosted, listing.PostedAt)
+	}
+}
@@ -0,0 +1,114 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"olx-hunter/internal/models"
+)
+
+// Порядок сортування результатів пошуку OLX
+const (
+	SortNewest    = "created_at:desc"
+	SortCheapest  = "filter_float_price:asc"
+	SortExpensive = "filter_float_price:desc"
+	SortRelevance = "relevance:desc"
+)
+
+// citySlugs відображає назви міст та областей на сегмент шляху OLX
+var citySlugs = map[string]string{
+	"київ":               "kiev",
+	"львів":              "lvov",
+	"одеса":              "odessa",
+	"харків":             "kharkov",
+	"дніпро":             "dnepr",
+	"запоріжжя":          "zaporozhe",
+	"вінниця":            "vinnitsa",
+	"полтава":            "poltava",
+	"чернігів":           "chernigov",
+	"черкаси":            "cherkassy",
+	"житомир":            "zhitomir",
+	"суми":               "sumy",
+	"рівне":              "rovno",
+	"івано-франківськ":   "ivano-frankovsk",
+	"тернопіль":          "ternopol",
+	"луцьк":              "lutsk",
+	"ужгород":            "uzhgorod",
+	"чернівці":           "chernovtsy",
+	"хмельницький":       "khmelnitskiy",
+	"кропивницький":      "kropivnitskiy",
+	"миколаїв":           "nikolaev",
+	"херсон":             "kherson",
+	"біла церква":        "belaya-tserkov",
+	"бровари":            "brovary",
+	"ірпінь":             "irpen",
+	"київська область":   "ko",
+	"львівська область":  "lv",
+	"одеська область":    "od",
+	"харківська область": "khar",
+	"дніпропетровська область": "dnp",
+	"запорізька область":       "zap",
+	"вінницька область":        "vin",
+	"полтавська область":       "pol",
+}
+
+// regionSlugs - slug-и областей з citySlugs
+var regionSlugs = func() map[string]bool {
+	regions := make(map[string]bool)
+	for name, slug := range citySlugs {
+		if strings.HasSuffix(name, " область") {
+			regions[slug] = true
+		}
+	}
+	return regions
+}()
+
+var slugRegex = regexp.MustCompile(`^[a-z][a-z-]*$`)
+
+// citySlug повертає сегмент шляху для міста або порожній рядок,
+// якщо місто невідоме (тоді фільтрація лишається на боці клієнта)
+func citySlug(city string) string {
+	city = strings.ToLower(strings.TrimSpace(city))
+	if city == "" {
+		return ""
+	}
+	if slug, ok := citySlugs[city]; ok {
+		return slug
+	}
+	if slugRegex.MatchString(city) {
+		return city
+	}
+	return ""
+}
+
+func buildSearchURL(baseURL string, filters models.SearchFilters, page int) string {
+	location := citySlug(filters.City)
+	if location == "" {
+		location = "list"
+	}
+
+	query := strings.Join(strings.Fields(filters.Query), "-")
+
+	order := filters.SortOrder
+	if order == "" {
+		order = SortNewest
+	}
+
+	params := url.Values{}
+	params.Set("search[order]", order)
+	if filters.MinPrice > 0 {
+		params.Set("search[filter_float_price:from]", strconv.Itoa(filters.MinPrice))
+	}
+	if filters.MaxPrice > 0 {
+		params.Set("search[filter_float_price:to]", strconv.Itoa(filters.MaxPrice))
+	}
+	if page > 1 {
+		params.Set("page", strconv.Itoa(page))
+	}
+
+	return fmt.Sprintf("%s/uk/%s/q-%s/?%s",
+		baseURL, location, url.PathEscape(query), params.Encode())
+}
@@ -2,40 +2,126 @@ package scraper
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"olx-hunter/internal/cache"
 	"olx-hunter/internal/database"
+	"olx-hunter/internal/events"
 	"olx-hunter/internal/models"
 )
 
+// maxDetailFetches обмежує кількість сторінок оголошень, які відкриваються
+// за один прохід фільтра
+const maxDetailFetches = 10
+
+// initialDelay - пауза перед першим скрапінгом після запуску
+const initialDelay = 30 * time.Second
+
+// ServiceOptions - налаштування періодичного скрапінгу
+type ServiceOptions struct {
+	WorkerCount  int
+	MaxPages     int
+	FetchDetails bool
+
+	// ScrapeInterval - інтервал за замовчуванням, MinInterval і MaxInterval
+	// обмежують інтервал, заданий у фільтрі
+	ScrapeInterval time.Duration
+	MinInterval    time.Duration
+	MaxInterval    time.Duration
+
+	// Budget - спільний з /find ліміт запитів до OLX, nil - без обмежень.
+	// Планувальник бере токен на кожну групу і відкладає ті, яким не вистачило.
+	Budget Budget
+
+	// PhotoClient завантажує фото для пошуку репостів, nil - без фото.
+	// Фото лежать на CDN, тож клієнт не витрачає бюджет запитів до OLX.
+	PhotoClient *http.Client
+	// RepostWindow - як давно бачене оголошення ще вважається оригіналом репосту
+	RepostWindow time.Duration
+
+	// Publisher отримує події про нові оголошення і зміни цін, nil - без подій
+	Publisher events.Publisher
+}
+
+// ServiceStore - дані, з якими працює сервіс скрапінгу. Його реалізує
+// *database.DB, а тести підставляють сховище в пам'яті.
+type ServiceStore interface {
+	GetActiveFilters() ([]*database.UserFilter, error)
+	GetExistingURLs(filterID uint) ([]string, error)
+	TouchListings(urls []string) error
+	RefreshFingerprints(listings []models.Listing) error
+	SaveNewListings(filterID uint, listings []models.Listing, notification *database.OutboxMessage) error
+	RecordPriceChanges(listings []models.Listing, alerts func(changes map[string]database.PriceHistory) ([]*database.OutboxMessage, error)) (map[string]database.PriceHistory, error)
+	GetPriceAlertFilters(listingID uint) ([]*database.UserFilter, error)
+	GetMatchedFilterIDs(listingID uint) ([]uint, error)
+	FindRepost(filterID uint, url, fingerprint, seller string, photoHash uint64, maxDistance int, since time.Time) (*database.SavedListing, error)
+}
+
 type ScraperService struct {
-	db             *database.DB
-	scraper        *OLXScraper
-	notifyCh       chan<- models.Notification
+	db             ServiceStore
+	backends       *Registry
 	workerCount    int
 	scrapeInterval time.Duration
+	minInterval    time.Duration
+	maxInterval    time.Duration
+	maxPages       int
+	fetchDetails   bool
+
+	scheduler *scheduler
+	budget    Budget
+
+	photoClient  *http.Client
+	repostWindow time.Duration
+
+	publisher events.Publisher
+
+	// leases - розподіл фільтрів між екземплярами, nil - один екземпляр
+	leases *LeaseCoordinator
 
 	activeFilters map[uint]*database.UserFilter
 	filtersMutex  sync.RWMutex
 }
 
-func NewScraperService(db *database.DB, notifyCh chan<- models.Notification, workerCount, scrapeIntervalSec int) *ScraperService {
-	if workerCount < 1 {
-		workerCount = 3
+func NewScraperService(db ServiceStore, backends *Registry, opts ServiceOptions) *ScraperService {
+	if opts.WorkerCount < 1 {
+		opts.WorkerCount = 3
+	}
+	if opts.MinInterval < 30*time.Second {
+		opts.MinInterval = 30 * time.Second
+	}
+	if opts.MaxInterval < opts.MinInterval {
+		opts.MaxInterval = 0
 	}
-	if scrapeIntervalSec < 30 {
-		scrapeIntervalSec = 60
+	if opts.ScrapeInterval < opts.MinInterval {
+		opts.ScrapeInterval = 60 * time.Second
+	}
+	if opts.MaxPages < 1 {
+		opts.MaxPages = 1
+	}
+	if opts.RepostWindow <= 0 {
+		opts.RepostWindow = 30 * 24 * time.Hour
 	}
 	return &ScraperService{
 		db:             db,
-		scraper:        NewOLXScraper(),
-		notifyCh:       notifyCh,
-		workerCount:    workerCount,
-		scrapeInterval: time.Duration(scrapeIntervalSec) * time.Second,
+		backends:       backends,
+		workerCount:    opts.WorkerCount,
+		scrapeInterval: opts.ScrapeInterval,
+		minInterval:    opts.MinInterval,
+		maxInterval:    opts.MaxInterval,
+		maxPages:       opts.MaxPages,
+		fetchDetails:   opts.FetchDetails,
+		scheduler:      newScheduler(),
+		budget:         opts.Budget,
+		photoClient:    opts.PhotoClient,
+		repostWindow:   opts.RepostWindow,
+		publisher:      opts.Publisher,
 		activeFilters:  make(map[uint]*database.UserFilter),
 	}
 }
@@ -52,104 +138,282 @@ func (s *ScraperService) LoadExistingFilters() error {
 		return err
 	}
 
+	s.replaceFilters(filters)
+	log.Printf("Loaded %d active filters for monitoring", len(filters))
+	return nil
+}
+
+// replaceFilters робить filters повним переліком фільтрів сервісу: нові
+// ставляться в чергу, відомі отримують свіжі налаштування, решта прибирається
+func (s *ScraperService) replaceFilters(filters []*database.UserFilter) {
 	s.filtersMutex.Lock()
 	defer s.filtersMutex.Unlock()
 
+	current := make(map[uint]bool, len(filters))
+	var added []*database.UserFilter
 	for _, filter := range filters {
+		current[filter.ID] = true
+		if _, exists := s.activeFilters[filter.ID]; !exists {
+			added = append(added, filter)
+		}
 		s.activeFilters[filter.ID] = filter
+	}
+
+	for filterID := range s.activeFilters {
+		if !current[filterID] {
+			delete(s.activeFilters, filterID)
+			s.scheduler.remove(filterID)
+			log.Printf("Filter removed from scraper: ID=%d", filterID)
+		}
+	}
+
+	// Розносимо перший прохід по інтервалу, щоб не скрапити все одночасно
+	start := time.Now().Add(initialDelay)
+	for i, filter := range added {
+		s.scheduler.schedule(filter.ID, start.Add(s.scrapeInterval*time.Duration(i)/time.Duration(len(added))))
 		log.Printf("Loaded filter: ID=%d, Query='%s', UserID=%d",
 			filter.ID, filter.Query, filter.UserID)
 	}
+}
 
-	log.Printf("Loaded %d active filters for monitoring", len(filters))
-	return nil
+// current повертає останню версію фільтра, яку отримав сервіс
+func (s *ScraperService) current(filter *database.UserFilter) *database.UserFilter {
+	s.filtersMutex.RLock()
+	defer s.filtersMutex.RUnlock()
+	if latest, exists := s.activeFilters[filter.ID]; exists {
+		return latest
+	}
+	return filter
 }
 
+// owns перевіряє, чи фільтр досі належить сервісу
+func (s *ScraperService) owns(filterID uint) bool {
+	s.filtersMutex.RLock()
+	defer s.filtersMutex.RUnlock()
+	_, exists := s.activeFilters[filterID]
+	return exists
+}
+
+// AddFilter додає новий або відновлений фільтр і скрапить його одразу.
+// Змінений фільтр скрапиться одразу, лише якщо змінився запит; інакше
+// він лишається на своєму місці в черзі.
 func (s *ScraperService) AddFilter(filter *database.UserFilter) {
+	if s.leases != nil && !s.leases.claim(filter.ID) {
+		// Власник підхопить нові налаштування під час оновлення оренд
+		log.Printf("Filter %d is owned by another instance", filter.ID)
+		return
+	}
+
 	s.filtersMutex.Lock()
 	defer s.filtersMutex.Unlock()
+	previous, exists := s.activeFilters[filter.ID]
 	s.activeFilters[filter.ID] = filter
+	if exists && !searchChanged(previous, filter) {
+		log.Printf("Filter updated in scraper: ID=%d", filter.ID)
+		return
+	}
+	s.scheduler.schedule(filter.ID, time.Now())
 	log.Printf("Filter added to scraper: ID=%d, Query='%s'", filter.ID, filter.Query)
 }
 
+// searchChanged перевіряє, чи змінились поля, від яких залежить запит до OLX.
+// Назва, сповіщення й відбір уже знайдених оголошень на запит не впливають.
+func searchChanged(previous, filter *database.UserFilter) bool {
+	return previous.Query != filter.Query ||
+		previous.MinPrice != filter.MinPrice ||
+		previous.MaxPrice != filter.MaxPrice ||
+		previous.City != filter.City ||
+		previous.SortOrder != filter.SortOrder ||
+		previous.Backend != filter.Backend ||
+		previous.MaxPages != filter.MaxPages
+}
+
 func (s *ScraperService) RemoveFilter(filterID uint) {
+	if s.leases != nil {
+		s.leases.release(filterID)
+	}
+
 	s.filtersMutex.Lock()
 	defer s.filtersMutex.Unlock()
 	delete(s.activeFilters, filterID)
+	s.scheduler.remove(filterID)
 	log.Printf("Filter removed from scraper: ID=%d", filterID)
 }
 
-func (s *ScraperService) StartPeriodicScraping(ctx context.Context) {
-	ticker := time.NewTicker(s.scrapeInterval)
-	defer ticker.Stop()
-
-	log.Println("Waiting 30 seconds before first scraping...")
-	select {
-	case <-time.After(30 * time.Second):
-	case <-ctx.Done():
-		log.Println("Shutdown during initial delay")
-		return
-	}
+// filterInterval повертає інтервал фільтра в межах з конфігурації
+func (s *ScraperService) filterInterval(filter *database.UserFilter) time.Duration {
+	return clampInterval(filter.ScrapeInterval, s.scrapeInterval, s.minInterval, s.maxInterval)
+}
 
-	log.Println("Starting initial scraping...")
-	s.scrapeAllFilters()
+// StartPeriodicScraping запускає планувальник: кожен фільтр скрапиться
+// за власним інтервалом, коли настає його час у черзі
+func (s *ScraperService) StartPeriodicScraping(ctx context.Context) {
+	log.Printf("Scheduler started, first filters are due in %v", initialDelay)
 
 	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Stopping periodic scraper due to shutdown signal...")
-			return
-		case <-ticker.C:
-			log.Println("Starting scheduled scraping session...")
-			s.scrapeAllFilters()
+		wait := idleWait
+		if due, ok := s.scheduler.nextDue(); ok {
+			wait = time.Until(due)
 		}
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				log.Println("Stopping scheduler due to shutdown signal...")
+				return
+			case <-s.scheduler.wake:
+				timer.Stop()
+				continue
+			case <-timer.C:
+			}
+		}
+
+		s.scrapeDueFilters(ctx)
 	}
 }
 
-func (s *ScraperService) scrapeAllFilters() {
-	startTime := time.Now()
+// scrapeDueFilters обробляє фільтри, час яких настав, і ставить їх у чергу
+// знову. Якщо бюджету запитів не вистачає на всі групи, першими йдуть ті,
+// що частіше знаходять нові оголошення, а решта відкладається.
+func (s *ScraperService) scrapeDueFilters(ctx context.Context) {
+	now := time.Now()
 
 	s.filtersMutex.RLock()
-	filters := make([]*database.UserFilter, 0, len(s.activeFilters))
-	for _, filter := range s.activeFilters {
-		filters = append(filters, filter)
+	var filters []*database.UserFilter
+	for _, filterID := range s.scheduler.popDue(now) {
+		if filter, exists := s.activeFilters[filterID]; exists {
+			filters = append(filters, filter)
+		}
 	}
 	s.filtersMutex.RUnlock()
 
 	if len(filters) == 0 {
-		log.Println("No active filters to scrape")
 		return
 	}
 
-	log.Printf("Starting scraping session: %d filters, %d workers", len(filters), s.workerCount)
+	if until, open := s.backends.Breaker().OpenUntil(); open {
+		log.Printf("🛑 Scraping paused by circuit breaker until %s, postponing %d filters", until.Format("15:04:05"), len(filters))
+		s.reschedule(filters, until)
+		return
+	}
+
+	groups := groupFilters(filters, s.backends.Resolve)
+	s.scheduler.prioritize(groups)
+
+	if reserved := s.reserveQueries(ctx, groups); reserved < len(groups) {
+		deferred := groups[reserved:]
+		groups = groups[:reserved]
+
+		deferredCount := 0
+		for _, group := range deferred {
+			s.reschedule(group.Filters, now.Add(deferDelay))
+			deferredCount += len(group.Filters)
+		}
+		log.Printf("⏳ Request budget is short: running %d queries, deferring %d (%d filters)",
+			len(groups), len(deferred), deferredCount)
+	}
+
+	s.scrapeGroups(ctx, groups)
+
+	if ctx.Err() != nil {
+		return
+	}
+	for _, group := range groups {
+		for _, filter := range group.Filters {
+			// За час скрапінгу фільтр могли змінити, інтервал беремо з нової версії
+			s.reschedule([]*database.UserFilter{filter}, time.Now().Add(s.filterInterval(s.current(filter))))
+		}
+	}
+}
+
+// reserveQueries бере з бюджету токен на перший запит кожної групи в порядку
+// пріоритету і повертає, скільки груп можна запустити. Токен береться без
+// очікування, тож інші екземпляри і /find не встигнуть витратити його раніше.
+// Група з кількома сторінками чи деталями витратить більше токенів, решту
+// запитів транспорт дочекається сам.
+func (s *ScraperService) reserveQueries(ctx context.Context, groups []*searchGroup) int {
+	if s.budget == nil {
+		return len(groups)
+	}
+	for i, group := range groups {
+		if err := s.budget.Acquire(cache.WithMaxWait(ctx, 0)); err != nil {
+			return i
+		}
+		group.reserved = true
+	}
+	return len(groups)
+}
+
+// reschedule повертає фільтри в чергу, якщо їх не видалили за цей час
+func (s *ScraperService) reschedule(filters []*database.UserFilter, due time.Time) {
+	s.filtersMutex.RLock()
+	defer s.filtersMutex.RUnlock()
+
+	for _, filter := range filters {
+		if _, exists := s.activeFilters[filter.ID]; exists {
+			s.scheduler.schedule(filter.ID, due)
+		}
+	}
+}
+
+// scrapeGroups обробляє групи пулом воркерів. Фільтри з однаковим запитом
+// об'єднуються в групи, і кожна група робить один запит до OLX. Після
+// скасування контексту запити, що виконуються, перериваються, а решта
+// груп пропускається.
+func (s *ScraperService) scrapeGroups(ctx context.Context, groups []*searchGroup) {
+	if len(groups) == 0 {
+		return
+	}
+
+	startTime := time.Now()
+
+	filterCount := 0
+	for _, group := range groups {
+		filterCount += len(group.Filters)
+	}
 
-	var successCount, errorCount int64
-	jobs := make(chan *database.UserFilter, len(filters))
+	log.Printf("Starting scraping session: %d filters in %d queries, %d workers",
+		filterCount, len(groups), s.workerCount)
+
+	var successCount, errorCount, skippedCount int64
+	jobs := make(chan *searchGroup, len(groups))
 	var wg sync.WaitGroup
 
 	for w := 0; w < s.workerCount; w++ {
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			for filter := range jobs {
-				log.Printf("[worker %d] Processing filter ID=%d, Query='%s'",
-					workerID, filter.ID, filter.Query)
+			for group := range jobs {
+				members := int64(len(group.Filters))
+				if ctx.Err() != nil {
+					atomic.AddInt64(&skippedCount, members)
+					continue
+				}
 
-				if err := s.scrapeFilter(filter); err != nil {
-					log.Printf("[worker %d] Error filter %d: %v", workerID, filter.ID, err)
-					atomic.AddInt64(&errorCount, 1)
+				log.Printf("[worker %d] Processing query '%s' for %d filters",
+					workerID, group.Key, members)
+
+				failed, err := s.scrapeGroup(ctx, group)
+				if errors.Is(err, ErrCircuitOpen) {
+					// Запити заблоковані, кожна група завершується одразу
+					atomic.AddInt64(&errorCount, members)
+					continue
+				}
+				if err != nil {
+					log.Printf("[worker %d] Error query '%s' (%s): %v", workerID, group.Key, KindOf(err), err)
 				} else {
-					log.Printf("[worker %d] ✅ Done filter %d", workerID, filter.ID)
-					atomic.AddInt64(&successCount, 1)
+					log.Printf("[worker %d] ✅ Done query '%s'", workerID, group.Key)
 				}
-
-				time.Sleep(2 * time.Second)
+				atomic.AddInt64(&errorCount, int64(failed))
+				atomic.AddInt64(&successCount, members-int64(failed))
 			}
 		}(w)
 	}
 
-	for _, filter := range filters {
-		jobs <- filter
+	for _, group := range groups {
+		jobs <- group
 	}
 	close(jobs)
 
@@ -158,97 +422,237 @@ func (s *ScraperService) scrapeAllFilters() {
 	duration := time.Since(startTime)
 	log.Printf("Scraping session completed:")
 	log.Printf("    Duration: %v", duration.Round(time.Second))
+	log.Printf("    OLX queries: %d", len(groups))
 	log.Printf("    Success: %d filters", successCount)
 	log.Printf("    Errors: %d filters", errorCount)
-	log.Printf("    Total: %d filters", len(filters))
+	if skippedCount > 0 {
+		log.Printf("    Skipped on shutdown: %d filters", skippedCount)
+	}
+	log.Printf("    Total: %d filters", filterCount)
 }
 
-func (s *ScraperService) scrapeFilter(filter *database.UserFilter) error {
-	log.Printf("Scraping filter: ID=%d, Query='%s'", filter.ID, filter.Query)
+// filterState - що фільтр вже бачив до цього циклу
+type filterState struct {
+	filter        *database.UserFilter
+	existing      map[string]bool
+	isFirstScrape bool
+}
 
-	searchFilters := models.SearchFilters{
-		Query:    filter.Query,
-		MinPrice: filter.MinPrice,
-		MaxPrice: filter.MaxPrice,
-		City:     filter.City,
+// scrapeGroup робить один запит для групи і роздає результат кожному фільтру.
+// Повертає кількість фільтрів, що завершились помилкою.
+func (s *ScraperService) scrapeGroup(ctx context.Context, group *searchGroup) (int, error) {
+	states := make([]filterState, 0, len(group.Filters))
+	searches := make([]models.SearchFilters, 0, len(group.Filters))
+	known := make([]map[string]bool, 0, len(group.Filters))
+
+	for _, filter := range group.Filters {
+		state := s.loadFilterState(filter)
+		states = append(states, state)
+		known = append(known, state.existing)
+
+		search := filterSearch(filter)
+		if search.MaxPages < 1 {
+			search.MaxPages = s.maxPages
+		}
+		if state.isFirstScrape {
+			// Для базової лінії достатньо першої сторінки
+			search.MaxPages = 1
+		}
+		searches = append(searches, search)
 	}
 
-	listings, err := s.scraper.SearchListings(searchFilters)
-	if err != nil {
-		return fmt.Errorf("failed to scrape OLX: %w", err)
+	backend := s.backends.Get(group.Backend)
+	if group.reserved {
+		ctx = withReservation(ctx, 1)
 	}
 
-	log.Printf("Found %d listings for filter %d", len(listings), filter.ID)
+	listings, err := backend.SearchListings(ctx, mergeSearch(searches, known))
+	if IsPartial(err) {
+		log.Printf("Using partial results for query '%s': %v", group.Key, err)
+	} else if err != nil {
+		return len(group.Filters), fmt.Errorf("failed to scrape OLX: %w", err)
+	}
+
+	log.Printf("Found %d listings for query '%s'", len(listings), group.Key)
 
-	if len(listings) == 0 {
-		return nil
+	urls := make([]string, 0, len(listings))
+	for i := range listings {
+		listings[i].Fingerprint = listingFingerprint(listings[i])
+		urls = append(urls, listings[i].URL)
+	}
+	if err := s.db.TouchListings(urls); err != nil {
+		log.Printf("Failed to update last seen time: %v", err)
 	}
+	// Відредаговане оголошення має шукатись серед репостів за новим текстом
+	if err := s.db.RefreshFingerprints(listings); err != nil {
+		log.Printf("Failed to refresh fingerprints: %v", err)
+	}
+
+	s.checkPriceChanges(ctx, listings)
 
+	// Сторінку оголошення відкриваємо один раз на групу
+	enriched := make(map[string]models.Listing)
+	failed := 0
+	for i, state := range states {
+		if !s.owns(state.filter.ID) {
+			// Фільтр видалили або віддали іншому екземпляру під час запиту
+			continue
+		}
+		found, err := s.processFilter(ctx, backend, state, searches[i], listings, enriched)
+		if err != nil {
+			log.Printf("Error filter %d: %v", state.filter.ID, err)
+			failed++
+			continue
+		}
+		if !state.isFirstScrape {
+			s.scheduler.recordYield(state.filter.ID, found)
+		}
+	}
+	return failed, nil
+}
+
+func (s *ScraperService) loadFilterState(filter *database.UserFilter) filterState {
 	existingURLs, err := s.db.GetExistingURLs(filter.ID)
 	if err != nil {
 		log.Printf("Failed to get existing URLs: %v", err)
 		existingURLs = []string{}
 	}
 
-	isFirstScrape := len(existingURLs) == 0
-
 	existingMap := make(map[string]bool)
 	for _, url := range existingURLs {
 		existingMap[url] = true
 	}
 
-	var newListings []models.Listing
+	return filterState{
+		filter:        filter,
+		existing:      existingMap,
+		isFirstScrape: len(existingURLs) == 0,
+	}
+}
+
+// processFilter застосовує умови фільтра до спільної видачі і зберігає нові
+// оголошення разом зі сповіщенням про них в одній транзакції. Повертає
+// кількість нових оголошень.
+func (s *ScraperService) processFilter(ctx context.Context, backend Scraper, state filterState, search models.SearchFilters, listings []models.Listing, enriched map[string]models.Listing) (int, error) {
+	filter := state.filter
+	isFirstScrape := state.isFirstScrape
+	existingMap := state.existing
+
+	var matched []models.Listing
 	for _, listing := range listings {
-		if !existingMap[listing.URL] {
-			newListings = append(newListings, listing)
+		if matchesFilters(listing, search, s.backends.Rates()) {
+			matched = append(matched, listing)
+		}
+	}
+
+	if len(matched) == 0 {
+		return 0, nil
+	}
 
-			if err := s.db.SaveListing(filter.ID, listing); err != nil {
-				log.Printf("Failed to save listing %s: %v", listing.URL, err)
+	var newListings []models.Listing
+	detailsFetched := 0
+	for _, listing := range matched {
+		if !existingMap[listing.URL] {
+			if cached, ok := enriched[listing.URL]; ok {
+				listing = cached
+			} else if !isFirstScrape && detailsFetched < maxDetailFetches {
+				s.enrichListing(ctx, backend, &listing)
+				enriched[listing.URL] = listing
+				detailsFetched++
 			}
+			listing.Fingerprint = listingFingerprint(listing)
+			newListings = append(newListings, listing)
 		}
 	}
 
 	if isFirstScrape {
-		for _, listing := range newListings {
-			if err := s.db.MarkListingAsNotified(listing.URL); err != nil {
-				log.Printf("Failed to mark baseline listing %s: %v", listing.URL, err)
-			}
+		if err := s.db.SaveNewListings(filter.ID, newListings, nil); err != nil {
+			return 0, fmt.Errorf("failed to save baseline: %w", err)
 		}
+		s.publishDiscovered(ctx, filter.ID, newListings, true)
 		log.Printf("📸 First scrape for filter %d: saved %d listings as baseline (no notification)",
 			filter.ID, len(newListings))
-		return nil
+		return len(newListings), nil
 	}
 
+	// Старіші оголошення перевіряються першими: з двох копій у видачі
+	// репостом вважається новіша
+	order := make([]int, len(newListings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return newListings[order[a]].PostedAt.Before(newListings[order[b]].PostedAt)
+	})
+
 	var notifiableListings []models.Listing
-	for _, listing := range newListings {
-		isNotified, err := s.db.IsListingNotified(listing.URL)
-		if err != nil {
-			log.Printf("Error checking is_notified for %s: %v", listing.URL, err)
-		}
-		if !isNotified {
-			notifiableListings = append(notifiableListings, listing)
+	var earlier []models.Listing
+	reposts := 0
+	for _, i := range order {
+		listing := newListings[i]
+		repost := s.findRepost(filter, listing, earlier)
+		earlier = append(earlier, listing)
+		if repost != nil {
+			if filter.RepostMode == RepostSkip {
+				log.Printf("♻️ Skipping repost %s of %s for filter %d", listing.URL, repost.URL, filter.ID)
+				reposts++
+				continue
+			}
+			newListings[i].Repost = repost
+			listing.Repost = repost
 		}
+		notifiableListings = append(notifiableListings, listing)
 	}
 
-	log.Printf("📈 Statistics for filter %d:", filter.ID)
-	log.Printf("    Total found: %d", len(listings))
-	log.Printf("    Already known: %d", len(listings)-len(newListings))
-	log.Printf("    New listings: %d", len(newListings))
-	log.Printf("    Ready to notify: %d", len(notifiableListings))
-
+	var notification *database.OutboxMessage
 	if len(notifiableListings) > 0 {
-		s.notifyCh <- models.Notification{
+		sort.SliceStable(notifiableListings, func(i, j int) bool {
+			return notifiableListings[i].PostedAt.After(notifiableListings[j].PostedAt)
+		})
+
+		var err error
+		notification, err = database.NewOutboxMessage(filter.ID, models.Notification{
 			TelegramID: filter.User.TelegramID,
 			FilterName: filter.Name,
 			Listings:   notifiableListings,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode notification: %w", err)
 		}
+	}
 
-		for _, listing := range notifiableListings {
-			if err := s.db.MarkListingAsNotified(listing.URL); err != nil {
-				log.Printf("Failed to mark listing as notified %s: %v", listing.URL, err)
-			}
-		}
+	// Оголошення і сповіщення зберігаються разом: або фільтр побачить їх
+	// наступного разу знову, або сповіщення точно дійде
+	if err := s.db.SaveNewListings(filter.ID, newListings, notification); err != nil {
+		return 0, fmt.Errorf("failed to save new listings: %w", err)
 	}
+	s.publishDiscovered(ctx, filter.ID, newListings, false)
 
-	return nil
+	log.Printf("📈 Statistics for filter %d:", filter.ID)
+	log.Printf("    Matched: %d", len(matched))
+	log.Printf("    Already known: %d", len(matched)-len(newListings))
+	log.Printf("    New listings: %d", len(newListings))
+	if reposts > 0 {
+		log.Printf("    Suppressed reposts: %d", reposts)
+	}
+	log.Printf("    Queued to notify: %d", len(notifiableListings))
+
+	return len(newListings), nil
+}
+
+func (s *ScraperService) enrichListing(ctx context.Context, backend Scraper, listing *models.Listing) {
+	if !s.fetchDetails || ctx.Err() != nil {
+		return
+	}
+
+	fetcher, ok := backend.(DetailFetcher)
+	if !ok {
+		return
+	}
+
+	if err := fetcher.FetchDetails(ctx, listing); err != nil {
+		log.Printf("Failed to fetch details for %s: %v", listing.URL, err)
+		return
+	}
+	s.hashPhoto(ctx, listing)
 }
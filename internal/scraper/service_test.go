@@ -0,0 +1,470 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"olx-hunter/internal/cache"
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/events"
+	"olx-hunter/internal/models"
+)
+
+// memStore - сховище сервісу і верифікатора в пам'яті
+type memStore struct {
+	mutex sync.Mutex
+
+	filters  []*database.UserFilter
+	existing map[uint][]string
+	saved    map[uint][]models.Listing
+	outbox   []*database.OutboxMessage
+	touched  []string
+
+	listings map[uint]*database.SavedListing
+	matches  map[uint][]uint // ID оголошення -> фільтри, що його бачили
+}
+
+func newMemStore(filters ...*database.UserFilter) *memStore {
+	return &memStore{
+		filters:  filters,
+		existing: make(map[uint][]string),
+		saved:    make(map[uint][]models.Listing),
+		listings: make(map[uint]*database.SavedListing),
+		matches:  make(map[uint][]uint),
+	}
+}
+
+// addListing зберігає оголошення, яке фільтр вже бачив
+func (m *memStore) addListing(filterID uint, listing database.SavedListing) *database.SavedListing {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.listings[listing.ID] = &listing
+	m.matches[listing.ID] = append(m.matches[listing.ID], filterID)
+	m.existing[filterID] = append(m.existing[filterID], listing.URL)
+	return &listing
+}
+
+func (m *memStore) listing(id uint) database.SavedListing {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return *m.listings[id]
+}
+
+func (m *memStore) filter(id uint) *database.UserFilter {
+	for _, filter := range m.filters {
+		if filter.ID == id {
+			return filter
+		}
+	}
+	return nil
+}
+
+func (m *memStore) GetActiveFilters() ([]*database.UserFilter, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]*database.UserFilter(nil), m.filters...), nil
+}
+
+func (m *memStore) GetExistingURLs(filterID uint) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]string(nil), m.existing[filterID]...), nil
+}
+
+func (m *memStore) TouchListings(urls []string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.touched = append(m.touched, urls...)
+	for _, url := range urls {
+		for _, listing := range m.listings {
+			if listing.URL == url {
+				listing.LastSeenAt = time.Now()
+				listing.ClosedAt = nil
+			}
+		}
+	}
+	return nil
+}
+
+func (m *memStore) RefreshFingerprints(listings []models.Listing) error {
+	return nil
+}
+
+func (m *memStore) SaveNewListings(filterID uint, listings []models.Listing, notification *database.OutboxMessage) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, listing := range listings {
+		m.saved[filterID] = append(m.saved[filterID], listing)
+		m.existing[filterID] = append(m.existing[filterID], listing.URL)
+	}
+	if notification != nil {
+		m.outbox = append(m.outbox, notification)
+	}
+	return nil
+}
+
+func (m *memStore) RecordPriceChanges(listings []models.Listing, alerts func(changes map[string]database.PriceHistory) ([]*database.OutboxMessage, error)) (map[string]database.PriceHistory, error) {
+	m.mutex.Lock()
+	changes := make(map[string]database.PriceHistory)
+	for _, listing := range listings {
+		for _, saved := range m.listings {
+			if saved.URL != listing.URL || saved.PriceInfo == listing.PriceInfo {
+				continue
+			}
+			// Невідому збережену ціну лише заповнюємо, як і база
+			if saved.PriceInfo != (models.Price{}) {
+				changes[listing.URL] = database.PriceHistory{
+					ListingID:    saved.ID,
+					OldPrice:     saved.Price,
+					OldPriceInfo: saved.PriceInfo,
+					NewPrice:     listing.Price,
+					NewPriceInfo: listing.PriceInfo,
+				}
+			}
+			saved.Price, saved.PriceInfo = listing.Price, listing.PriceInfo
+		}
+	}
+	m.mutex.Unlock()
+
+	if len(changes) == 0 || alerts == nil {
+		return changes, nil
+	}
+	messages, err := alerts(changes)
+	if err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	m.outbox = append(m.outbox, messages...)
+	m.mutex.Unlock()
+	return changes, nil
+}
+
+func (m *memStore) GetPriceAlertFilters(listingID uint) ([]*database.UserFilter, error) {
+	return nil, nil
+}
+
+func (m *memStore) GetMatchedFilterIDs(listingID uint) ([]uint, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]uint(nil), m.matches[listingID]...), nil
+}
+
+func (m *memStore) ClaimListingsToVerify(seenAfter, seenBefore, checkedBefore time.Time, limit int, lease time.Duration) ([]database.SavedListing, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ids := make([]uint, 0, len(m.listings))
+	for id := range m.listings {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var claimed []database.SavedListing
+	now := time.Now()
+	for _, id := range ids {
+		listing := m.listings[id]
+		if len(claimed) == limit || listing.ClosedAt != nil ||
+			!listing.LastSeenAt.After(seenAfter) || !listing.LastSeenAt.Before(seenBefore) ||
+			(listing.CheckedAt != nil && !listing.CheckedAt.Before(checkedBefore)) ||
+			(listing.VerifyClaimedUntil != nil && listing.VerifyClaimedUntil.After(now)) {
+			continue
+		}
+		until := now.Add(lease)
+		listing.VerifyClaimedUntil = &until
+		claimed = append(claimed, *listing)
+	}
+	return claimed, nil
+}
+
+func (m *memStore) ReleaseListingClaims(listingIDs []uint) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, id := range listingIDs {
+		m.listings[id].VerifyClaimedUntil = nil
+	}
+	return nil
+}
+
+func (m *memStore) MarkListingChecked(listingID uint) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	m.listings[listingID].CheckedAt = &now
+	m.listings[listingID].VerifyClaimedUntil = nil
+	return nil
+}
+
+func (m *memStore) MarkListingsClosed(listingIDs []uint, notifications []*database.OutboxMessage) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	for _, id := range listingIDs {
+		m.listings[id].CheckedAt = &now
+		m.listings[id].ClosedAt = &now
+		m.listings[id].VerifyClaimedUntil = nil
+	}
+	m.outbox = append(m.outbox, notifications...)
+	return nil
+}
+
+func (m *memStore) GetClosedAlertFilters(listingID uint) ([]*database.UserFilter, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var filters []*database.UserFilter
+	for _, filterID := range m.matches[listingID] {
+		if filter := m.filter(filterID); filter != nil && filter.IsActive && filter.ClosedAlerts {
+			filters = append(filters, filter)
+		}
+	}
+	return filters, nil
+}
+
+func (m *memStore) FindRepost(filterID uint, url, fingerprint, seller string, photoHash uint64, maxDistance int, since time.Time) (*database.SavedListing, error) {
+	return nil, nil
+}
+
+// savedURLs - відсортовані URL, збережені фільтром
+func (m *memStore) savedURLs(filterID uint) []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	urls := make([]string, 0, len(m.saved[filterID]))
+	for _, listing := range m.saved[filterID] {
+		urls = append(urls, listing.URL)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func newTestService(store ServiceStore, backend Scraper, opts ServiceOptions) *ScraperService {
+	backends := NewRegistry(BackendHTML)
+	backends.Register(BackendHTML, backend)
+	return NewScraperService(store, backends, opts)
+}
+
+func TestScrapeGroupFansOutToEveryFilter(t *testing.T) {
+	fs := newFixtureServer(t)
+
+	// Київ і kiev - одне місто, тож фільтри діляться одним запитом
+	filters := []*database.UserFilter{
+		{ID: 1, Query: "iPhone 15", City: "Київ", IncludeNegotiable: true, User: database.User{TelegramID: 101}},
+		{ID: 2, Query: "iphone-15", City: "kiev", IncludeNegotiable: true, User: database.User{TelegramID: 102}},
+	}
+	store := newMemStore(filters...)
+	for _, filter := range filters {
+		// Базова лінія вже є, тож нові оголошення йдуть у сповіщення
+		store.existing[filter.ID] = []string{fs.URL + "/d/uk/obyavlenie/old-IDold.html"}
+	}
+
+	service := newTestService(store, newTestScraper(fs), ServiceOptions{})
+	if err := service.LoadExistingFilters(); err != nil {
+		t.Fatal(err)
+	}
+
+	groups := groupFilters(filters, service.backends.Resolve)
+	if len(groups) != 1 {
+		t.Fatalf("Expected one shared group, got %d", len(groups))
+	}
+	failed, err := service.scrapeGroup(context.Background(), groups[0])
+	if err != nil || failed != 0 {
+		t.Fatalf("Expected the group to succeed, got %d failed: %v", failed, err)
+	}
+
+	if fs.requestCount() != 1 {
+		t.Errorf("Expected one shared request, got %d", fs.requestCount())
+	}
+
+	first, second := store.savedURLs(1), store.savedURLs(2)
+	if len(first) == 0 {
+		t.Fatal("Filter 1 should receive the Kyiv listings")
+	}
+	if len(first) != len(second) {
+		t.Fatalf("Both filters should receive the same listings, got %v and %v", first, second)
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("Both filters should receive the same listings, got %v and %v", first, second)
+			break
+		}
+	}
+
+	notified := make(map[int64]int)
+	for _, message := range store.outbox {
+		notif, err := message.Notification()
+		if err != nil {
+			t.Fatal(err)
+		}
+		notified[notif.TelegramID] = len(notif.Listings)
+	}
+	if notified[101] != len(first) || notified[102] != len(second) {
+		t.Errorf("Every filter should be notified about its listings, got %v", notified)
+	}
+}
+
+// countingBudget видає заданий запас токенів без поповнення
+type countingBudget struct {
+	mutex    sync.Mutex
+	tokens   int
+	acquired int
+	refused  int
+}
+
+func (b *countingBudget) Acquire(ctx context.Context) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.tokens == 0 {
+		b.refused++
+		return cache.ErrBudgetExhausted
+	}
+	b.tokens--
+	b.acquired++
+	return nil
+}
+
+func (b *countingBudget) Available(ctx context.Context) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.tokens
+}
+
+func TestSchedulerReservesBudgetPerQuery(t *testing.T) {
+	fs := newFixtureServer(t)
+	budget := &countingBudget{tokens: 1}
+
+	backend := NewOLXScraper(Options{BaseURL: fs.URL, Transport: NewRotatingTransport(nil, budget)})
+	store := newMemStore()
+	service := newTestService(store, backend, ServiceOptions{Budget: budget})
+
+	// Два різні запити - дві групи, а токен лише один
+	service.AddFilter(&database.UserFilter{ID: 1, Query: "iphone 15", IncludeNegotiable: true})
+	service.AddFilter(&database.UserFilter{ID: 2, Query: "macbook", IncludeNegotiable: true})
+
+	service.scrapeDueFilters(context.Background())
+
+	// Запит групи оплачено під час резервування, транспорт не бере токен вдруге
+	if fs.requestCount() != 1 {
+		t.Errorf("Expected one query within the budget, got %d requests", fs.requestCount())
+	}
+	if budget.acquired != 1 || budget.refused != 1 {
+		t.Errorf("Expected 1 reserved and 1 refused token, got %d and %d", budget.acquired, budget.refused)
+	}
+	if len(store.savedURLs(1))+len(store.savedURLs(2)) == 0 {
+		t.Error("The reserved query should save its listings")
+	}
+
+	due, ok := service.scheduler.nextDue()
+	if !ok || time.Until(due) <= 0 {
+		t.Error("The deferred query should be rescheduled for later")
+	}
+}
+
+var testTopics = events.Topics{
+	Discovered:   "listings",
+	PriceChanged: "prices",
+	Closed:       "closed",
+}
+
+// publishedEvent - розібране повідомлення з MemoryPublisher
+type publishedEvent struct {
+	Key  string
+	Type string
+	URL  string
+}
+
+// publishedEvents розбирає повідомлення і групує їх за топіками
+func publishedEvents(t *testing.T, publisher *events.MemoryPublisher) map[string][]publishedEvent {
+	t.Helper()
+	byTopic := make(map[string][]publishedEvent)
+	for _, message := range publisher.Messages() {
+		var decoded struct {
+			Type string `json:"type"`
+			Data struct {
+				URL     string         `json:"url"`
+				Listing models.Listing `json:"listing"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(message.Value, &decoded); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		url := decoded.Data.URL
+		if url == "" {
+			url = decoded.Data.Listing.URL
+		}
+		byTopic[message.Topic] = append(byTopic[message.Topic], publishedEvent{
+			Key:  string(message.Key),
+			Type: decoded.Type,
+			URL:  url,
+		})
+	}
+	return byTopic
+}
+
+func TestScrapeGroupPublishesEvents(t *testing.T) {
+	fs := newFixtureServer(t)
+
+	filters := []*database.UserFilter{
+		{ID: 1, Query: "iphone 15", IncludeNegotiable: true, User: database.User{TelegramID: 101}},
+		{ID: 2, Query: "iPhone 15", IncludeNegotiable: true, User: database.User{TelegramID: 102}},
+	}
+	store := newMemStore(filters...)
+
+	// Оголошення вже бачили фільтр 1 і фільтр 3 з іншого запиту, тепер воно дешевше
+	known := fs.URL + "/d/uk/obyavlenie/iphone-15-lviv-IDa002.html"
+	store.addListing(1, database.SavedListing{ID: 1, URL: known, Price: "$ 700", PriceInfo: models.Price{Amount: 700, Currency: "USD"}})
+	store.addListing(3, database.SavedListing{ID: 1, URL: known, Price: "$ 700", PriceInfo: models.Price{Amount: 700, Currency: "USD"}})
+	store.existing[2] = []string{known}
+
+	publisher := events.NewMemoryPublisher(testTopics)
+	service := newTestService(store, newTestScraper(fs), ServiceOptions{Publisher: publisher})
+	for _, filter := range filters {
+		service.AddFilter(filter)
+	}
+
+	groups := groupFilters(filters, service.backends.Resolve)
+	if len(groups) != 1 {
+		t.Fatalf("Expected one shared group, got %d", len(groups))
+	}
+	if _, err := service.scrapeGroup(context.Background(), groups[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	published := publishedEvents(t, publisher)
+
+	// Нове оголошення - окрема подія для кожного фільтра, ключ - ID фільтра
+	discovered := make(map[string]int)
+	for _, event := range published["listings"] {
+		if event.Type != events.TypeListingDiscovered {
+			t.Errorf("Unexpected event type %q in the listings topic", event.Type)
+		}
+		if event.URL == known {
+			t.Error("A known listing should not be published as discovered")
+		}
+		discovered[event.Key]++
+	}
+	for _, filter := range filters {
+		key := fmt.Sprint(filter.ID)
+		if saved := len(store.savedURLs(filter.ID)); saved == 0 || discovered[key] != saved {
+			t.Errorf("Filter %d saved %d listings but published %d", filter.ID, saved, discovered[key])
+		}
+	}
+
+	// Зміна ціни йде всім фільтрам, що бачили оголошення, а не учасникам групи
+	prices := published["prices"]
+	if len(prices) != 2 {
+		t.Fatalf("Expected a price event per matched filter, got %+v", prices)
+	}
+	keys := []string{prices[0].Key, prices[1].Key}
+	sort.Strings(keys)
+	if keys[0] != "1" || keys[1] != "3" {
+		t.Errorf("Price events should be keyed by filters 1 and 3, got %v", keys)
+	}
+	for _, event := range prices {
+		if event.Type != events.TypePriceChanged || event.URL != known {
+			t.Errorf("Unexpected price event %+v", event)
+		}
+	}
+}
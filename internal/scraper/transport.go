@@ -0,0 +1,137 @@
+package scraper
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+)
+
+// browserProfile - User-Agent разом із заголовками, які надсилає той самий браузер
+type browserProfile struct {
+	UserAgent string
+	Headers   map[string]string
+}
+
+var browserProfiles = []browserProfile{
+	{
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+		Headers: map[string]string{
+			"Sec-Ch-Ua":          `"Google Chrome";v="129", "Not=A?Brand";v="8", "Chromium";v="129"`,
+			"Sec-Ch-Ua-Mobile":   "?0",
+			"Sec-Ch-Ua-Platform": `"Windows"`,
+		},
+	},
+	{
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128.0.0.0 Safari/537.36",
+		Headers: map[string]string{
+			"Sec-Ch-Ua":          `"Chromium";v="128", "Not;A=Brand";v="24", "Google Chrome";v="128"`,
+			"Sec-Ch-Ua-Mobile":   "?0",
+			"Sec-Ch-Ua-Platform": `"macOS"`,
+		},
+	},
+	{
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0",
+		Headers:   map[string]string{},
+	},
+	{
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_6_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Safari/605.1.15",
+		Headers:   map[string]string{},
+	},
+	{
+		UserAgent: "Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36",
+		Headers: map[string]string{
+			"Sec-Ch-Ua":          `"Google Chrome";v="129", "Not=A?Brand";v="8", "Chromium";v="129"`,
+			"Sec-Ch-Ua-Mobile":   "?1",
+			"Sec-Ch-Ua-Platform": `"Android"`,
+		},
+	},
+}
+
+func randomBrowserProfile() browserProfile {
+	return browserProfiles[rand.Intn(len(browserProfiles))]
+}
+
+// applyBrowserHeaders підміняє User-Agent і додає типові заголовки браузера.
+// Accept та Accept-Language, виставлені викликачем, не перезаписуються
+// (colly за замовчуванням ставить Accept: */*).
+func applyBrowserHeaders(req *http.Request, profile browserProfile) {
+	req.Header.Set("User-Agent", profile.UserAgent)
+	for name, value := range profile.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if accept := req.Header.Get("Accept"); accept == "" || accept == "*/*" {
+		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8")
+	}
+	if req.Header.Get("Accept-Language") == "" {
+		req.Header.Set("Accept-Language", "uk-UA,uk;q=0.9,en-US;q=0.8,en;q=0.7")
+	}
+}
+
+// Budget - спільний ліміт запитів до OLX
+type Budget interface {
+	// Acquire чекає на дозвіл для одного запиту
+	Acquire(ctx context.Context) error
+	// Available повертає, скільки запитів можна зробити прямо зараз
+	Available(ctx context.Context) int
+}
+
+// reservationKey - токени бюджету, взяті наперед для запитів групи
+type reservationKey struct{}
+
+type reservation struct {
+	tokens int32
+}
+
+// withReservation позначає, що перші tokens запитів у ctx вже оплачені
+func withReservation(ctx context.Context, tokens int) context.Context {
+	return context.WithValue(ctx, reservationKey{}, &reservation{tokens: int32(tokens)})
+}
+
+// useReservation витрачає один заздалегідь взятий токен, якщо він є
+func useReservation(ctx context.Context) bool {
+	r, ok := ctx.Value(reservationKey{}).(*reservation)
+	return ok && atomic.AddInt32(&r.tokens, -1) >= 0
+}
+
+// RotatingTransport - спільний http.RoundTripper для всіх бекендів: кожен
+// запит чекає на токен зі спільного бюджету і йде через наступний проксі
+// з пулу з випадковим профілем браузера
+type RotatingTransport struct {
+	proxies *ProxyPool
+	budget  Budget
+}
+
+// NewRotatingTransport створює транспорт; budget може бути nil
+func NewRotatingTransport(proxies *ProxyPool, budget Budget) *RotatingTransport {
+	if proxies == nil {
+		proxies = NewProxyPool(nil, "", 0)
+	}
+	return &RotatingTransport{proxies: proxies, budget: budget}
+}
+
+func (t *RotatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.budget != nil && !useReservation(req.Context()) {
+		if err := t.budget.Acquire(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := t.proxies.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTripper не повинен змінювати вхідний запит
+	req = req.Clone(req.Context())
+	applyBrowserHeaders(req, randomBrowserProfile())
+
+	resp, err := t.proxies.transportFor(entry).RoundTrip(req)
+	if err != nil {
+		t.proxies.report(entry, 0, err)
+		return nil, err
+	}
+	t.proxies.report(entry, resp.StatusCode, nil)
+	return resp, nil
+}
@@ -0,0 +1,287 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"olx-hunter/internal/cache"
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/events"
+	"olx-hunter/internal/models"
+)
+
+// verifyLease - на скільки оголошення забирається на перевірку. Якщо
+// екземпляр не встиг його перевірити, після цього його візьме інший.
+const verifyLease = 10 * time.Minute
+
+// VerifierOptions - налаштування перевірки зниклих оголошень
+type VerifierOptions struct {
+	// Interval - як часто запускати перевірку, нуль вимикає її
+	Interval time.Duration
+	// StaleAfter - скільки оголошення має не траплятись у видачі, щоб його перевірити
+	StaleAfter time.Duration
+	// RecheckAfter - як часто повторно перевіряти те саме оголошення
+	RecheckAfter time.Duration
+	// MaxAge - оголошення, яких не було у видачі довше, вже не перевіряються
+	MaxAge time.Duration
+	// BatchSize - скільки сторінок оголошень відкривати за один запуск
+	BatchSize int
+	// Publisher отримує події про зняті оголошення, nil - без подій
+	Publisher events.Publisher
+}
+
+// VerifierStore - дані, з якими працює верифікатор. Його реалізує *database.DB.
+type VerifierStore interface {
+	ClaimListingsToVerify(seenAfter, seenBefore, checkedBefore time.Time, limit int, lease time.Duration) ([]database.SavedListing, error)
+	ReleaseListingClaims(listingIDs []uint) error
+	MarkListingChecked(listingID uint) error
+	MarkListingsClosed(listingIDs []uint, notifications []*database.OutboxMessage) error
+	GetClosedAlertFilters(listingID uint) ([]*database.UserFilter, error)
+	GetMatchedFilterIDs(listingID uint) ([]uint, error)
+}
+
+// ListingVerifier відкриває сторінки оголошень, що зникли з видачі, і
+// позначає продані або зняті з публікації. Використовує лише вільну частину
+// спільного бюджету запитів і ніколи не чекає на токени.
+type ListingVerifier struct {
+	db       VerifierStore
+	backends *Registry
+	budget   Budget
+	opts     VerifierOptions
+
+	mutex       sync.Mutex
+	lastRun     time.Time
+	checked     int
+	closed      int
+	timeOnSale  time.Duration
+	timedClosed int
+}
+
+func NewListingVerifier(db VerifierStore, backends *Registry, budget Budget, opts VerifierOptions) *ListingVerifier {
+	if opts.StaleAfter <= 0 {
+		opts.StaleAfter = 2 * time.Hour
+	}
+	if opts.RecheckAfter <= 0 {
+		opts.RecheckAfter = 6 * time.Hour
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 7 * 24 * time.Hour
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 20
+	}
+	return &ListingVerifier{
+		db:       db,
+		backends: backends,
+		budget:   budget,
+		opts:     opts,
+	}
+}
+
+// Run перевіряє оголошення з інтервалом, поки не скасовано контекст
+func (v *ListingVerifier) Run(ctx context.Context) {
+	if v.opts.Interval <= 0 {
+		log.Println("Listing verifier is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(v.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			v.VerifyOnce(ctx)
+		}
+	}
+}
+
+// batchLimit - скільки оголошень можна перевірити зараз: не більше половини
+// вільних токенів, щоб планувальник і /find не залишились без запитів
+func (v *ListingVerifier) batchLimit(ctx context.Context) int {
+	limit := v.opts.BatchSize
+	if v.budget != nil {
+		if spare := v.budget.Available(ctx) / 2; spare < limit {
+			limit = spare
+		}
+	}
+	return limit
+}
+
+// VerifyOnce перевіряє одну порцію оголошень
+func (v *ListingVerifier) VerifyOnce(ctx context.Context) {
+	if until, open := v.backends.Breaker().OpenUntil(); open {
+		log.Printf("Listing verifier skipped, circuit breaker is open until %s", until.Format("15:04:05"))
+		return
+	}
+
+	fetcher, ok := v.backends.Get(BackendHTML).(DetailFetcher)
+	if !ok {
+		return
+	}
+
+	limit := v.batchLimit(ctx)
+	if limit <= 0 {
+		log.Println("Listing verifier skipped, request budget is short")
+		return
+	}
+
+	now := time.Now()
+	listings, err := v.db.ClaimListingsToVerify(now.Add(-v.opts.MaxAge), now.Add(-v.opts.StaleAfter), now.Add(-v.opts.RecheckAfter), limit, verifyLease)
+	if err != nil {
+		log.Printf("Failed to get listings to verify: %v", err)
+		return
+	}
+
+	// Верифікатор не чекає на токени, вільний бюджет потрібніший пошуку
+	ctx = cache.WithMaxWait(ctx, 0)
+
+	checked := 0
+	var closed []*database.SavedListing
+	batch := newNotificationBatch()
+
+	for i := range listings {
+		saved := &listings[i]
+		listing := models.Listing{URL: saved.URL}
+
+		err := fetcher.FetchDetails(ctx, &listing)
+		if ctx.Err() != nil || errors.Is(err, cache.ErrBudgetExhausted) || errors.Is(err, ErrCircuitOpen) {
+			v.release(listings[i:])
+			break
+		}
+		checked++
+
+		if err == nil {
+			if err := v.db.MarkListingChecked(saved.ID); err != nil {
+				log.Printf("Failed to mark listing %s as checked: %v", saved.URL, err)
+			}
+			continue
+		}
+		if !KindOf(err).IsClosed() {
+			log.Printf("Failed to verify listing %s: %v", saved.URL, err)
+			continue
+		}
+
+		// Без фільтрів для сповіщення оголошення лишається зайнятим і
+		// перевіриться знову, коли мине verifyLease
+		filters, err := v.db.GetClosedAlertFilters(saved.ID)
+		if err != nil {
+			log.Printf("Failed to get closed alert filters for %s: %v", saved.URL, err)
+			continue
+		}
+		for _, filter := range filters {
+			notif := batch.get(filter)
+			notif.Closed = append(notif.Closed, saved.ToListing())
+		}
+		closed = append(closed, saved)
+	}
+
+	if err := v.markClosed(closed, batch); err != nil {
+		log.Printf("Failed to mark %d listings as closed: %v", len(closed), err)
+		closed = nil
+	}
+	for _, saved := range closed {
+		v.recordClosed(saved, now)
+		v.publishClosed(ctx, saved, now)
+	}
+
+	v.mutex.Lock()
+	v.lastRun = now
+	v.checked += checked
+	v.closed += len(closed)
+	v.mutex.Unlock()
+
+	if checked > 0 {
+		log.Printf("🔎 Listing verifier: checked %d, closed %d", checked, len(closed))
+	}
+}
+
+// markClosed позначає оголошення знятими разом зі сповіщеннями про них
+func (v *ListingVerifier) markClosed(closed []*database.SavedListing, batch *notificationBatch) error {
+	if len(closed) == 0 {
+		return nil
+	}
+	messages, err := batch.messages()
+	if err != nil {
+		return err
+	}
+	ids := make([]uint, 0, len(closed))
+	for _, saved := range closed {
+		ids = append(ids, saved.ID)
+	}
+	return v.db.MarkListingsClosed(ids, messages)
+}
+
+// release повертає неперевірені оголошення іншим запускам
+func (v *ListingVerifier) release(listings []database.SavedListing) {
+	ids := make([]uint, 0, len(listings))
+	for _, listing := range listings {
+		ids = append(ids, listing.ID)
+	}
+	if err := v.db.ReleaseListingClaims(ids); err != nil {
+		log.Printf("Failed to release %d listings to verify: %v", len(ids), err)
+	}
+}
+
+// recordClosed враховує, скільки оголошення пробуло на OLX
+func (v *ListingVerifier) recordClosed(saved *database.SavedListing, closedAt time.Time) {
+	listedAt := saved.CreatedAt
+	if saved.PostedAt != nil && saved.PostedAt.Before(listedAt) {
+		listedAt = *saved.PostedAt
+	}
+	if listedAt.IsZero() || !listedAt.Before(closedAt) {
+		return
+	}
+
+	onSale := closedAt.Sub(listedAt)
+	log.Printf("🏁 Listing closed after %v: %s (%s)", onSale.Round(time.Hour), saved.URL, saved.Price)
+
+	v.mutex.Lock()
+	v.timeOnSale += onSale
+	v.timedClosed++
+	v.mutex.Unlock()
+}
+
+// publishClosed публікує подію для кожного фільтра, що бачив оголошення
+func (v *ListingVerifier) publishClosed(ctx context.Context, saved *database.SavedListing, closedAt time.Time) {
+	if v.opts.Publisher == nil {
+		return
+	}
+	closed := events.ListingClosed{
+		URL:         saved.URL,
+		Title:       saved.Title,
+		Price:       saved.Price,
+		PostedAt:    saved.PostedAt,
+		FirstSeenAt: saved.CreatedAt,
+		ClosedAt:    closedAt,
+	}
+	publishEvents(ctx, v.opts.Publisher, matchedFilterEvents(v.db, saved.ID, func(filterID uint) events.Event {
+		return events.NewListingClosed(filterID, closed)
+	}))
+}
+
+// StatusReport - стан верифікатора для команди /status
+func (v *ListingVerifier) StatusReport() string {
+	if v.opts.Interval <= 0 {
+		return "🔎 Перевірка зниклих оголошень: вимкнена\n"
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	text := fmt.Sprintf("🔎 Перевірка зниклих оголошень: перевірено %d, знято з продажу %d\n", v.checked, v.closed)
+	if !v.lastRun.IsZero() {
+		text += fmt.Sprintf("   останній запуск: %s\n", v.lastRun.Format("15:04:05"))
+	}
+	if v.timedClosed > 0 {
+		average := v.timeOnSale / time.Duration(v.timedClosed)
+		text += fmt.Sprintf("   в середньому на OLX: %.1f дн\n", average.Hours()/24)
+	}
+	return text
+}
@@ -0,0 +1,238 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"olx-hunter/internal/database"
+	"olx-hunter/internal/events"
+	"olx-hunter/internal/models"
+)
+
+func TestFetchDetailsDetectsClosedListings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch r.URL.Path {
+		case "/active.html":
+			w.Write([]byte(`<html><body><div data-cy="ad_description"><div>Продаю iPhone</div></div></body></html>`))
+		case "/inactive.html":
+			w.Write([]byte(`<html><body><div data-testid="ad-inactive-msg">Це оголошення більше не доступне</div></body></html>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	s := NewOLXScraper(Options{BaseURL: server.URL})
+
+	tests := []struct {
+		path   string
+		closed bool
+	}{
+		{"/active.html", false},
+		{"/inactive.html", true},
+		{"/removed.html", true},
+	}
+
+	for _, tt := range tests {
+		listing := models.Listing{URL: server.URL + tt.path}
+		err := s.FetchDetails(context.Background(), &listing)
+		if closed := err != nil && KindOf(err).IsClosed(); closed != tt.closed {
+			t.Errorf("%s: expected closed=%v, got error %v", tt.path, tt.closed, err)
+		}
+	}
+}
+
+// newListingServer віддає першу сторінку видачі і сторінки оголошень. Оголошення
+// з closed зняті з публікації, а "removed" повертає 404.
+func newListingServer(t *testing.T, closed map[string]bool) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var detailRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if !strings.HasPrefix(r.URL.Path, "/d/uk/obyavlenie/") {
+			body, err := os.ReadFile(filepath.Join("testdata", "search_page1.html"))
+			if err != nil {
+				t.Errorf("Failed to read fixture: %v", err)
+			}
+			w.Write(body)
+			return
+		}
+
+		atomic.AddInt32(&detailRequests, 1)
+		switch {
+		case closed[r.URL.Path]:
+			w.Write([]byte(`<html><body><div data-testid="ad-inactive-msg">Це оголошення більше не доступне</div></body></html>`))
+		case strings.Contains(r.URL.Path, "removed"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte(`<html><body><div data-cy="ad_description"><div>Продаю iPhone</div></div></body></html>`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &detailRequests
+}
+
+func TestVerifyOnceClosesVanishedListings(t *testing.T) {
+	server, detailRequests := newListingServer(t, map[string]bool{"/d/uk/obyavlenie/sold.html": true})
+
+	store := newMemStore(&database.UserFilter{ID: 1, IsActive: true, ClosedAlerts: true, User: database.User{TelegramID: 101}})
+	now := time.Now()
+	stale := now.Add(-3 * time.Hour)
+	for id, listing := range map[uint]database.SavedListing{
+		1: {URL: "/d/uk/obyavlenie/live.html", LastSeenAt: stale},
+		2: {URL: "/d/uk/obyavlenie/sold.html", LastSeenAt: stale, CreatedAt: now.Add(-48 * time.Hour)},
+		3: {URL: "/d/uk/obyavlenie/removed.html", LastSeenAt: stale},
+		4: {URL: "/d/uk/obyavlenie/fresh.html", LastSeenAt: now},
+		5: {URL: "/d/uk/obyavlenie/ancient.html", LastSeenAt: now.Add(-30 * 24 * time.Hour)},
+	} {
+		listing.ID = id
+		listing.URL = server.URL + listing.URL
+		store.addListing(1, listing)
+	}
+
+	backends := NewRegistry(BackendHTML)
+	backends.Register(BackendHTML, NewOLXScraper(Options{BaseURL: server.URL}))
+	verifier := NewListingVerifier(store, backends, nil, VerifierOptions{Interval: time.Minute})
+
+	verifier.VerifyOnce(context.Background())
+
+	if live := store.listing(1); live.CheckedAt == nil || live.ClosedAt != nil {
+		t.Errorf("Live listing should be checked and stay open, got %+v", live)
+	}
+	for _, id := range []uint{2, 3} {
+		if closed := store.listing(id); closed.ClosedAt == nil {
+			t.Errorf("Listing %d should be marked closed", id)
+		}
+	}
+	for _, id := range []uint{4, 5} {
+		if skipped := store.listing(id); skipped.CheckedAt != nil {
+			t.Errorf("Listing %d is outside the stale window and should not be verified", id)
+		}
+	}
+	if got := atomic.LoadInt32(detailRequests); got != 3 {
+		t.Errorf("Expected 3 listing pages to be opened, got %d", got)
+	}
+
+	if len(store.outbox) != 1 {
+		t.Fatalf("Expected one closed alert, got %d", len(store.outbox))
+	}
+	notif, err := store.outbox[0].Notification()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if notif.TelegramID != 101 || len(notif.Closed) != 2 {
+		t.Errorf("Expected both closed listings in the alert, got %+v", notif)
+	}
+
+	// Перевірене оголошення не відкривається знову до RecheckAfter
+	verifier.VerifyOnce(context.Background())
+	if got := atomic.LoadInt32(detailRequests); got != 3 {
+		t.Errorf("Checked listings should not be verified again, got %d requests", got)
+	}
+}
+
+func TestSearchReopensClosedListing(t *testing.T) {
+	server, _ := newListingServer(t, nil)
+
+	filter := &database.UserFilter{ID: 1, Query: "iphone 15", IncludeNegotiable: true, IsActive: true}
+	store := newMemStore(filter)
+	closedAt := time.Now().Add(-time.Hour)
+	store.addListing(1, database.SavedListing{
+		ID:         1,
+		URL:        server.URL + "/d/uk/obyavlenie/iphone-15-lviv-IDa002.html",
+		LastSeenAt: time.Now().Add(-3 * time.Hour),
+		ClosedAt:   &closedAt,
+	})
+
+	service := newTestService(store, NewOLXScraper(Options{BaseURL: server.URL}), ServiceOptions{})
+	service.AddFilter(filter)
+	if _, err := service.scrapeGroup(context.Background(), groupFilters([]*database.UserFilter{filter}, resolveHTML)[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	// Відоме оголошення знову у видачі: воно бачене і більше не зняте
+	reopened := store.listing(1)
+	if reopened.ClosedAt != nil {
+		t.Error("Listing seen in search again should be reopened")
+	}
+	if time.Since(reopened.LastSeenAt) > time.Minute {
+		t.Error("Known listing seen in search should be marked as seen")
+	}
+}
+
+func TestVerifyOnceReleasesUncheckedListings(t *testing.T) {
+	server, _ := newListingServer(t, nil)
+
+	store := newMemStore(&database.UserFilter{ID: 1, IsActive: true})
+	stale := time.Now().Add(-3 * time.Hour)
+	for id := uint(1); id <= 3; id++ {
+		store.addListing(1, database.SavedListing{
+			ID:         id,
+			URL:        server.URL + fmt.Sprintf("/d/uk/obyavlenie/live-%d.html", id),
+			LastSeenAt: stale,
+		})
+	}
+
+	// Бюджету вистачає лише на одну сторінку
+	budget := &countingBudget{tokens: 1}
+	backends := NewRegistry(BackendHTML)
+	backends.Register(BackendHTML, NewOLXScraper(Options{BaseURL: server.URL, Transport: NewRotatingTransport(nil, budget)}))
+	verifier := NewListingVerifier(store, backends, nil, VerifierOptions{Interval: time.Minute})
+
+	verifier.VerifyOnce(context.Background())
+
+	if checked := store.listing(1); checked.CheckedAt == nil || checked.VerifyClaimedUntil != nil {
+		t.Errorf("Verified listing should be checked and unclaimed, got %+v", checked)
+	}
+	for _, id := range []uint{2, 3} {
+		listing := store.listing(id)
+		if listing.CheckedAt != nil {
+			t.Errorf("Listing %d was not opened and should not be marked as checked", id)
+		}
+		if listing.VerifyClaimedUntil != nil {
+			t.Errorf("Listing %d should be released for the next run", id)
+		}
+	}
+}
+
+func TestVerifyOncePublishesClosedEvents(t *testing.T) {
+	server, _ := newListingServer(t, map[string]bool{"/d/uk/obyavlenie/sold.html": true})
+
+	store := newMemStore(&database.UserFilter{ID: 1, IsActive: true}, &database.UserFilter{ID: 2, IsActive: true})
+	stale := time.Now().Add(-3 * time.Hour)
+	sold := database.SavedListing{ID: 1, URL: server.URL + "/d/uk/obyavlenie/sold.html", Title: "iPhone 15", LastSeenAt: stale}
+	store.addListing(1, sold)
+	store.addListing(2, sold)
+	store.addListing(1, database.SavedListing{ID: 2, URL: server.URL + "/d/uk/obyavlenie/live.html", LastSeenAt: stale})
+
+	publisher := events.NewMemoryPublisher(testTopics)
+	backends := NewRegistry(BackendHTML)
+	backends.Register(BackendHTML, NewOLXScraper(Options{BaseURL: server.URL}))
+	verifier := NewListingVerifier(store, backends, nil, VerifierOptions{Interval: time.Minute, Publisher: publisher})
+
+	verifier.VerifyOnce(context.Background())
+
+	published := publishedEvents(t, publisher)
+	if len(published) != 1 {
+		t.Errorf("Only closed events should be published, got %+v", published)
+	}
+	closed := published["closed"]
+	if len(closed) != 2 {
+		t.Fatalf("Expected a closed event per filter that saw the listing, got %+v", closed)
+	}
+	for i, key := range []string{"1", "2"} {
+		if closed[i].Key != key || closed[i].Type != events.TypeListingClosed || closed[i].URL != sold.URL {
+			t.Errorf("Expected closed event for filter %s, got %+v", key, closed[i])
+		}
+	}
+}
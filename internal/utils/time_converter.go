@@ -1,29 +1,99 @@
 package utils
 
 import (
-	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
 )
 
-func AdjustedTimeToKyiv(locationStr string) string {
-	re := regexp.MustCompile(`(Сьогодні|Вчора) о (\d{1,2}):(\d{2})`)
+// Kyiv - часовий пояс, у якому показуємо час оголошень користувачам
+var Kyiv = loadKyiv()
 
-	return re.ReplaceAllStringFunc(locationStr, func(match string) string{
-		parts := re.FindStringSubmatch(match)
-		if len(parts) != 4 {
-			return match
-		}
+func loadKyiv() *time.Location {
+	loc, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		return time.FixedZone("EET", 2*60*60)
+	}
+	return loc
+}
+
+var (
+	relativeDateRegex = regexp.MustCompile(`(сьогодні|вчора) о (\d{1,2}):(\d{2})`)
+	absoluteDateRegex = regexp.MustCompile(`(\d{1,2}) ([а-яіїєґ']+) (\d{4})`)
+)
+
+var ukrainianMonths = map[string]time.Month{
+	"січня":     time.January,
+	"лютого":    time.February,
+	"березня":   time.March,
+	"квітня":    time.April,
+	"травня":    time.May,
+	"червня":    time.June,
+	"липня":     time.July,
+	"серпня":    time.August,
+	"вересня":   time.September,
+	"жовтня":    time.October,
+	"листопада": time.November,
+	"грудня":    time.December,
+}
+
+// ParseLocationDate розбирає рядок з картки OLX виду
+// "Київ, Печерський - Сьогодні о 12:30" на місто, район та час публікації.
+// postedAt дорівнює нулю, якщо дату не вдалося розпізнати.
+func ParseLocationDate(text string, now time.Time) (city, district string, postedAt time.Time) {
+	location, date, found := strings.Cut(text, " - ")
+	if !found {
+		location = text
+	}
+
+	city, district, _ = strings.Cut(location, ",")
+	city = strings.TrimSpace(city)
+	district = strings.TrimSpace(district)
+
+	if found {
+		postedAt, _ = ParsePostedDate(date, now)
+	}
+	return city, district, postedAt
+}
 
-		day := parts[1]
+// ParsePostedDate розбирає дату публікації OLX: "Сьогодні о 12:30",
+// "Вчора о 23:05", "15 жовтня 2025 р." з необов'язковими префіксами
+// "Оновлено" чи "Опубліковано". Відносні дати OLX віддає в UTC,
+// результат повертається в часовому поясі Києва.
+func ParsePostedDate(text string, now time.Time) (time.Time, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.TrimPrefix(text, "оновлено")
+	text = strings.TrimPrefix(text, "опубліковано")
+	text = strings.TrimSpace(text)
+
+	if parts := relativeDateRegex.FindStringSubmatch(text); parts != nil {
 		hour, _ := strconv.Atoi(parts[2])
-		minute := parts[3]
+		minute, _ := strconv.Atoi(parts[3])
+		if hour > 23 || minute > 59 {
+			return time.Time{}, false
+		}
+
+		day := now.UTC()
+		if parts[1] == "вчора" {
+			day = day.AddDate(0, 0, -1)
+		}
+
+		posted := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, time.UTC)
+		return posted.In(Kyiv), true
+	}
 
-		hour += 3
-		if hour >= 24 {
-			hour -= 24
+	if parts := absoluteDateRegex.FindStringSubmatch(text); parts != nil {
+		month, ok := ukrainianMonths[parts[2]]
+		if !ok {
+			return time.Time{}, false
 		}
+		day, _ := strconv.Atoi(parts[1])
+		year, _ := strconv.Atoi(parts[3])
+
+		return time.Date(year, month, day, 0, 0, 0, 0, Kyiv), true
+	}
 
-		return fmt.Sprintf("%s о %02d:%s", day, hour, minute)
-	})
-}
\ No newline at end of file
+	return time.Time{}, false
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLocationDate(t *testing.T) {
+	// 17 жовтня 2025, 21:30 UTC - в Києві вже 00:30 наступного дня (UTC+3)
+	now := time.Date(2025, time.October, 17, 21, 30, 0, 0, time.UTC)
+
+	city, district, postedAt := ParseLocationDate("Київ, Печерський - Сьогодні о 20:15", now)
+	if city != "Київ" {
+		t.Errorf("Expected city='Київ', got '%s'", city)
+	}
+	if district != "Печерський" {
+		t.Errorf("Expected district='Печерський', got '%s'", district)
+	}
+	expected := time.Date(2025, time.October, 17, 23, 15, 0, 0, Kyiv)
+	if !postedAt.Equal(expected) {
+		t.Errorf("Expected postedAt=%v, got %v", expected, postedAt)
+	}
+
+	city, district, postedAt = ParseLocationDate("Львів", now)
+	if city != "Львів" || district != "" {
+		t.Errorf("Expected city='Львів' without district, got '%s', '%s'", city, district)
+	}
+	if !postedAt.IsZero() {
+		t.Errorf("Expected zero postedAt, got %v", postedAt)
+	}
+}
+
+func TestParsePostedDate(t *testing.T) {
+	summer := time.Date(2025, time.July, 10, 12, 0, 0, 0, time.UTC)
+	winter := time.Date(2025, time.January, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		text     string
+		now      time.Time
+		expected time.Time
+		ok       bool
+	}{
+		{"today summer (UTC+3)", "Сьогодні о 09:05", summer, time.Date(2025, time.July, 10, 12, 5, 0, 0, Kyiv), true},
+		{"today winter (UTC+2)", "Сьогодні о 09:05", winter, time.Date(2025, time.January, 10, 11, 5, 0, 0, Kyiv), true},
+		{"yesterday", "Вчора о 23:40", summer, time.Date(2025, time.July, 10, 2, 40, 0, 0, Kyiv), true},
+		{"updated prefix", "Оновлено Сьогодні о 10:00", summer, time.Date(2025, time.July, 10, 13, 0, 0, 0, Kyiv), true},
+		{"absolute date", "15 жовтня 2025 р.", summer, time.Date(2025, time.October, 15, 0, 0, 0, 0, Kyiv), true},
+		{"published prefix", "Опубліковано 3 березня 2024 р.", summer, time.Date(2024, time.March, 3, 0, 0, 0, 0, Kyiv), true},
+		{"unknown month", "3 бреня 2024 р.", summer, time.Time{}, false},
+		{"garbage", "нещодавно", summer, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParsePostedDate(tt.text, tt.now)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v", tt.ok, ok)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,3 @@
+-- Adding per-filter pagination depth (0 means the service default)
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS max_pages INTEGER DEFAULT 0;
@@ -0,0 +1,10 @@
+-- Adding details collected from the listing page
+ALTER TABLE saved_listings
+ADD COLUMN IF NOT EXISTS ad_id VARCHAR(30),
+ADD COLUMN IF NOT EXISTS description TEXT,
+ADD COLUMN IF NOT EXISTS photos JSONB,
+ADD COLUMN IF NOT EXISTS seller_name VARCHAR(100),
+ADD COLUMN IF NOT EXISTS seller_url VARCHAR(500),
+ADD COLUMN IF NOT EXISTS posted_at TIMESTAMPTZ,
+ADD COLUMN IF NOT EXISTS views INTEGER DEFAULT 0,
+ADD COLUMN IF NOT EXISTS category JSONB;
@@ -0,0 +1,3 @@
+-- Adding per-filter sort order for the OLX search URL (empty means newest first)
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS sort_order VARCHAR(30) DEFAULT '';
@@ -0,0 +1,11 @@
+-- Adding structured price for saved listings
+ALTER TABLE saved_listings
+ADD COLUMN IF NOT EXISTS price_amount NUMERIC(14, 2) DEFAULT 0,
+ADD COLUMN IF NOT EXISTS price_currency VARCHAR(3) DEFAULT 'UAH',
+ADD COLUMN IF NOT EXISTS price_negotiable BOOLEAN DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS price_free BOOLEAN DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS price_exchange BOOLEAN DEFAULT FALSE;
+
+-- Whether listings with a negotiable price match the filter
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS include_negotiable BOOLEAN DEFAULT TRUE;
@@ -0,0 +1,8 @@
+-- Adding parsed city and district of saved listings
+ALTER TABLE saved_listings
+ADD COLUMN IF NOT EXISTS city VARCHAR(100),
+ADD COLUMN IF NOT EXISTS district VARCHAR(100);
+
+-- Freshness limit per filter (0 means no limit)
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS max_age_hours INTEGER DEFAULT 0;
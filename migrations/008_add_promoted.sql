@@ -0,0 +1,7 @@
+-- Marking promoted (TOP) listings
+ALTER TABLE saved_listings
+ADD COLUMN IF NOT EXISTS promoted BOOLEAN DEFAULT FALSE;
+
+-- Whether the filter skips promoted listings instead of labelling them
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS skip_promoted BOOLEAN DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- Adding per-filter scraper backend (empty means the global default)
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS backend VARCHAR(20) DEFAULT '';
@@ -0,0 +1,3 @@
+-- Per-filter scrape interval in seconds (0 means the global default)
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS scrape_interval INTEGER DEFAULT 0;
@@ -0,0 +1,28 @@
+-- Per-filter matches: the same listing can be found by several filters
+-- (including other users' filters) and each of them is notified separately
+CREATE TABLE IF NOT EXISTS filter_matches (
+    id SERIAL PRIMARY KEY,
+    filter_id INTEGER NOT NULL REFERENCES user_filters(id) ON DELETE CASCADE,
+    listing_id INTEGER NOT NULL REFERENCES saved_listings(id) ON DELETE CASCADE,
+    is_notified BOOLEAN DEFAULT FALSE,
+    notified_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+
+    UNIQUE(filter_id, listing_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_filter_matches_listing_id ON filter_matches(listing_id);
+
+-- Existing listings become matches of the filter that found them
+INSERT INTO filter_matches (filter_id, listing_id, is_notified, created_at)
+SELECT filter_id, id, COALESCE(is_notified, FALSE), created_at
+FROM saved_listings
+WHERE filter_id IS NOT NULL
+ON CONFLICT (filter_id, listing_id) DO NOTHING;
+
+-- A listing is shared now, deleting the filter that found it first
+-- must not delete it for the other filters
+ALTER TABLE saved_listings DROP CONSTRAINT IF EXISTS saved_listings_filter_id_fkey;
+ALTER TABLE saved_listings
+ADD CONSTRAINT saved_listings_filter_id_fkey
+FOREIGN KEY (filter_id) REFERENCES user_filters(id) ON DELETE SET NULL;
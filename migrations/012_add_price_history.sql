@@ -0,0 +1,25 @@
+-- Price changes of saved listings
+CREATE TABLE IF NOT EXISTS price_history (
+    id SERIAL PRIMARY KEY,
+    listing_id INTEGER NOT NULL REFERENCES saved_listings(id) ON DELETE CASCADE,
+    old_price VARCHAR(500),
+    old_price_amount NUMERIC(14, 2) DEFAULT 0,
+    old_price_currency VARCHAR(3) DEFAULT 'UAH',
+    old_price_negotiable BOOLEAN DEFAULT FALSE,
+    old_price_free BOOLEAN DEFAULT FALSE,
+    old_price_exchange BOOLEAN DEFAULT FALSE,
+    new_price VARCHAR(500),
+    new_price_amount NUMERIC(14, 2) DEFAULT 0,
+    new_price_currency VARCHAR(3) DEFAULT 'UAH',
+    new_price_negotiable BOOLEAN DEFAULT FALSE,
+    new_price_free BOOLEAN DEFAULT FALSE,
+    new_price_exchange BOOLEAN DEFAULT FALSE,
+    changed_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_listing_id ON price_history(listing_id);
+
+-- Price-drop alerts are opt-in per filter, min_price_drop is in percent
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS price_alerts BOOLEAN DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS min_price_drop INTEGER DEFAULT 0;
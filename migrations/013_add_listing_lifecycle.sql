@@ -0,0 +1,15 @@
+-- When a listing was last seen in search, last verified and closed on OLX
+ALTER TABLE saved_listings
+ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP DEFAULT NOW(),
+ADD COLUMN IF NOT EXISTS checked_at TIMESTAMP,
+ADD COLUMN IF NOT EXISTS closed_at TIMESTAMP;
+
+UPDATE saved_listings
+SET last_seen_at = updated_at
+WHERE updated_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_saved_listings_last_seen_at ON saved_listings(last_seen_at);
+
+-- Whether the filter owner is told when a listing they saw is sold or removed
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS closed_alerts BOOLEAN DEFAULT FALSE;
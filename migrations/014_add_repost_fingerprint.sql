@@ -0,0 +1,11 @@
+-- Fingerprints for detecting reposts of the same item
+ALTER TABLE saved_listings
+ADD COLUMN IF NOT EXISTS fingerprint VARCHAR(40),
+ADD COLUMN IF NOT EXISTS photo_hash BIGINT DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_saved_listings_fingerprint ON saved_listings(fingerprint);
+CREATE INDEX IF NOT EXISTS idx_saved_listings_created_at ON saved_listings(created_at);
+
+-- What to do with reposts: '' tags them, 'skip' suppresses, 'off' disables the check
+ALTER TABLE user_filters
+ADD COLUMN IF NOT EXISTS repost_mode VARCHAR(10) DEFAULT '';
@@ -0,0 +1,16 @@
+-- Scraper instances and their heartbeats
+CREATE TABLE IF NOT EXISTS scraper_instances (
+    id VARCHAR(100) PRIMARY KEY,
+    started_at TIMESTAMP DEFAULT NOW(),
+    heartbeat_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Each active filter is scraped by the instance holding its lease
+CREATE TABLE IF NOT EXISTS filter_leases (
+    filter_id INTEGER PRIMARY KEY REFERENCES user_filters(id) ON DELETE CASCADE,
+    owner VARCHAR(100) NOT NULL,
+    acquired_at TIMESTAMP DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_filter_leases_owner ON filter_leases(owner);
@@ -0,0 +1,10 @@
+-- Filter commands received from Kafka, keyed by the sender's idempotency key.
+-- The stored reply is returned again when the same command is redelivered.
+CREATE TABLE IF NOT EXISTS processed_commands (
+    idempotency_key VARCHAR(100) PRIMARY KEY,
+    type VARCHAR(30) NOT NULL,
+    reply TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_processed_commands_created_at ON processed_commands(created_at);
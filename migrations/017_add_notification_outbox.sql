@@ -0,0 +1,19 @@
+-- Notifications waiting for delivery to Telegram. Rows are written in the
+-- same transaction as the listings they announce and are marked delivered
+-- only after Telegram accepts the message.
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id SERIAL PRIMARY KEY,
+    telegram_id BIGINT NOT NULL,
+    filter_id INTEGER,
+    payload TEXT NOT NULL,
+    attempts INTEGER DEFAULT 0,
+    next_attempt_at TIMESTAMP DEFAULT NOW(),
+    last_error VARCHAR(500),
+    delivered_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_pending
+    ON notification_outbox(next_attempt_at) WHERE delivered_at IS NULL AND failed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_created_at ON notification_outbox(created_at);
@@ -0,0 +1,10 @@
+-- Log of filter changes made by the bot, so that scrapers running as
+-- separate processes pick them up. Only the filter ID is stored: the
+-- scraper reloads the filter and decides whether it is still active.
+CREATE TABLE IF NOT EXISTS filter_changes (
+    id BIGSERIAL PRIMARY KEY,
+    filter_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_filter_changes_created_at ON filter_changes(created_at);
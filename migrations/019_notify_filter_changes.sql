@@ -0,0 +1,28 @@
+-- Every insert, update or delete of a filter, wherever it comes from (the
+-- bot, Kafka commands or a manual SQL edit), is recorded in filter_changes
+-- and announced on the filter_changes channel. NOTIFY is sent on commit,
+-- so listeners always find the new row.
+CREATE OR REPLACE FUNCTION record_filter_change() RETURNS trigger AS $$
+DECLARE
+    changed_id INTEGER;
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD IS NOT DISTINCT FROM NEW THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        changed_id := OLD.id;
+    ELSE
+        changed_id := NEW.id;
+    END IF;
+
+    INSERT INTO filter_changes (filter_id) VALUES (changed_id);
+    PERFORM pg_notify('filter_changes', changed_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_filters_changed ON user_filters;
+CREATE TRIGGER user_filters_changed
+    AFTER INSERT OR UPDATE OR DELETE ON user_filters
+    FOR EACH ROW EXECUTE FUNCTION record_filter_change();
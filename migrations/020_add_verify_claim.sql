@@ -0,0 +1,4 @@
+-- Short claim on a listing while a verifier instance opens its page;
+-- checked_at is only set after the page was actually checked
+ALTER TABLE saved_listings
+ADD COLUMN IF NOT EXISTS verify_claimed_until TIMESTAMP;
@@ -0,0 +1,8 @@
+-- Sequence numbers are taken before commit, so a change with a lower id can
+-- become visible after a higher one has been read. Each change also keeps the
+-- ID of the transaction that made it; readers only take changes from
+-- transactions older than every transaction still running.
+ALTER TABLE filter_changes
+ADD COLUMN IF NOT EXISTS txid BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint;
+
+CREATE INDEX IF NOT EXISTS idx_filter_changes_position ON filter_changes(txid, id);